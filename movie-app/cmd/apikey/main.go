@@ -0,0 +1,100 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"movie-app/internal/auth"
+	"movie-app/internal/database"
+	"movie-app/internal/models"
+	"movie-app/internal/store"
+	"os"
+)
+
+func usage() {
+	fmt.Fprintf(os.Stderr, "Usage: apikey [-db path] [-role role] create <name>|list|revoke <name>\n")
+	flag.PrintDefaults()
+}
+
+func main() {
+	dsn := flag.String("db", database.DefaultDSN, "SQLite database file")
+	roleName := flag.String("role", auth.Viewer.String(), "role of a created key: viewer, reviewer, editor or admin")
+	flag.Usage = usage
+	flag.Parse()
+
+	if flag.NArg() < 1 {
+		usage()
+		os.Exit(2)
+	}
+
+	db, err := database.Open(*dsn)
+	if err != nil {
+		log.Fatalf("Failed to open database: %v", err)
+	}
+	defer db.Close()
+
+	ctx := context.Background()
+	s := store.NewSQLiteStore(db)
+
+	switch cmd := flag.Arg(0); cmd {
+	case "create":
+		name := nameArg()
+		role, err := auth.ParseRole(*roleName)
+		if err != nil {
+			log.Fatalf("Invalid -role: %v", err)
+		}
+		key, prefix, hash, err := auth.NewAPIKey()
+		if err != nil {
+			log.Fatalf("Failed to generate API key: %v", err)
+		}
+		_, err = s.CreateAPIKey(ctx, models.APIKey{Name: name, Prefix: prefix, KeyHash: hash, Role: role.String()})
+		if err == store.ErrDuplicate {
+			log.Fatalf("An API key named %q already exists", name)
+		}
+		if err != nil {
+			log.Fatalf("Failed to create API key: %v", err)
+		}
+		fmt.Printf("created %s API key %q; it will not be shown again:\n%s\n", role, name, key)
+
+	case "list":
+		keys, err := s.ListAPIKeys(ctx)
+		if err != nil {
+			log.Fatalf("Failed to list API keys: %v", err)
+		}
+		for _, k := range keys {
+			status := "active"
+			if k.RevokedAt != nil {
+				status = "revoked " + k.RevokedAt.Format("2006-01-02 15:04:05")
+			}
+			fmt.Printf("%-24s %-8s %s...  created %s  %s\n", k.Name, k.Role, k.Prefix, k.CreatedAt.Format("2006-01-02 15:04:05"), status)
+		}
+		if len(keys) == 0 {
+			fmt.Println("no API keys")
+		}
+
+	case "revoke":
+		name := nameArg()
+		revoked, err := s.RevokeAPIKey(ctx, name)
+		if err != nil {
+			log.Fatalf("Failed to revoke API key: %v", err)
+		}
+		if !revoked {
+			log.Fatalf("No active API key named %q", name)
+		}
+		fmt.Printf("revoked API key %q\n", name)
+
+	default:
+		fmt.Fprintf(os.Stderr, "unknown command %q\n", cmd)
+		usage()
+		os.Exit(2)
+	}
+}
+
+func nameArg() string {
+	if flag.NArg() < 2 || flag.Arg(1) == "" {
+		usage()
+		os.Exit(2)
+	}
+	return flag.Arg(1)
+}
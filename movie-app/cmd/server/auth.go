@@ -0,0 +1,108 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"movie-app/internal/auth"
+	"movie-app/internal/store"
+)
+
+// jwtLeeway allows for clock skew between the token issuer and the server.
+const jwtLeeway = time.Minute
+
+// authenticator builds the request authenticator from the environment:
+//
+//   - AUTH_HS256_SECRET: the shared secret of HS256 tokens
+//   - AUTH_PUBLIC_KEYS: comma-separated PEM files of RS256/EdDSA public keys
+//   - AUTH_JWKS_FILE: a JSON Web Key Set file
+//   - AUTH_ISSUER, AUTH_AUDIENCE: required iss and aud claims, if set
+//
+// Without any key JWTs are rejected; API keys from s are always accepted.
+func authenticator(s store.APIKeyStore) (*auth.Authenticator, error) {
+	var keys []auth.Key
+	if secret := os.Getenv("AUTH_HS256_SECRET"); secret != "" {
+		if len(secret) < 32 {
+			return nil, fmt.Errorf("AUTH_HS256_SECRET must be at least 32 bytes")
+		}
+		keys = append(keys, auth.HMACKey("", []byte(secret)))
+	}
+	for _, path := range splitList(os.Getenv("AUTH_PUBLIC_KEYS")) {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("AUTH_PUBLIC_KEYS: %v", err)
+		}
+		parsed, err := auth.ParsePublicKeys(data)
+		if err != nil {
+			return nil, fmt.Errorf("AUTH_PUBLIC_KEYS: %s: %v", path, err)
+		}
+		keys = append(keys, parsed...)
+	}
+	if path := os.Getenv("AUTH_JWKS_FILE"); path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("AUTH_JWKS_FILE: %v", err)
+		}
+		parsed, err := auth.ParseJWKS(data)
+		if err != nil {
+			return nil, fmt.Errorf("AUTH_JWKS_FILE: %s: %v", path, err)
+		}
+		keys = append(keys, parsed...)
+	}
+
+	a := &auth.Authenticator{APIKeys: s}
+	if len(keys) > 0 {
+		verifier, err := auth.NewVerifier(auth.VerifierConfig{
+			Keys:     keys,
+			Issuer:   os.Getenv("AUTH_ISSUER"),
+			Audience: os.Getenv("AUTH_AUDIENCE"),
+			Leeway:   jwtLeeway,
+		})
+		if err != nil {
+			return nil, err
+		}
+		a.JWT = verifier
+	}
+	return a, nil
+}
+
+// enableCORS lets browsers on the origins in CORS_ALLOWED_ORIGINS (comma
+// separated, or "*" for any) call the API. Other origins get no CORS
+// headers, so browsers keep them to same-origin requests.
+func enableCORS(next http.Handler) http.Handler {
+	allowed := map[string]bool{}
+	for _, origin := range splitList(os.Getenv("CORS_ALLOWED_ORIGINS")) {
+		allowed[origin] = true
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		origin := r.Header.Get("Origin")
+		if origin != "" && (allowed["*"] || allowed[origin]) {
+			w.Header().Set("Access-Control-Allow-Origin", origin)
+			w.Header().Set("Access-Control-Allow-Methods", "POST, GET, OPTIONS")
+			w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, X-API-Key")
+			w.Header().Set("Access-Control-Expose-Headers", "RateLimit-Limit, RateLimit-Remaining, RateLimit-Reset, RateLimit-Policy, Retry-After")
+			w.Header().Add("Vary", "Origin")
+		}
+
+		if r.Method == "OPTIONS" {
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+
+	"movie-app/internal/complexity"
+	"movie-app/internal/resolvers"
+
+	"github.com/graphql-go/graphql"
+)
+
+// Default query limits, unless the environment says otherwise. A page of
+// 100 movies with their actors and reviews costs about 4,200.
+const (
+	defaultMaxQueryDepth = 10
+	defaultMaxQueryCost  = 5000
+)
+
+// queryGuard builds the query depth and cost guard from the environment:
+//
+//   - QUERY_MAX_DEPTH: how deeply fields may nest
+//   - QUERY_MAX_COST: the most an operation may cost
+//
+// Page sizes are scored as the resolvers clamp them.
+func queryGuard(schema *graphql.Schema) (*complexity.Guard, error) {
+	maxDepth, err := positiveIntFromEnv("QUERY_MAX_DEPTH", defaultMaxQueryDepth)
+	if err != nil {
+		return nil, err
+	}
+	maxCost, err := positiveIntFromEnv("QUERY_MAX_COST", defaultMaxQueryCost)
+	if err != nil {
+		return nil, err
+	}
+	return complexity.New(schema, complexity.Config{
+		MaxDepth:        maxDepth,
+		MaxCost:         maxCost,
+		MaxListSize:     resolvers.MaxPageSize,
+		DefaultListSize: resolvers.DefaultPageSize,
+	}), nil
+}
+
+func positiveIntFromEnv(name string, fallback int) (int, error) {
+	value := os.Getenv(name)
+	if value == "" {
+		return fallback, nil
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil || n < 1 {
+		return 0, fmt.Errorf("%s must be a positive integer, got %q", name, value)
+	}
+	return n, nil
+}
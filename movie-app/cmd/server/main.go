@@ -1,11 +1,14 @@
 package main
 
 import (
+	"context"
 	"log"
 	"net/http"
 	"os"
 	"movie-app/internal/database"
+	"movie-app/internal/request"
 	"movie-app/internal/resolvers"
+	"movie-app/internal/store"
 
 	"github.com/graphql-go/handler"
 )
@@ -17,21 +20,70 @@ func main() {
 	}
 	defer database.CloseDatabase()
 
+	movieStore := store.NewSQLiteStore(database.DB)
+
+	retention, err := trashRetention()
+	if err != nil {
+		log.Fatalf("Invalid configuration: %v", err)
+	}
+	go purgeTrash(context.Background(), movieStore, retention)
+
+	authn, err := authenticator(movieStore)
+	if err != nil {
+		log.Fatalf("Invalid auth configuration: %v", err)
+	}
+
+	limiter, err := rateLimiter()
+	if err != nil {
+		log.Fatalf("Invalid rate limit configuration: %v", err)
+	}
+
 	// Create GraphQL schema
-	schema, err := resolvers.CreateSchema()
+	schema, err := resolvers.CreateSchema(movieStore)
 	if err != nil {
 		log.Fatalf("Failed to create schema: %v", err)
 	}
 
+	guard, err := queryGuard(&schema)
+	if err != nil {
+		log.Fatalf("Invalid query limit configuration: %v", err)
+	}
+
+	maxBodyBytes, err := maxRequestBytes()
+	if err != nil {
+		log.Fatalf("Invalid request size configuration: %v", err)
+	}
+
 	// Create GraphQL handler
 	h := handler.New(&handler.Config{
 		Schema:   &schema,
 		Pretty:   true,
 		GraphiQL: true,
+		// Codes the errors graphql-go raises itself and masks unexpected ones.
+		FormatErrorFn: resolvers.FormatError,
+	})
+
+	// Every request gets its own batch loaders so Movie.actors and
+	// Movie.reviews are fetched with one query per list. The raw variables
+	// let patchMovie tell an explicit null from a missing field.
+	graphqlHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := resolvers.WithLoaders(r.Context(), movieStore)
+		if parsed, ok := request.From(ctx); ok {
+			ctx = resolvers.WithVariables(ctx, parsed.Options.Variables)
+		}
+		h.ContextHandler(ctx, w, r)
 	})
 
-	// Set up routes
-	http.Handle("/graphql", enableCORS(h))
+	// Set up routes. The rate limiter first charges each request to its IP
+	// address, before credentials are checked. The request middleware reads
+	// the body, capped at maxBodyBytes, and parses it for the middlewares
+	// after it; graphql-go's handler still parses it again. The auth
+	// middleware then puts the caller in the context; mutations require one.
+	// The rate limiter charges each request to its caller, or to its IP
+	// address when it is anonymous. The guard turns away queries that nest
+	// too deeply or fetch too much before they run.
+	graphqlRoute := authn.Middleware(limiter.Middleware(guard.Middleware(graphqlHandler)))
+	http.Handle("/graphql", enableCORS(limiter.AddressMiddleware(request.Middleware(maxBodyBytes, graphqlRoute))))
 	http.Handle("/health", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusOK)
 		w.Write([]byte("OK"))
@@ -50,18 +102,3 @@ func main() {
 		log.Fatalf("Server failed: %v", err)
 	}
 }
-
-func enableCORS(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		w.Header().Set("Access-Control-Allow-Origin", "*")
-		w.Header().Set("Access-Control-Allow-Methods", "POST, GET, OPTIONS")
-		w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
-
-		if r.Method == "OPTIONS" {
-			w.WriteHeader(http.StatusOK)
-			return
-		}
-
-		next.ServeHTTP(w, r)
-	})
-}
\ No newline at end of file
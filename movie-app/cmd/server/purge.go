@@ -0,0 +1,60 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"movie-app/internal/store"
+)
+
+// defaultTrashRetention is how long deleted items stay restorable unless
+// TRASH_RETENTION says otherwise.
+const defaultTrashRetention = 30 * 24 * time.Hour
+
+// trashRetention reads TRASH_RETENTION, a Go duration such as "168h".
+func trashRetention() (time.Duration, error) {
+	value := os.Getenv("TRASH_RETENTION")
+	if value == "" {
+		return defaultTrashRetention, nil
+	}
+	retention, err := time.ParseDuration(value)
+	if err != nil || retention <= 0 {
+		return 0, fmt.Errorf("TRASH_RETENTION must be a positive duration such as 720h, got %q", value)
+	}
+	return retention, nil
+}
+
+// purgeTrash permanently removes items deleted more than retention ago,
+// once at startup and then every hour (or every retention, if shorter).
+func purgeTrash(ctx context.Context, s store.Store, retention time.Duration) {
+	interval := time.Hour
+	if retention < interval {
+		interval = retention
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		var counts store.PurgeCounts
+		err := s.InTx(ctx, func(tx store.Store) error {
+			var err error
+			counts, err = tx.PurgeDeleted(ctx, time.Now().Add(-retention))
+			return err
+		})
+		if err != nil {
+			log.Printf("Trash purge failed: %v", err)
+		} else if counts != (store.PurgeCounts{}) {
+			log.Printf("Purged %d movies, %d actors and %d reviews from the trash",
+				counts.Movies, counts.Actors, counts.Reviews)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"movie-app/internal/ratelimit"
+)
+
+// Default budgets per client, unless the environment says otherwise. The
+// field budgets keep review spam and full-text search loops in check, and
+// the per-address budget slows down credential guessing.
+const (
+	defaultQueryLimit    = "300/1m"
+	defaultMutationLimit = "30/1m"
+	defaultAddressLimit  = "600/1m"
+	defaultFieldLimits   = "createReview=10/1m,upsertReview=10/1m,searchMovies=60/1m,searchMoviesConnection=60/1m"
+)
+
+// rateLimiter builds the request limiter from the environment:
+//
+//   - RATE_LIMIT_QUERIES, RATE_LIMIT_MUTATIONS: budgets such as "300/1m"
+//   - RATE_LIMIT_FIELDS: comma-separated root field budgets such as
+//     "createReview=10/1m"; set it empty to drop the defaults
+//   - RATE_LIMIT_PER_IP: the budget of each IP address, checked before
+//     authentication
+//   - RATE_LIMIT_TRUSTED_PROXIES: how many proxies in front of the server
+//     append to X-Forwarded-For; 0, the default, ignores the header
+func rateLimiter() (*ratelimit.Limiter, error) {
+	var config ratelimit.Config
+	var err error
+	if config.Queries, err = limitFromEnv("RATE_LIMIT_QUERIES", defaultQueryLimit); err != nil {
+		return nil, err
+	}
+	if config.Mutations, err = limitFromEnv("RATE_LIMIT_MUTATIONS", defaultMutationLimit); err != nil {
+		return nil, err
+	}
+	if config.PerAddress, err = limitFromEnv("RATE_LIMIT_PER_IP", defaultAddressLimit); err != nil {
+		return nil, err
+	}
+
+	fields, ok := os.LookupEnv("RATE_LIMIT_FIELDS")
+	if !ok {
+		fields = defaultFieldLimits
+	}
+	config.Fields = map[string]ratelimit.Limit{}
+	for _, item := range splitList(fields) {
+		name, value, ok := strings.Cut(item, "=")
+		if !ok || strings.TrimSpace(name) == "" {
+			return nil, fmt.Errorf("RATE_LIMIT_FIELDS: expected field=requests/duration, got %q", item)
+		}
+		limit, err := ratelimit.ParseLimit(value)
+		if err != nil {
+			return nil, fmt.Errorf("RATE_LIMIT_FIELDS: %v", err)
+		}
+		config.Fields[strings.TrimSpace(name)] = limit
+	}
+
+	if value := os.Getenv("RATE_LIMIT_TRUSTED_PROXIES"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 0 {
+			return nil, fmt.Errorf("RATE_LIMIT_TRUSTED_PROXIES must be a non-negative integer, got %q", value)
+		}
+		config.TrustedProxies = n
+	}
+	return ratelimit.New(config), nil
+}
+
+func limitFromEnv(name, fallback string) (ratelimit.Limit, error) {
+	value := os.Getenv(name)
+	if value == "" {
+		value = fallback
+	}
+	limit, err := ratelimit.ParseLimit(value)
+	if err != nil {
+		return ratelimit.Limit{}, fmt.Errorf("%s: %v", name, err)
+	}
+	return limit, nil
+}
@@ -0,0 +1,12 @@
+package main
+
+// defaultMaxRequestBytes caps request bodies, 1 MiB, unless the environment
+// says otherwise.
+const defaultMaxRequestBytes = 1 << 20
+
+// maxRequestBytes reads REQUEST_MAX_BYTES, the largest request body the
+// server reads.
+func maxRequestBytes() (int64, error) {
+	n, err := positiveIntFromEnv("REQUEST_MAX_BYTES", defaultMaxRequestBytes)
+	return int64(n), err
+}
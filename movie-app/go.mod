@@ -3,8 +3,8 @@ module movie-app
 go 1.25.0
 
 require (
-	github.com/google/uuid v1.6.0 // indirect
-	github.com/graphql-go/graphql v0.8.1 // indirect
-	github.com/graphql-go/handler v0.2.4 // indirect
-	github.com/mattn/go-sqlite3 v1.14.33 // indirect
+	github.com/google/uuid v1.6.0
+	github.com/graphql-go/graphql v0.8.1
+	github.com/graphql-go/handler v0.2.4
+	github.com/mattn/go-sqlite3 v1.14.33
 )
@@ -0,0 +1,145 @@
+// Package apperr defines the errors the API reports to clients. Each carries
+// a Code, reported as extensions.code, so clients can branch on the kind of
+// failure instead of matching messages.
+package apperr
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// Code classifies an Error.
+type Code string
+
+const (
+	NotFound         Code = "NOT_FOUND"
+	ValidationFailed Code = "VALIDATION_FAILED"
+	Conflict         Code = "CONFLICT"
+	Unauthenticated  Code = "UNAUTHENTICATED"
+	Forbidden        Code = "FORBIDDEN"
+	RateLimited      Code = "RATE_LIMITED"
+	QueryTooComplex  Code = "QUERY_TOO_COMPLEX"
+	RequestTooLarge  Code = "REQUEST_TOO_LARGE"
+	Internal         Code = "INTERNAL"
+)
+
+// FieldError is one problem with an input. Field is the path of the
+// argument or input field, such as "input.rating" or "actors[2].name".
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// Error is an error that is safe to show to clients.
+type Error struct {
+	Code    Code
+	Message string
+	// Fields lists the invalid inputs of a VALIDATION_FAILED error.
+	Fields []FieldError
+	// Details are reported as extra extensions, such as the current state
+	// of a row for a CONFLICT.
+	Details map[string]interface{}
+	// CorrelationID identifies an INTERNAL error in the server logs.
+	CorrelationID string
+
+	cause error
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+// Unwrap returns the masked error of an INTERNAL error.
+func (e *Error) Unwrap() error {
+	return e.cause
+}
+
+// Extensions implements gqlerrors.ExtendedError.
+func (e *Error) Extensions() map[string]interface{} {
+	extensions := map[string]interface{}{"code": string(e.Code)}
+	for k, v := range e.Details {
+		extensions[k] = v
+	}
+	if len(e.Fields) > 0 {
+		extensions["fields"] = e.Fields
+	}
+	if e.CorrelationID != "" {
+		extensions["correlation_id"] = e.CorrelationID
+	}
+	return extensions
+}
+
+// New returns an Error with the given code and message.
+func New(code Code, format string, args ...interface{}) *Error {
+	return &Error{Code: code, Message: fmt.Sprintf(format, args...)}
+}
+
+// NotFoundf reports a missing row, such as "movie not found".
+func NotFoundf(format string, args ...interface{}) *Error {
+	return New(NotFound, format, args...)
+}
+
+// Invalid reports a single invalid input at field.
+func Invalid(field, format string, args ...interface{}) *Error {
+	message := fmt.Sprintf(format, args...)
+	return &Error{
+		Code:    ValidationFailed,
+		Message: message,
+		Fields:  []FieldError{{Field: field, Message: message}},
+	}
+}
+
+// Validation reports every invalid input at once.
+func Validation(fields []FieldError) *Error {
+	if len(fields) == 1 {
+		return Invalid(fields[0].Field, "%s", fields[0].Message)
+	}
+	messages := make([]string, len(fields))
+	for i, f := range fields {
+		messages[i] = f.Field + ": " + f.Message
+	}
+	return &Error{
+		Code:    ValidationFailed,
+		Message: fmt.Sprintf("%d inputs are invalid: %s", len(fields), strings.Join(messages, "; ")),
+		Fields:  fields,
+	}
+}
+
+// Conflictf reports a write that lost a race with another, with details
+// that help the client retry.
+func Conflictf(details map[string]interface{}, format string, args ...interface{}) *Error {
+	e := New(Conflict, format, args...)
+	e.Details = details
+	return e
+}
+
+// Mask hides err behind an INTERNAL error. The original is logged with the
+// correlation ID the client sees, so a report can be traced to it.
+func Mask(err error) *Error {
+	id := uuid.New().String()
+	log.Printf("internal error %s: %v", id, err)
+	return &Error{
+		Code:          Internal,
+		Message:       "internal server error (correlation id " + id + ")",
+		CorrelationID: id,
+		cause:         err,
+	}
+}
+
+// WriteHTTP answers an HTTP request with e as its only error, in GraphQL's
+// response shape, for middleware that stops a request before it runs.
+func WriteHTTP(w http.ResponseWriter, status int, e *Error) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"errors": []map[string]interface{}{{
+			"message":    e.Message,
+			"extensions": e.Extensions(),
+		}},
+	})
+}
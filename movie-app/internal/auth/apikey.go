@@ -0,0 +1,33 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+)
+
+// APIKeyPrefix starts every API key, which tells them apart from JWTs in
+// an Authorization header.
+const APIKeyPrefix = "mvk_"
+
+// apiKeyDisplayLength is how much of a key is stored as its prefix.
+const apiKeyDisplayLength = len(APIKeyPrefix) + 6
+
+// NewAPIKey generates a random API key. It returns the key, which is shown
+// to its owner once, and the prefix and hash to store.
+func NewAPIKey() (key, prefix, hash string, err error) {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return "", "", "", err
+	}
+	key = APIKeyPrefix + base64.RawURLEncoding.EncodeToString(secret)
+	return key, key[:apiKeyDisplayLength], HashAPIKey(key), nil
+}
+
+// HashAPIKey returns the hex SHA-256 of key. API keys carry 256 random bits,
+// so a fast hash is enough to keep a leaked table from revealing them.
+func HashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
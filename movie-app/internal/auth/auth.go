@@ -0,0 +1,149 @@
+// Package auth authenticates API requests with JWTs verified against locally
+// configured keys, or with API keys stored in SQLite, and carries the
+// resulting Principal in the request context.
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"movie-app/internal/apperr"
+	"movie-app/internal/models"
+	"movie-app/internal/store"
+)
+
+// Authentication methods.
+const (
+	MethodJWT    = "jwt"
+	MethodAPIKey = "api_key"
+)
+
+// Principal is the authenticated caller of a request.
+type Principal struct {
+	// Subject identifies the caller: a JWT's sub claim, or "api-key:" and
+	// the key's name.
+	Subject string
+	// Method is MethodJWT or MethodAPIKey.
+	Method string
+	// Role is the highest of a JWT's role and roles claims, or the API
+	// key's role.
+	Role Role
+}
+
+type principalKey struct{}
+
+// WithPrincipal attaches the authenticated caller to ctx.
+func WithPrincipal(ctx context.Context, principal *Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, principal)
+}
+
+// PrincipalFrom returns the caller attached by WithPrincipal, if any.
+func PrincipalFrom(ctx context.Context) (*Principal, bool) {
+	principal, _ := ctx.Value(principalKey{}).(*Principal)
+	return principal, principal != nil
+}
+
+// APIKeyLookup finds stored API keys by hash; store.APIKeyStore implements it.
+type APIKeyLookup interface {
+	APIKeyByHash(ctx context.Context, hash string) (*models.APIKey, error)
+}
+
+// Authenticator checks the credentials of a request. A nil JWT or APIKeys
+// turns that method off.
+type Authenticator struct {
+	JWT     *Verifier
+	APIKeys APIKeyLookup
+}
+
+// errNoCredentials distinguishes an anonymous request from a rejected one.
+var errNoCredentials = errors.New("no credentials")
+
+// Authenticate returns the caller named by the request's Authorization
+// ("Bearer <jwt or API key>") or X-API-Key header. It returns nil and no
+// error for a request without credentials, and an error for credentials
+// that are present but invalid.
+func (a *Authenticator) Authenticate(r *http.Request) (*Principal, error) {
+	credential, err := credentialFrom(r)
+	if err == errNoCredentials {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if strings.HasPrefix(credential, APIKeyPrefix) {
+		return a.authenticateAPIKey(r.Context(), credential)
+	}
+	if a.JWT == nil {
+		return nil, errors.New("bearer tokens are not accepted")
+	}
+	return a.JWT.Verify(credential)
+}
+
+func credentialFrom(r *http.Request) (string, error) {
+	if key := r.Header.Get("X-API-Key"); key != "" {
+		return key, nil
+	}
+	header := r.Header.Get("Authorization")
+	if header == "" {
+		return "", errNoCredentials
+	}
+	scheme, credential, ok := strings.Cut(header, " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") || strings.TrimSpace(credential) == "" {
+		return "", errors.New(`the Authorization header must be "Bearer <token>"`)
+	}
+	return strings.TrimSpace(credential), nil
+}
+
+func (a *Authenticator) authenticateAPIKey(ctx context.Context, key string) (*Principal, error) {
+	if a.APIKeys == nil {
+		return nil, errors.New("API keys are not accepted")
+	}
+	stored, err := a.APIKeys.APIKeyByHash(ctx, HashAPIKey(key))
+	if err == store.ErrNotFound {
+		return nil, errors.New("unknown or revoked API key")
+	}
+	if err != nil {
+		return nil, apperr.Mask(err)
+	}
+	role, err := ParseRole(stored.Role)
+	if err != nil {
+		return nil, apperr.Mask(fmt.Errorf("API key %q: %v", stored.Name, err))
+	}
+	return &Principal{Subject: "api-key:" + stored.Name, Method: MethodAPIKey, Role: role}, nil
+}
+
+// Middleware attaches the caller of each request to its context. Requests
+// without credentials pass through anonymously; resolvers decide what they
+// may do. Requests with invalid credentials are answered with 401 and an
+// UNAUTHENTICATED error in GraphQL's response shape.
+func (a *Authenticator) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		principal, err := a.Authenticate(r)
+		if err != nil {
+			writeUnauthenticated(w, err)
+			return
+		}
+		if principal != nil {
+			r = r.WithContext(WithPrincipal(r.Context(), principal))
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func writeUnauthenticated(w http.ResponseWriter, err error) {
+	appErr, ok := err.(*apperr.Error)
+	if !ok {
+		appErr = apperr.New(apperr.Unauthenticated, "invalid credentials: %v", err)
+	}
+	status := http.StatusUnauthorized
+	if appErr.Code == apperr.Internal {
+		status = http.StatusInternalServerError
+	} else {
+		w.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+	}
+	apperr.WriteHTTP(w, status, appErr)
+}
@@ -0,0 +1,249 @@
+package auth_test
+
+import (
+	"context"
+	"crypto"
+	"crypto/ed25519"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"movie-app/internal/auth"
+	"movie-app/internal/models"
+	"movie-app/internal/store"
+)
+
+var b64 = base64.RawURLEncoding
+
+// sign builds a JWT with the given header and claims, signed by key: a
+// []byte HMAC secret, an *rsa.PrivateKey or an ed25519.PrivateKey.
+func sign(t *testing.T, header, claims map[string]interface{}, key interface{}) string {
+	t.Helper()
+
+	segment := func(v interface{}) string {
+		data, err := json.Marshal(v)
+		if err != nil {
+			t.Fatalf("failed to encode token: %v", err)
+		}
+		return b64.EncodeToString(data)
+	}
+	signed := segment(header) + "." + segment(claims)
+
+	var signature []byte
+	switch key := key.(type) {
+	case []byte:
+		mac := hmac.New(sha256.New, key)
+		mac.Write([]byte(signed))
+		signature = mac.Sum(nil)
+	case *rsa.PrivateKey:
+		digest := sha256.Sum256([]byte(signed))
+		var err error
+		if signature, err = rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:]); err != nil {
+			t.Fatalf("failed to sign token: %v", err)
+		}
+	case ed25519.PrivateKey:
+		signature = ed25519.Sign(key, []byte(signed))
+	}
+	return signed + "." + b64.EncodeToString(signature)
+}
+
+func validClaims() map[string]interface{} {
+	return map[string]interface{}{"sub": "alice", "exp": time.Now().Add(time.Hour).Unix()}
+}
+
+func pemPublicKey(t *testing.T, public interface{}) []byte {
+	t.Helper()
+	der, err := x509.MarshalPKIXPublicKey(public)
+	if err != nil {
+		t.Fatalf("failed to marshal public key: %v", err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})
+}
+
+func TestVerifierAlgorithms(t *testing.T) {
+	secret := []byte("0123456789abcdef0123456789abcdef")
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("failed to generate RSA key: %v", err)
+	}
+	edPublic, edPrivate, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate Ed25519 key: %v", err)
+	}
+
+	publicKeys, err := auth.ParsePublicKeys(append(pemPublicKey(t, &rsaKey.PublicKey), pemPublicKey(t, edPublic)...))
+	if err != nil || len(publicKeys) != 2 {
+		t.Fatalf("expected 2 PEM keys, got %v (err %v)", publicKeys, err)
+	}
+	verifier, err := auth.NewVerifier(auth.VerifierConfig{
+		Keys:     append(publicKeys, auth.HMACKey("", secret)),
+		Issuer:   "https://issuer.example",
+		Audience: "movie-app",
+	})
+	if err != nil {
+		t.Fatalf("failed to create verifier: %v", err)
+	}
+
+	claims := validClaims()
+	claims["iss"] = "https://issuer.example"
+	claims["aud"] = []string{"other", "movie-app"}
+	for alg, key := range map[string]interface{}{"HS256": secret, "RS256": rsaKey, "EdDSA": edPrivate} {
+		principal, err := verifier.Verify(sign(t, map[string]interface{}{"alg": alg, "typ": "JWT"}, claims, key))
+		if err != nil || principal.Subject != "alice" || principal.Method != auth.MethodJWT {
+			t.Fatalf("expected a valid %s token, got %+v (err %v)", alg, principal, err)
+		}
+	}
+
+	for _, tc := range []struct {
+		claims map[string]interface{}
+		want   auth.Role
+	}{
+		{map[string]interface{}{}, auth.Viewer},
+		{map[string]interface{}{"role": "reviewer"}, auth.Reviewer},
+		{map[string]interface{}{"role": "editor", "roles": []string{"auditor", "admin"}}, auth.Admin},
+		{map[string]interface{}{"role": "superuser"}, auth.Viewer},
+	} {
+		roleClaims := validClaims()
+		for k, v := range claims {
+			roleClaims[k] = v
+		}
+		for k, v := range tc.claims {
+			roleClaims[k] = v
+		}
+		principal, err := verifier.Verify(sign(t, map[string]interface{}{"alg": "HS256"}, roleClaims, secret))
+		if err != nil || principal.Role != tc.want {
+			t.Errorf("claims %v: expected role %s, got %+v (err %v)", tc.claims, tc.want, principal, err)
+		}
+	}
+
+	expired := validClaims()
+	expired["exp"] = time.Now().Add(-time.Hour).Unix()
+	noAudience := validClaims()
+	noAudience["iss"] = "https://issuer.example"
+	wrongKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	valid := sign(t, map[string]interface{}{"alg": "RS256"}, claims, rsaKey)
+
+	for name, tc := range map[string]struct {
+		token, want string
+	}{
+		"expired":      {sign(t, map[string]interface{}{"alg": "HS256"}, expired, secret), "expired"},
+		"wrong issuer": {sign(t, map[string]interface{}{"alg": "HS256"}, validClaims(), secret), "issuer"},
+		"no audience":  {sign(t, map[string]interface{}{"alg": "HS256"}, noAudience, secret), "audience"},
+		"unknown key":  {sign(t, map[string]interface{}{"alg": "RS256"}, claims, wrongKey), "signature is invalid"},
+		"tampered":     {valid[:len(valid)-4] + "AAAA", "signature is invalid"},
+		"alg none":     {sign(t, map[string]interface{}{"alg": "none"}, claims, nil), "signature is invalid"},
+		"malformed":    {"not-a-token", "malformed"},
+		// The RSA public key used as an HMAC secret must not verify.
+		"alg confusion": {sign(t, map[string]interface{}{"alg": "HS256"}, claims, pemPublicKey(t, &rsaKey.PublicKey)), "signature is invalid"},
+	} {
+		if _, err := verifier.Verify(tc.token); err == nil || !strings.Contains(err.Error(), tc.want) {
+			t.Errorf("%s: expected an error containing %q, got %v", name, tc.want, err)
+		}
+	}
+}
+
+func TestParseJWKS(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("failed to generate RSA key: %v", err)
+	}
+	edPublic, edPrivate, _ := ed25519.GenerateKey(rand.Reader)
+
+	jwks := fmt.Sprintf(`{"keys": [
+		{"kty": "RSA", "kid": "rsa-1", "alg": "RS256", "use": "sig", "n": %q, "e": %q},
+		{"kty": "OKP", "kid": "ed-1", "crv": "Ed25519", "x": %q},
+		{"kty": "RSA", "kid": "enc-1", "use": "enc", "n": "AQAB", "e": "AQAB"}
+	]}`, b64.EncodeToString(rsaKey.N.Bytes()), b64.EncodeToString(big.NewInt(int64(rsaKey.E)).Bytes()), b64.EncodeToString(edPublic))
+
+	keys, err := auth.ParseJWKS([]byte(jwks))
+	if err != nil || len(keys) != 2 {
+		t.Fatalf("expected the 2 signing keys, got %v (err %v)", keys, err)
+	}
+	verifier, err := auth.NewVerifier(auth.VerifierConfig{Keys: keys})
+	if err != nil {
+		t.Fatalf("failed to create verifier: %v", err)
+	}
+
+	if _, err := verifier.Verify(sign(t, map[string]interface{}{"alg": "RS256", "kid": "rsa-1"}, validClaims(), rsaKey)); err != nil {
+		t.Fatalf("expected the RS256 token to verify, got %v", err)
+	}
+	if _, err := verifier.Verify(sign(t, map[string]interface{}{"alg": "EdDSA", "kid": "ed-1"}, validClaims(), edPrivate)); err != nil {
+		t.Fatalf("expected the EdDSA token to verify, got %v", err)
+	}
+	// A kid naming another key is not tried against this one.
+	if _, err := verifier.Verify(sign(t, map[string]interface{}{"alg": "RS256", "kid": "ed-1"}, validClaims(), rsaKey)); err == nil {
+		t.Fatalf("expected a mismatched kid to fail")
+	}
+
+	if _, err := auth.ParseJWKS([]byte(`{"keys": [{"kty": "OKP", "crv": "Ed25519", "alg": "RS256", "x": "` +
+		b64.EncodeToString(edPublic) + `"}]}`)); err == nil {
+		t.Fatalf("expected an alg that does not fit the key type to be rejected")
+	}
+}
+
+type fakeAPIKeys map[string]*models.APIKey
+
+func (f fakeAPIKeys) APIKeyByHash(ctx context.Context, hash string) (*models.APIKey, error) {
+	if key, ok := f[hash]; ok {
+		return key, nil
+	}
+	return nil, store.ErrNotFound
+}
+
+func TestMiddleware(t *testing.T) {
+	key, prefix, hash, err := auth.NewAPIKey()
+	if err != nil {
+		t.Fatalf("failed to generate API key: %v", err)
+	}
+	if !strings.HasPrefix(key, auth.APIKeyPrefix) || !strings.HasPrefix(key, prefix) || hash != auth.HashAPIKey(key) {
+		t.Fatalf("unexpected API key %q (prefix %q, hash %q)", key, prefix, hash)
+	}
+
+	secret := []byte("0123456789abcdef0123456789abcdef")
+	verifier, _ := auth.NewVerifier(auth.VerifierConfig{Keys: []auth.Key{auth.HMACKey("", secret)}})
+	authn := &auth.Authenticator{JWT: verifier, APIKeys: fakeAPIKeys{hash: {Name: "importer", Role: "editor"}}}
+
+	handler := authn.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if principal, ok := auth.PrincipalFrom(r.Context()); ok {
+			fmt.Fprint(w, principal.Method+" "+principal.Subject+" "+principal.Role.String())
+		} else {
+			fmt.Fprint(w, "anonymous")
+		}
+	}))
+
+	for _, tc := range []struct {
+		header, value string
+		status        int
+		body          string
+	}{
+		{"", "", http.StatusOK, "anonymous"},
+		{"X-API-Key", key, http.StatusOK, "api_key api-key:importer editor"},
+		{"Authorization", "Bearer " + key, http.StatusOK, "api_key api-key:importer editor"},
+		{"Authorization", "Bearer " + sign(t, map[string]interface{}{"alg": "HS256"}, validClaims(), secret), http.StatusOK, "jwt alice viewer"},
+		{"X-API-Key", auth.APIKeyPrefix + "revoked", http.StatusUnauthorized, `"code":"UNAUTHENTICATED"`},
+		{"Authorization", "Basic YWxpY2U6c2VjcmV0", http.StatusUnauthorized, "Bearer"},
+		{"Authorization", "Bearer not-a-token", http.StatusUnauthorized, "malformed"},
+	} {
+		r := httptest.NewRequest(http.MethodPost, "/graphql", nil)
+		if tc.header != "" {
+			r.Header.Set(tc.header, tc.value)
+		}
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		if w.Code != tc.status || !strings.Contains(w.Body.String(), tc.body) {
+			t.Errorf("%s %q: expected %d with %q, got %d %s", tc.header, tc.value, tc.status, tc.body, w.Code, w.Body)
+		}
+	}
+}
@@ -0,0 +1,204 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/hmac"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Supported JWT signing algorithms.
+const (
+	HS256 = "HS256"
+	RS256 = "RS256"
+	EdDSA = "EdDSA"
+)
+
+// Key verifies tokens signed with one algorithm. ID is matched against a
+// token's kid header; a key without an ID is tried whatever the kid.
+type Key struct {
+	ID        string
+	Algorithm string
+	// key is a []byte secret for HS256, an *rsa.PublicKey for RS256 and an
+	// ed25519.PublicKey for EdDSA.
+	key interface{}
+}
+
+// HMACKey returns a key for HS256 tokens signed with secret.
+func HMACKey(id string, secret []byte) Key {
+	return Key{ID: id, Algorithm: HS256, key: secret}
+}
+
+func (k Key) verify(signed string, signature []byte) bool {
+	switch key := k.key.(type) {
+	case []byte:
+		mac := hmac.New(sha256.New, key)
+		mac.Write([]byte(signed))
+		return hmac.Equal(mac.Sum(nil), signature)
+	case *rsa.PublicKey:
+		digest := sha256.Sum256([]byte(signed))
+		return rsa.VerifyPKCS1v15(key, crypto.SHA256, digest[:], signature) == nil
+	case ed25519.PublicKey:
+		return ed25519.Verify(key, []byte(signed), signature)
+	}
+	return false
+}
+
+type VerifierConfig struct {
+	Keys []Key
+	// Issuer and Audience, when set, must match the iss claim and one of
+	// the aud claims.
+	Issuer   string
+	Audience string
+	// Leeway allows for clock skew when checking exp and nbf.
+	Leeway time.Duration
+}
+
+// Verifier checks JWTs against locally configured keys. Each key is bound
+// to one algorithm, so a token cannot pick how it is verified, and "none"
+// is never accepted.
+type Verifier struct {
+	cfg VerifierConfig
+	now func() time.Time
+}
+
+func NewVerifier(cfg VerifierConfig) (*Verifier, error) {
+	if len(cfg.Keys) == 0 {
+		return nil, errors.New("no JWT verification keys are configured")
+	}
+	for _, k := range cfg.Keys {
+		if k.Algorithm != HS256 && k.Algorithm != RS256 && k.Algorithm != EdDSA {
+			return nil, fmt.Errorf("key %q: unsupported algorithm %q", k.ID, k.Algorithm)
+		}
+	}
+	return &Verifier{cfg: cfg, now: time.Now}, nil
+}
+
+type jwtHeader struct {
+	Alg string `json:"alg"`
+	Kid string `json:"kid"`
+}
+
+type jwtClaims struct {
+	Subject   string          `json:"sub"`
+	Issuer    string          `json:"iss"`
+	Audience  json.RawMessage `json:"aud"`
+	ExpiresAt *float64        `json:"exp"`
+	NotBefore *float64        `json:"nbf"`
+	Role      string          `json:"role"`
+	Roles     []string        `json:"roles"`
+}
+
+// role is the highest known role named by the role and roles claims, or
+// Viewer when there is none.
+func (c jwtClaims) role() Role {
+	role := Viewer
+	for _, name := range append(c.Roles, c.Role) {
+		if r, err := ParseRole(name); err == nil && r > role {
+			role = r
+		}
+	}
+	return role
+}
+
+// Verify checks token's signature and claims and returns its subject and
+// role as a Principal. Tokens must carry sub and exp.
+func (v *Verifier) Verify(token string) (*Principal, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, errors.New("token is malformed")
+	}
+
+	var header jwtHeader
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return nil, fmt.Errorf("token header is malformed: %v", err)
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, errors.New("token signature is malformed")
+	}
+	if !v.verifySignature(header, parts[0]+"."+parts[1], signature) {
+		return nil, errors.New("token signature is invalid")
+	}
+
+	var claims jwtClaims
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return nil, fmt.Errorf("token claims are malformed: %v", err)
+	}
+	if err := v.checkClaims(claims); err != nil {
+		return nil, err
+	}
+	return &Principal{Subject: claims.Subject, Method: MethodJWT, Role: claims.role()}, nil
+}
+
+func (v *Verifier) verifySignature(header jwtHeader, signed string, signature []byte) bool {
+	for _, k := range v.cfg.Keys {
+		if k.Algorithm != header.Alg || (k.ID != "" && header.Kid != "" && k.ID != header.Kid) {
+			continue
+		}
+		if k.verify(signed, signature) {
+			return true
+		}
+	}
+	return false
+}
+
+func (v *Verifier) checkClaims(claims jwtClaims) error {
+	now := v.now()
+	if claims.Subject == "" {
+		return errors.New("token has no sub claim")
+	}
+	if claims.ExpiresAt == nil {
+		return errors.New("token has no exp claim")
+	}
+	if now.After(unixTime(*claims.ExpiresAt).Add(v.cfg.Leeway)) {
+		return errors.New("token is expired")
+	}
+	if claims.NotBefore != nil && now.Add(v.cfg.Leeway).Before(unixTime(*claims.NotBefore)) {
+		return errors.New("token is not valid yet")
+	}
+	if v.cfg.Issuer != "" && claims.Issuer != v.cfg.Issuer {
+		return errors.New("token has the wrong issuer")
+	}
+	if v.cfg.Audience != "" && !hasAudience(claims.Audience, v.cfg.Audience) {
+		return errors.New("token has the wrong audience")
+	}
+	return nil
+}
+
+// hasAudience reports whether aud, a string or an array of strings, names
+// audience.
+func hasAudience(aud json.RawMessage, audience string) bool {
+	var one string
+	if json.Unmarshal(aud, &one) == nil {
+		return one == audience
+	}
+	var many []string
+	if json.Unmarshal(aud, &many) == nil {
+		for _, a := range many {
+			if a == audience {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func unixTime(seconds float64) time.Time {
+	return time.Unix(0, int64(seconds*float64(time.Second)))
+}
+
+func decodeSegment(segment string, v interface{}) error {
+	data, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
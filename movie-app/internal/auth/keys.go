@@ -0,0 +1,136 @@
+package auth
+
+import (
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+)
+
+// minRSABits is the smallest RSA key accepted for RS256.
+const minRSABits = 2048
+
+// ParsePublicKeys reads the PKIX "PUBLIC KEY" blocks of PEM data. RSA keys
+// verify RS256 tokens and Ed25519 keys EdDSA ones. The keys have no ID, so
+// they are tried for any kid.
+func ParsePublicKeys(data []byte) ([]Key, error) {
+	var keys []Key
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			break
+		}
+		if block.Type != "PUBLIC KEY" {
+			return nil, fmt.Errorf("unsupported PEM block %q, expected PUBLIC KEY", block.Type)
+		}
+		public, err := x509.ParsePKIXPublicKey(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse public key: %v", err)
+		}
+		key, err := publicKey("", public)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+	if len(keys) == 0 {
+		return nil, errors.New("no PEM public keys found")
+	}
+	return keys, nil
+}
+
+func publicKey(id string, public interface{}) (Key, error) {
+	switch public := public.(type) {
+	case *rsa.PublicKey:
+		if public.N.BitLen() < minRSABits {
+			return Key{}, fmt.Errorf("RSA key %q has %d bits, fewer than %d", id, public.N.BitLen(), minRSABits)
+		}
+		return Key{ID: id, Algorithm: RS256, key: public}, nil
+	case ed25519.PublicKey:
+		return Key{ID: id, Algorithm: EdDSA, key: public}, nil
+	}
+	return Key{}, fmt.Errorf("unsupported public key type %T", public)
+}
+
+// jwk is one key of a JSON Web Key Set (RFC 7517).
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Alg string `json:"alg"`
+	Use string `json:"use"`
+	// RSA
+	N string `json:"n"`
+	E string `json:"e"`
+	// OKP
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	// oct
+	K string `json:"k"`
+}
+
+// ParseJWKS reads a JSON Web Key Set. RSA keys verify RS256, OKP Ed25519
+// keys EdDSA and oct keys HS256. Keys meant for encryption are skipped.
+func ParseJWKS(data []byte) ([]Key, error) {
+	var set struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err := json.Unmarshal(data, &set); err != nil {
+		return nil, fmt.Errorf("failed to parse JWKS: %v", err)
+	}
+
+	var keys []Key
+	for i, k := range set.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+		key, err := k.key()
+		if err != nil {
+			return nil, fmt.Errorf("JWKS key %d (%q): %v", i, k.Kid, err)
+		}
+		if k.Alg != "" && k.Alg != key.Algorithm {
+			return nil, fmt.Errorf("JWKS key %d (%q): alg %s does not fit a %s key", i, k.Kid, k.Alg, k.Kty)
+		}
+		keys = append(keys, key)
+	}
+	if len(keys) == 0 {
+		return nil, errors.New("JWKS has no signing keys")
+	}
+	return keys, nil
+}
+
+func (k jwk) key() (Key, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := base64.RawURLEncoding.DecodeString(k.N)
+		if err != nil {
+			return Key{}, fmt.Errorf("invalid n: %v", err)
+		}
+		e, err := base64.RawURLEncoding.DecodeString(k.E)
+		if err != nil || len(e) == 0 || len(e) > 4 {
+			return Key{}, errors.New("invalid e")
+		}
+		return publicKey(k.Kid, &rsa.PublicKey{
+			N: new(big.Int).SetBytes(n),
+			E: int(new(big.Int).SetBytes(e).Int64()),
+		})
+	case "OKP":
+		x, err := base64.RawURLEncoding.DecodeString(k.X)
+		if k.Crv != "Ed25519" || err != nil || len(x) != ed25519.PublicKeySize {
+			return Key{}, errors.New("only Ed25519 OKP keys are supported")
+		}
+		return publicKey(k.Kid, ed25519.PublicKey(x))
+	case "oct":
+		secret, err := base64.RawURLEncoding.DecodeString(k.K)
+		if err != nil || len(secret) == 0 {
+			return Key{}, errors.New("invalid k")
+		}
+		return HMACKey(k.Kid, secret), nil
+	}
+	return Key{}, fmt.Errorf("unsupported kty %q", k.Kty)
+}
@@ -0,0 +1,44 @@
+package auth
+
+import "fmt"
+
+// Role is what a caller may do. Roles are ordered: each one may do
+// everything the roles before it may.
+type Role int
+
+const (
+	// RoleNone is below every role; no principal has it.
+	RoleNone Role = iota
+	// Viewer reads. Anonymous callers are viewers.
+	Viewer
+	// Reviewer also writes reviews and deletes their own.
+	Reviewer
+	// Editor also changes movies, actors and casts and moderates reviews.
+	Editor
+	// Admin also purges the trash and imports movies with their details.
+	Admin
+)
+
+var roleNames = map[Role]string{
+	Viewer:   "viewer",
+	Reviewer: "reviewer",
+	Editor:   "editor",
+	Admin:    "admin",
+}
+
+func (r Role) String() string {
+	if name, ok := roleNames[r]; ok {
+		return name
+	}
+	return "none"
+}
+
+// ParseRole returns the role named name, such as "editor".
+func ParseRole(name string) (Role, error) {
+	for role, n := range roleNames {
+		if n == name {
+			return role, nil
+		}
+	}
+	return RoleNone, fmt.Errorf("unknown role %q; expected viewer, reviewer, editor or admin", name)
+}
@@ -0,0 +1,247 @@
+// Package complexity scores GraphQL operations before they run, so queries
+// that would fetch too much, such as deeply nested lists of large pages, are
+// rejected instead of tying up the database.
+package complexity
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/language/ast"
+)
+
+// Config sets how operations are scored and what they may cost.
+type Config struct {
+	// MaxDepth is how deeply fields may nest; root fields are at depth 1.
+	MaxDepth int
+	// MaxCost caps an operation's cost, roughly the number of fields it
+	// may resolve.
+	MaxCost int
+	// MaxListSize is the most items a limit, first or last argument
+	// fetches. It should match what the resolvers clamp those arguments to.
+	MaxListSize int
+	// DefaultListSize is the size assumed for a list fetched without one
+	// of those arguments.
+	DefaultListSize int
+}
+
+// sizeArgs are the arguments that set how many items a field fetches.
+var sizeArgs = []string{"limit", "first", "last"}
+
+// Report is the score of one operation.
+type Report struct {
+	Depth int
+	Cost  int
+}
+
+// analyzer scores one operation of a document.
+type analyzer struct {
+	schema    *graphql.Schema
+	config    Config
+	fragments map[string]*ast.FragmentDefinition
+	variables map[string]interface{}
+}
+
+// Analyze scores the operation of doc named operationName, or its first
+// operation if operationName is empty. Fields the schema does not know are
+// skipped; validation reports them once the operation runs. Introspection
+// is not scored, since its size is bounded by the schema.
+func Analyze(schema *graphql.Schema, doc *ast.Document, operationName string, variables map[string]interface{}, config Config) Report {
+	a := &analyzer{
+		schema:    schema,
+		config:    config,
+		fragments: map[string]*ast.FragmentDefinition{},
+		variables: map[string]interface{}{},
+	}
+
+	var operation *ast.OperationDefinition
+	for _, def := range doc.Definitions {
+		switch def := def.(type) {
+		case *ast.OperationDefinition:
+			named := def.Name != nil && def.Name.Value == operationName
+			if operation == nil && (operationName == "" || named) {
+				operation = def
+			}
+		case *ast.FragmentDefinition:
+			a.fragments[def.Name.Value] = def
+		}
+	}
+	if operation == nil {
+		return Report{}
+	}
+
+	for _, def := range operation.VariableDefinitions {
+		if def.DefaultValue != nil {
+			a.variables[def.Variable.Name.Value] = def.DefaultValue.GetValue()
+		}
+	}
+	for name, value := range variables {
+		a.variables[name] = value
+	}
+
+	var root *graphql.Object
+	switch operation.Operation {
+	case ast.OperationTypeMutation:
+		root = schema.MutationType()
+	case ast.OperationTypeSubscription:
+		root = schema.SubscriptionType()
+	default:
+		root = schema.QueryType()
+	}
+	if root == nil {
+		return Report{}
+	}
+	depth, cost := a.selections(operation.SelectionSet, root, 0, map[string]bool{})
+	return Report{Depth: depth, Cost: cost}
+}
+
+// selections scores the fields of set, selected on parent. pending is the
+// page size set by the field that owns set, for the first list below it,
+// such as the movies of a MoviesResult or the edges of a connection.
+// spread holds the fragments being expanded, to stop at cycles.
+func (a *analyzer) selections(set *ast.SelectionSet, parent graphql.Type, pending int, spread map[string]bool) (depth, cost int) {
+	if set == nil {
+		return 0, 0
+	}
+	for _, selection := range set.Selections {
+		var d, c int
+		switch s := selection.(type) {
+		case *ast.Field:
+			d, c = a.field(s, parent, pending, spread)
+		case *ast.InlineFragment:
+			d, c = a.selections(s.SelectionSet, a.typeCondition(s.TypeCondition, parent), pending, spread)
+		case *ast.FragmentSpread:
+			name := s.Name.Value
+			fragment, ok := a.fragments[name]
+			if !ok || spread[name] {
+				continue
+			}
+			spread[name] = true
+			d, c = a.selections(fragment.SelectionSet, a.typeCondition(fragment.TypeCondition, parent), pending, spread)
+			delete(spread, name)
+		}
+		if d > depth {
+			depth = d
+		}
+		cost += c
+	}
+	return depth, cost
+}
+
+// field scores one field: 1 for each value it resolves plus the cost of
+// their fields, multiplied by its page size if it is a list. Leaf fields
+// cost 1 too, so a flood of aliased scalar fields, such as deleteMovie
+// mutations, adds up like any other.
+func (a *analyzer) field(field *ast.Field, parent graphql.Type, pending int, spread map[string]bool) (depth, cost int) {
+	name := field.Name.Value
+	if strings.HasPrefix(name, "__") {
+		return 0, 0
+	}
+	def := fieldDefinition(parent, name)
+	if def == nil {
+		return 0, 0
+	}
+
+	fieldType := def.Type
+	if nonNull, ok := fieldType.(*graphql.NonNull); ok {
+		fieldType = nonNull.OfType
+	}
+	_, isList := fieldType.(*graphql.List)
+	named := graphql.GetNamed(fieldType)
+	if !graphql.IsCompositeType(named) {
+		return 1, 1
+	}
+
+	size, sized := a.size(field, def)
+	multiplier, childPending := 1, 0
+	switch {
+	case sized && isList:
+		multiplier = size
+	case sized:
+		childPending = size
+	case isList && pending > 0:
+		multiplier = pending
+	case isList:
+		multiplier = a.config.DefaultListSize
+	}
+	childDepth, childCost := a.selections(field.SelectionSet, named.(graphql.Type), childPending, spread)
+	return childDepth + 1, multiplier * (1 + childCost)
+}
+
+// size returns the page size a field asks for, clamped to MaxListSize, if
+// the field takes one of sizeArgs.
+func (a *analyzer) size(field *ast.Field, def *graphql.FieldDefinition) (int, bool) {
+	takesSize := false
+	for _, arg := range def.Args {
+		for _, name := range sizeArgs {
+			takesSize = takesSize || arg.Name() == name
+		}
+	}
+	if !takesSize {
+		return 0, false
+	}
+
+	size := a.config.DefaultListSize
+	for _, arg := range field.Arguments {
+		for _, name := range sizeArgs {
+			if arg.Name.Value != name {
+				continue
+			}
+			if n, ok := a.intValue(arg.Value); ok {
+				size = n
+			}
+		}
+	}
+	if size > a.config.MaxListSize {
+		size = a.config.MaxListSize
+	}
+	if size < 1 {
+		size = 1
+	}
+	return size, true
+}
+
+// intValue reads an Int argument written inline or passed in a variable.
+func (a *analyzer) intValue(value ast.Value) (int, bool) {
+	switch v := value.(type) {
+	case *ast.IntValue:
+		n, err := strconv.Atoi(v.Value)
+		return n, err == nil
+	case *ast.Variable:
+		switch n := a.variables[v.Name.Value].(type) {
+		case int:
+			return n, true
+		case float64:
+			return int(n), true
+		case string:
+			// Variable defaults are read from the document as strings.
+			i, err := strconv.Atoi(n)
+			return i, err == nil
+		}
+	}
+	return 0, false
+}
+
+// typeCondition is the type a fragment selects on, or parent if it names
+// none.
+func (a *analyzer) typeCondition(condition *ast.Named, parent graphql.Type) graphql.Type {
+	if condition == nil || condition.Name == nil {
+		return parent
+	}
+	if t := a.schema.Type(condition.Name.Value); t != nil {
+		return t
+	}
+	return parent
+}
+
+// fieldDefinition looks name up on an object or interface type.
+func fieldDefinition(parent graphql.Type, name string) *graphql.FieldDefinition {
+	switch t := parent.(type) {
+	case *graphql.Object:
+		return t.Fields()[name]
+	case *graphql.Interface:
+		return t.Fields()[name]
+	}
+	return nil
+}
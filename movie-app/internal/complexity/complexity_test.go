@@ -0,0 +1,174 @@
+package complexity
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"movie-app/internal/request"
+	"movie-app/internal/resolvers"
+
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/language/parser"
+)
+
+var testConfig = Config{MaxDepth: 6, MaxCost: 5000, MaxListSize: 100, DefaultListSize: 10}
+
+func newTestSchema(t *testing.T) *graphql.Schema {
+	t.Helper()
+	// Only the types are needed; no resolver runs.
+	schema, err := resolvers.CreateSchema(nil)
+	if err != nil {
+		t.Fatalf("failed to create schema: %v", err)
+	}
+	return &schema
+}
+
+func TestAnalyze(t *testing.T) {
+	schema := newTestSchema(t)
+
+	tests := []struct {
+		name          string
+		query         string
+		operationName string
+		variables     map[string]interface{}
+		want          Report
+	}{
+		{
+			name:  "single movie",
+			query: `{ movie(id: "1") { title director } }`,
+			want:  Report{Depth: 2, Cost: 3},
+		},
+		{
+			name:  "page weighted by limit",
+			query: `{ movies(limit: 100) { movies { title actors { name } reviews { rating } } pagination { total } } }`,
+			want:  Report{Depth: 4, Cost: 4203},
+		},
+		{
+			name:  "limit clamped to the maximum",
+			query: `{ movies(limit: 10000) { movies { title actors { name } reviews { rating } } pagination { total } } }`,
+			want:  Report{Depth: 4, Cost: 4203},
+		},
+		{
+			name:  "default page size",
+			query: `{ movies { movies { title } } }`,
+			want:  Report{Depth: 3, Cost: 21},
+		},
+		{
+			name:  "connection weighted by first",
+			query: `{ moviesConnection(first: 5) { edges { node { title } } totalCount } }`,
+			want:  Report{Depth: 4, Cost: 17},
+		},
+		{
+			name:  "limit from a variable default",
+			query: `query($n: Int = 50) { movies(limit: $n) { movies { title } } }`,
+			want:  Report{Depth: 3, Cost: 101},
+		},
+		{
+			name:      "limit from a variable",
+			query:     `query($n: Int = 50) { movies(limit: $n) { movies { title } } }`,
+			variables: map[string]interface{}{"n": float64(3)},
+			want:      Report{Depth: 3, Cost: 7},
+		},
+		{
+			name:  "fragments",
+			query: `{ ...Q } fragment Q on Query { movie(id: "1") { ... on Movie { actors { name } } } }`,
+			want:  Report{Depth: 3, Cost: 21},
+		},
+		{
+			name:          "named operation",
+			query:         `query A { movie(id: "1") { title } } query B { movies { movies { title } } }`,
+			operationName: "B",
+			want:          Report{Depth: 3, Cost: 21},
+		},
+		{
+			name:  "nested lists",
+			query: `{ movie(id: "1") { actors { filmography { movie { actors { filmography { movie { title } } } } } } } }`,
+			want:  Report{Depth: 8, Cost: 31211},
+		},
+		{
+			name:  "scalar mutations",
+			query: `mutation { a: deleteMovie(id: "1") b: deleteMovie(id: "2") c: deleteReview(id: "3") }`,
+			want:  Report{Depth: 1, Cost: 3},
+		},
+		{
+			name:  "introspection is free",
+			query: `{ __schema { types { name fields { name type { name ofType { name ofType { name } } } } } } }`,
+			want:  Report{},
+		},
+	}
+	for _, tt := range tests {
+		doc, err := parser.Parse(parser.ParseParams{Source: tt.query})
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if got := Analyze(schema, doc, tt.operationName, tt.variables, testConfig); got != tt.want {
+			t.Errorf("%s: Analyze = %+v, want %+v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestMiddleware(t *testing.T) {
+	guard := New(newTestSchema(t), testConfig)
+	h := request.Middleware(1<<20, guard.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// The handler still gets the body that was parsed.
+		if body, _ := io.ReadAll(r.Body); len(body) == 0 {
+			t.Errorf("handler got an empty body")
+		}
+		w.WriteHeader(http.StatusOK)
+	})))
+
+	do := func(query string) (int, map[string]interface{}) {
+		body, _ := json.Marshal(map[string]string{"query": query})
+		r := httptest.NewRequest(http.MethodPost, "/graphql", strings.NewReader(string(body)))
+		r.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+
+		var resp struct {
+			Errors []struct {
+				Message    string                 `json:"message"`
+				Extensions map[string]interface{} `json:"extensions"`
+			} `json:"errors"`
+		}
+		json.NewDecoder(w.Body).Decode(&resp)
+		if len(resp.Errors) == 0 {
+			return w.Code, nil
+		}
+		return w.Code, resp.Errors[0].Extensions
+	}
+
+	for _, query := range []string{
+		`{ movies(limit: 100) { movies { title actors { name } } } }`,
+		`{ movies( }`,
+	} {
+		if code, _ := do(query); code != http.StatusOK {
+			t.Errorf("%s: status %d, want it passed on", query, code)
+		}
+	}
+
+	code, extensions := do(`{ movies(limit: 100) { movies { actors { filmography { movie { title } } } } } }`)
+	if code != http.StatusBadRequest || extensions["code"] != "QUERY_TOO_COMPLEX" || extensions["cost"] != float64(31101) || extensions["max_cost"] != float64(5000) {
+		t.Errorf("costly query: status %d, extensions %v", code, extensions)
+	}
+
+	var flood strings.Builder
+	flood.WriteString("mutation {")
+	for i := 0; i <= testConfig.MaxCost; i++ {
+		fmt.Fprintf(&flood, ` d%d: deleteMovie(id: "%d")`, i, i)
+	}
+	flood.WriteString(" }")
+	code, extensions = do(flood.String())
+	if code != http.StatusBadRequest || extensions["code"] != "QUERY_TOO_COMPLEX" || extensions["cost"] != float64(testConfig.MaxCost+1) {
+		t.Errorf("aliased mutation flood: status %d, extensions %v", code, extensions)
+	}
+
+	code, extensions = do(`{ movie(id: "1") { cast { actor { filmography { movie { cast { actor { name } } } } } } } }`)
+	if code != http.StatusBadRequest || extensions["code"] != "QUERY_TOO_COMPLEX" || extensions["depth"] != float64(8) {
+		t.Errorf("deep query: status %d, extensions %v", code, extensions)
+	}
+}
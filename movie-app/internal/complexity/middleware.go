@@ -0,0 +1,61 @@
+package complexity
+
+import (
+	"net/http"
+
+	"movie-app/internal/apperr"
+	"movie-app/internal/request"
+
+	"github.com/graphql-go/graphql"
+)
+
+// Guard rejects operations that nest too deeply or cost too much.
+type Guard struct {
+	schema *graphql.Schema
+	config Config
+}
+
+// New returns a Guard that scores operations against schema.
+func New(schema *graphql.Schema, config Config) *Guard {
+	return &Guard{schema: schema, config: config}
+}
+
+// Check returns a QUERY_TOO_COMPLEX error if report is over the limits,
+// reporting the score and the limits in its extensions.
+func (g *Guard) Check(report Report) *apperr.Error {
+	details := map[string]interface{}{
+		"depth":     report.Depth,
+		"max_depth": g.config.MaxDepth,
+		"cost":      report.Cost,
+		"max_cost":  g.config.MaxCost,
+	}
+	var e *apperr.Error
+	switch {
+	case g.config.MaxDepth > 0 && report.Depth > g.config.MaxDepth:
+		e = apperr.New(apperr.QueryTooComplex, "query depth %d exceeds the maximum of %d", report.Depth, g.config.MaxDepth)
+	case g.config.MaxCost > 0 && report.Cost > g.config.MaxCost:
+		e = apperr.New(apperr.QueryTooComplex, "query cost %d exceeds the maximum of %d; request smaller pages or fewer nested lists", report.Cost, g.config.MaxCost)
+	default:
+		return nil
+	}
+	e.Details = details
+	return e
+}
+
+// Middleware scores each request's operation before it runs and answers
+// those over the limits with 400 and a QUERY_TOO_COMPLEX error. It must run
+// after the request middleware, which parses the operation. Requests that
+// do not parse are passed on for the handler to report.
+func (g *Guard) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if parsed, ok := request.From(r.Context()); ok && parsed.Document != nil {
+			opts := parsed.Options
+			report := Analyze(g.schema, parsed.Document, opts.OperationName, opts.Variables, g.config)
+			if appErr := g.Check(report); appErr != nil {
+				apperr.WriteHTTP(w, http.StatusBadRequest, appErr)
+				return
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
@@ -1,26 +1,26 @@
 package database
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
 	"log"
 	"movie-app/internal/models"
+	"movie-app/internal/store"
 
-	"github.com/google/uuid"
 	_ "github.com/mattn/go-sqlite3"
 )
 
 var DB *sql.DB
 
+// DefaultDSN is the SQLite file used by the server and the migrate command.
+const DefaultDSN = "./movies.db"
+
 func InitDatabase() error {
 	var err error
-	DB, err = sql.Open("sqlite3", "./movies.db")
+	DB, err = Open(DefaultDSN)
 	if err != nil {
-		return fmt.Errorf("failed to open database: %v", err)
-	}
-
-	if err = createTables(); err != nil {
-		return fmt.Errorf("failed to create tables: %v", err)
+		return err
 	}
 
 	// Seed sample data
@@ -29,95 +29,37 @@ func InitDatabase() error {
 	return nil
 }
 
-func EnsureDirector(name string) (string, error) {
-	if name == "" {
-		return "", fmt.Errorf("director name is required")
-	}
-
-	var id string
-	err := DB.QueryRow("SELECT id FROM directors WHERE name = ?", name).Scan(&id)
-	if err == nil {
-		return id, nil
-	}
-	if err != sql.ErrNoRows {
-		return "", err
+// Open opens the SQLite database at dsn and applies any pending migrations.
+// It does not seed data.
+func Open(dsn string) (*sql.DB, error) {
+	db, err := Connect(dsn)
+	if err != nil {
+		return nil, err
 	}
 
-	id = uuid.New().String()
-	_, err = DB.Exec("INSERT INTO directors (id, name) VALUES (?, ?)", id, name)
+	applied, err := MigrateUp(db)
 	if err != nil {
-		err2 := DB.QueryRow("SELECT id FROM directors WHERE name = ?", name).Scan(&id)
-		if err2 == nil {
-			return id, nil
-		}
-		return "", err
+		db.Close()
+		return nil, fmt.Errorf("failed to migrate database: %v", err)
+	}
+	for _, m := range applied {
+		log.Printf("Applied migration %04d_%s", m.Version, m.Name)
 	}
 
-	return id, nil
+	return db, nil
 }
 
-func createTables() error {
-	moviesTable := `
-	CREATE TABLE IF NOT EXISTS movies (
-		id TEXT PRIMARY KEY,
-		title TEXT NOT NULL,
-		description TEXT,
-		year INTEGER,
-		rating REAL,
-		duration INTEGER,
-		genre TEXT,
-		director TEXT,
-		poster_url TEXT,
-		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
-		updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
-	);`
-
-	directorsTable := `
-	CREATE TABLE IF NOT EXISTS directors (
-		id TEXT PRIMARY KEY,
-		name TEXT NOT NULL UNIQUE
-	);`
-
-	actorsTable := `
-	CREATE TABLE IF NOT EXISTS actors (
-		id TEXT PRIMARY KEY,
-		name TEXT NOT NULL,
-		birth_date TEXT,
-		nationality TEXT,
-		biography TEXT,
-		profile_url TEXT
-	);`
-
-	movieActorsTable := `
-	CREATE TABLE IF NOT EXISTS movie_actors (
-		movie_id TEXT,
-		actor_id TEXT,
-		character_name TEXT,
-		FOREIGN KEY (movie_id) REFERENCES movies(id),
-		FOREIGN KEY (actor_id) REFERENCES actors(id),
-		PRIMARY KEY (movie_id, actor_id)
-	);`
-
-	reviewsTable := `
-	CREATE TABLE IF NOT EXISTS reviews (
-		id TEXT PRIMARY KEY,
-		movie_id TEXT,
-		user_name TEXT NOT NULL,
-		rating INTEGER CHECK(rating >= 1 AND rating <= 5),
-		comment TEXT,
-		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
-		FOREIGN KEY (movie_id) REFERENCES movies(id)
-	);`
-
-	tables := []string{moviesTable, directorsTable, actorsTable, movieActorsTable, reviewsTable}
-
-	for _, table := range tables {
-		if _, err := DB.Exec(table); err != nil {
-			return fmt.Errorf("failed to create table: %v", err)
-		}
+// Connect opens the SQLite database at dsn without touching its schema.
+func Connect(dsn string) (*sql.DB, error) {
+	db, err := sql.Open("sqlite3", dsn)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database: %v", err)
 	}
+	return db, nil
+}
 
-	return nil
+func EnsureDirector(name string) (string, error) {
+	return store.NewSQLiteStore(DB).EnsureDirector(context.Background(), name)
 }
 
 func seedData() {
@@ -143,12 +85,9 @@ func seedData() {
 		{ID: "10", Title: "Parasite", Description: "A poor family schemes to become employed by a wealthy household.", Year: 2019, Rating: 8.5, Duration: 132, Genre: "Thriller, Drama", Director: "Bong Joon-ho", PosterURL: "https://example.com/parasite.jpg"},
 	}
 
+	s := store.NewSQLiteStore(DB)
 	for _, movie := range movies {
-		if movie.Director != "" {
-			_, _ = EnsureDirector(movie.Director)
-		}
-
-		_, err := DB.Exec(
+		result, err := DB.Exec(
 			`INSERT OR IGNORE INTO movies (id, title, description, year, rating, duration, genre, director, poster_url)
 			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
 			movie.ID, movie.Title, movie.Description, movie.Year, movie.Rating,
@@ -156,6 +95,18 @@ func seedData() {
 		)
 		if err != nil {
 			log.Printf("Failed to insert movie: %v", err)
+			continue
+		}
+		if err := s.SetMovieDirectors(context.Background(), movie.ID, movie.Director); err != nil {
+			log.Printf("Failed to credit directors: %v", err)
+		}
+		if err := s.SetMovieGenres(context.Background(), movie.ID, movie.Genre); err != nil {
+			log.Printf("Failed to tag genres: %v", err)
+		}
+		if n, _ := result.RowsAffected(); n > 0 {
+			if err := recordSeedRevision(s, movie.ID); err != nil {
+				log.Printf("Failed to record movie history: %v", err)
+			}
 		}
 	}
 
@@ -180,19 +131,19 @@ func seedData() {
 	}
 
 	links := []models.MovieActor{
-		{MovieID: "1", ActorID: "a1", CharacterName: "Cobb"},
-		{MovieID: "2", ActorID: "a2", CharacterName: "Red"},
-		{MovieID: "3", ActorID: "a3", CharacterName: "Bruce Wayne"},
-		{MovieID: "8", ActorID: "a4", CharacterName: "Neo"},
-		{MovieID: "5", ActorID: "a5", CharacterName: "Tyler Durden"},
-		{MovieID: "7", ActorID: "a6", CharacterName: "Forrest Gump"},
+		{MovieID: "1", ActorID: "a1", CharacterName: "Cobb", BillingOrder: 1},
+		{MovieID: "2", ActorID: "a2", CharacterName: "Red", BillingOrder: 1},
+		{MovieID: "3", ActorID: "a3", CharacterName: "Bruce Wayne", BillingOrder: 1},
+		{MovieID: "8", ActorID: "a4", CharacterName: "Neo", BillingOrder: 1},
+		{MovieID: "5", ActorID: "a5", CharacterName: "Tyler Durden", BillingOrder: 1},
+		{MovieID: "7", ActorID: "a6", CharacterName: "Forrest Gump", BillingOrder: 1},
 	}
 
 	for _, link := range links {
 		_, err := DB.Exec(
-			`INSERT OR IGNORE INTO movie_actors (movie_id, actor_id, character_name)
-			VALUES (?, ?, ?)`,
-			link.MovieID, link.ActorID, link.CharacterName,
+			`INSERT OR IGNORE INTO movie_actors (movie_id, actor_id, character_name, billing_order)
+			VALUES (?, ?, ?, ?)`,
+			link.MovieID, link.ActorID, link.CharacterName, link.BillingOrder,
 		)
 		if err != nil {
 			log.Printf("Failed to insert movie_actors: %v", err)
@@ -207,11 +158,18 @@ func seedData() {
 		{ID: "r5", MovieID: "10", UserName: "eve", Rating: 5, Comment: "Masterpiece."},
 	}
 
+	// Sample reviewers are placeholder users, like the authors of reviews
+	// written before accounts existed.
 	for _, review := range reviews {
-		_, err := DB.Exec(
-			`INSERT OR IGNORE INTO reviews (id, movie_id, user_name, rating, comment)
+		user, err := s.EnsurePlaceholderUser(context.Background(), review.UserName)
+		if err != nil {
+			log.Printf("Failed to insert user: %v", err)
+			continue
+		}
+		_, err = DB.Exec(
+			`INSERT OR IGNORE INTO reviews (id, movie_id, user_id, rating, comment)
 			VALUES (?, ?, ?, ?, ?)`,
-			review.ID, review.MovieID, review.UserName, review.Rating, review.Comment,
+			review.ID, review.MovieID, user.ID, review.Rating, review.Comment,
 		)
 		if err != nil {
 			log.Printf("Failed to insert review: %v", err)
@@ -224,3 +182,18 @@ func CloseDatabase() {
 		DB.Close()
 	}
 }
+
+// recordSeedRevision starts a seeded movie's history with its inserted state.
+func recordSeedRevision(s *store.SQLiteStore, movieID string) error {
+	movie, err := s.GetMovie(context.Background(), movieID)
+	if err != nil {
+		return err
+	}
+	_, err = s.RecordMovieRevision(context.Background(), models.MovieRevision{
+		MovieID:  movieID,
+		Mutation: "seed",
+		Actor:    "system",
+		After:    movie,
+	})
+	return err
+}
@@ -2,6 +2,7 @@ package database
 
 import (
 	"database/sql"
+	"fmt"
 	"testing"
 
 	"github.com/google/uuid"
@@ -16,8 +17,8 @@ func TestInsertTenMoviesWithDetails(t *testing.T) {
 	}
 	defer DB.Close()
 
-	if err := createTables(); err != nil {
-		t.Fatalf("failed to create tables: %v", err)
+	if _, err := MigrateUp(DB); err != nil {
+		t.Fatalf("failed to migrate: %v", err)
 	}
 
 	type movieSeed struct {
@@ -82,13 +83,18 @@ func TestInsertTenMoviesWithDetails(t *testing.T) {
 			}
 		}
 
-		// 2 reviews per movie
+		// 2 reviews per movie, by different users
 		for i := 0; i < 2; i++ {
+			userID := fmt.Sprint("user-", i)
+			if _, err := DB.Exec(`INSERT OR IGNORE INTO users (id, name) VALUES (?, ?)`, userID, userID); err != nil {
+				t.Fatalf("failed to insert user: %v", err)
+			}
+
 			reviewID := uuid.New().String()
 			_, err := DB.Exec(
-				`INSERT INTO reviews (id, movie_id, user_name, rating, comment)
+				`INSERT INTO reviews (id, movie_id, user_id, rating, comment)
 				VALUES (?, ?, ?, ?, ?)`,
-				reviewID, m.id, "user", 5, "great",
+				reviewID, m.id, userID, 5, "great",
 			)
 			if err != nil {
 				t.Fatalf("failed to insert review for movie %s: %v", m.id, err)
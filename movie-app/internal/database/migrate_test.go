@@ -0,0 +1,406 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"movie-app/internal/store"
+
+	"github.com/google/uuid"
+)
+
+func openTestDB(t *testing.T) *sql.DB {
+	t.Helper()
+
+	db, err := Connect(filepath.Join(t.TempDir(), "movies.db"))
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+func tableExists(t *testing.T, db *sql.DB, name string) bool {
+	t.Helper()
+
+	var n int
+	err := db.QueryRow("SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = ?", name).Scan(&n)
+	if err != nil {
+		t.Fatalf("failed to inspect sqlite_master: %v", err)
+	}
+	return n > 0
+}
+
+func TestMigrateUpDownAndStatus(t *testing.T) {
+	db := openTestDB(t)
+
+	all, err := Migrations()
+	if err != nil {
+		t.Fatalf("failed to load migrations: %v", err)
+	}
+
+	applied, err := MigrateUp(db)
+	if err != nil {
+		t.Fatalf("failed to migrate up: %v", err)
+	}
+	if len(applied) != len(all) {
+		t.Fatalf("expected %d migrations applied, got %d", len(all), len(applied))
+	}
+	if !tableExists(t, db, "movies") {
+		t.Fatalf("expected movies table after migrating up")
+	}
+
+	// A second run is a no-op.
+	applied, err = MigrateUp(db)
+	if err != nil || len(applied) != 0 {
+		t.Fatalf("expected no pending migrations, got %v (err %v)", applied, err)
+	}
+
+	states, err := MigrationStatus(db)
+	if err != nil {
+		t.Fatalf("failed to read status: %v", err)
+	}
+	for _, s := range states {
+		if !s.Applied {
+			t.Fatalf("expected %04d_%s to be applied", s.Version, s.Name)
+		}
+	}
+
+	reverted, err := MigrateDown(db, len(all))
+	if err != nil {
+		t.Fatalf("failed to migrate down: %v", err)
+	}
+	if len(reverted) != len(all) || reverted[0].Version != all[len(all)-1].Version {
+		t.Fatalf("expected migrations to be reverted newest first, got %v", reverted)
+	}
+	if tableExists(t, db, "movies") {
+		t.Fatalf("expected movies table to be dropped")
+	}
+}
+
+func TestMigrateUpAdoptsLegacyDatabase(t *testing.T) {
+	db := openTestDB(t)
+
+	// Databases created before migrations existed already have the tables.
+	if _, err := db.Exec(`CREATE TABLE movies (id TEXT PRIMARY KEY, title TEXT NOT NULL, description TEXT,
+		year INTEGER, rating REAL, duration INTEGER, genre TEXT, director TEXT, poster_url TEXT,
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP, updated_at DATETIME DEFAULT CURRENT_TIMESTAMP)`); err != nil {
+		t.Fatalf("failed to create legacy table: %v", err)
+	}
+	if _, err := db.Exec(`INSERT INTO movies (id, title) VALUES ('1', 'Inception')`); err != nil {
+		t.Fatalf("failed to insert legacy row: %v", err)
+	}
+
+	if _, err := MigrateUp(db); err != nil {
+		t.Fatalf("failed to migrate legacy database: %v", err)
+	}
+
+	var title string
+	if err := db.QueryRow("SELECT title FROM movies WHERE id = '1'").Scan(&title); err != nil || title != "Inception" {
+		t.Fatalf("expected legacy row to survive, got %q (err %v)", title, err)
+	}
+}
+
+func TestFailedMigrationIsRolledBack(t *testing.T) {
+	db := openTestDB(t)
+
+	migrations, err := loadMigrations(fstest.MapFS{
+		"m/0001_widgets.up.sql":   {Data: []byte("CREATE TABLE widgets (id TEXT);")},
+		"m/0001_widgets.down.sql": {Data: []byte("DROP TABLE widgets;")},
+		"m/0002_broken.up.sql":    {Data: []byte("CREATE TABLE gadgets (id TEXT); INSERT INTO nope VALUES (1);")},
+		"m/0002_broken.down.sql":  {Data: []byte("DROP TABLE gadgets;")},
+	}, "m")
+	if err != nil {
+		t.Fatalf("failed to load migrations: %v", err)
+	}
+
+	applied, err := migrateUp(db, migrations)
+	if err == nil || !strings.Contains(err.Error(), "0002_broken") {
+		t.Fatalf("expected 0002_broken to fail, got %v", err)
+	}
+	if len(applied) != 1 {
+		t.Fatalf("expected only the first migration to apply, got %v", applied)
+	}
+	if tableExists(t, db, "gadgets") {
+		t.Fatalf("expected the failed migration to be rolled back")
+	}
+
+	states, err := migrationStatus(db, migrations)
+	if err != nil {
+		t.Fatalf("failed to read status: %v", err)
+	}
+	if !states[0].Applied || states[1].Applied {
+		t.Fatalf("unexpected status after failure: %+v", states)
+	}
+}
+
+// migrateUpBefore applies every migration older than the one named name.
+func migrateUpBefore(t *testing.T, db *sql.DB, name string) {
+	t.Helper()
+
+	all, err := Migrations()
+	if err != nil {
+		t.Fatalf("failed to load migrations: %v", err)
+	}
+	var before []Migration
+	for _, m := range all {
+		if m.Name == name {
+			break
+		}
+		before = append(before, m)
+	}
+	if _, err := migrateUp(db, before); err != nil {
+		t.Fatalf("failed to migrate to before %s: %v", name, err)
+	}
+}
+
+func TestMigrateUpSplitsDirectorCredits(t *testing.T) {
+	db := openTestDB(t)
+	migrateUpBefore(t, db, "movie_directors")
+
+	// What EnsureDirector recorded before credits were split.
+	if _, err := db.Exec(`
+		INSERT INTO movies (id, title, director) VALUES ('8', 'The Matrix', 'Lana Wachowski, Lilly Wachowski');
+		INSERT INTO movies (id, title, director) VALUES ('9', 'Bound', 'Lilly Wachowski,Lana Wachowski');
+		INSERT INTO directors (id, name) VALUES ('d1', 'Lana Wachowski, Lilly Wachowski');`); err != nil {
+		t.Fatalf("failed to insert legacy rows: %v", err)
+	}
+
+	if _, err := MigrateUp(db); err != nil {
+		t.Fatalf("failed to migrate up: %v", err)
+	}
+
+	rows, err := db.Query(`
+		SELECT md.movie_id, d.name FROM movie_directors md
+		INNER JOIN directors d ON d.id = md.director_id
+		ORDER BY md.movie_id, md.position`)
+	if err != nil {
+		t.Fatalf("failed to query credits: %v", err)
+	}
+	defer rows.Close()
+
+	var credits []string
+	for rows.Next() {
+		var movieID, name string
+		if err := rows.Scan(&movieID, &name); err != nil {
+			t.Fatalf("failed to scan credit: %v", err)
+		}
+		credits = append(credits, movieID+":"+name)
+	}
+	want := "8:Lana Wachowski 8:Lilly Wachowski 9:Lilly Wachowski 9:Lana Wachowski"
+	if got := strings.Join(credits, " "); got != want {
+		t.Fatalf("expected credits %q, got %q", want, got)
+	}
+
+	var directors int
+	if err := db.QueryRow("SELECT COUNT(*) FROM directors").Scan(&directors); err != nil || directors != 2 {
+		t.Fatalf("expected the combined director to be replaced by 2 directors, got %d (err %v)", directors, err)
+	}
+}
+
+func TestMigrateUpBackfillsReviewStats(t *testing.T) {
+	db := openTestDB(t)
+	migrateUpBefore(t, db, "movie_review_stats")
+
+	if _, err := db.Exec(`
+		INSERT INTO movies (id, title) VALUES ('1', 'Inception');
+		INSERT INTO reviews (id, movie_id, user_name, rating) VALUES ('r1', '1', 'alice', 5);
+		INSERT INTO reviews (id, movie_id, user_name, rating) VALUES ('r2', '1', 'bob', 2);
+		INSERT INTO reviews (id, movie_id, user_name, rating) VALUES ('r3', '1', 'carol', 5);`); err != nil {
+		t.Fatalf("failed to insert legacy rows: %v", err)
+	}
+
+	if _, err := MigrateUp(db); err != nil {
+		t.Fatalf("failed to migrate up: %v", err)
+	}
+
+	var count, sum, twos, fives int
+	err := db.QueryRow("SELECT review_count, rating_sum, stars_2, stars_5 FROM movie_review_stats WHERE movie_id = '1'").
+		Scan(&count, &sum, &twos, &fives)
+	if err != nil || count != 3 || sum != 12 || twos != 1 || fives != 2 {
+		t.Fatalf("unexpected backfilled stats: count=%d sum=%d twos=%d fives=%d (err %v)", count, sum, twos, fives, err)
+	}
+}
+
+func TestMigrateUpStartsMovieHistory(t *testing.T) {
+	db := openTestDB(t)
+	migrateUpBefore(t, db, "movie_revisions")
+
+	if _, err := db.Exec(`INSERT INTO movies (id, title, year, rating, duration, genre)
+		VALUES ('1', 'Inception', 2010, 8.8, 148, 'Sci-Fi')`); err != nil {
+		t.Fatalf("failed to insert legacy row: %v", err)
+	}
+
+	if _, err := MigrateUp(db); err != nil {
+		t.Fatalf("failed to migrate up: %v", err)
+	}
+
+	movie, err := store.NewSQLiteStore(db).MovieAt(context.Background(), "1", time.Now())
+	if err != nil || movie.Title != "Inception" || movie.Year != 2010 || movie.Genre != "Sci-Fi" || movie.Version != 1 {
+		t.Fatalf("expected the current state as the first revision, got %+v (err %v)", movie, err)
+	}
+
+	if _, err := db.Exec("DELETE FROM movie_revisions"); err == nil || !strings.Contains(err.Error(), "append-only") {
+		t.Fatalf("expected movie_revisions to reject deletes, got %v", err)
+	}
+}
+
+func TestMigrateUpLinksReviewsToUsers(t *testing.T) {
+	db := openTestDB(t)
+	migrateUpBefore(t, db, "users")
+
+	if _, err := db.Exec(`
+		INSERT INTO movies (id, title) VALUES ('1', 'Inception');
+		INSERT INTO reviews (id, movie_id, user_name, rating, created_at) VALUES ('r1', '1', 'alice', 2, '2024-01-01 00:00:00.000');
+		INSERT INTO reviews (id, movie_id, user_name, rating, created_at) VALUES ('r2', '1', 'alice', 5, '2024-02-01 00:00:00.000');
+		INSERT INTO reviews (id, movie_id, user_name, rating) VALUES ('r3', '1', 'bob', 4);
+		INSERT INTO reviews (id, movie_id, user_name, rating, created_by) VALUES ('r4', '1', 'Carl', 3, 'auth0|carl');`); err != nil {
+		t.Fatalf("failed to insert legacy rows: %v", err)
+	}
+
+	if _, err := MigrateUp(db); err != nil {
+		t.Fatalf("failed to migrate up: %v", err)
+	}
+
+	rows, err := db.Query(`
+		SELECT r.id, u.name, COALESCE(u.subject, ''), r.deleted_at IS NOT NULL
+		FROM reviews r INNER JOIN users u ON u.id = r.user_id ORDER BY r.id`)
+	if err != nil {
+		t.Fatalf("failed to query reviews: %v", err)
+	}
+	defer rows.Close()
+	var got []string
+	for rows.Next() {
+		var id, name, subject string
+		var trashed bool
+		if err := rows.Scan(&id, &name, &subject, &trashed); err != nil {
+			t.Fatalf("failed to scan review: %v", err)
+		}
+		got = append(got, fmt.Sprint(id, " ", name, " ", subject, " ", trashed))
+	}
+	// alice's older review of the movie is trashed; Carl's review belongs to
+	// the subject that wrote it.
+	want := "[r1 alice  true r2 alice  false r3 bob  false r4 auth0|carl auth0|carl false]"
+	if fmt.Sprint(got) != want {
+		t.Fatalf("unexpected review authors:\n got %v\nwant %s", got, want)
+	}
+
+	// Migrated users get v4 UUIDs, like the ones the application creates.
+	ids, err := db.Query("SELECT id FROM users")
+	if err != nil {
+		t.Fatalf("failed to query users: %v", err)
+	}
+	defer ids.Close()
+	for ids.Next() {
+		var id string
+		if err := ids.Scan(&id); err != nil {
+			t.Fatalf("failed to scan user: %v", err)
+		}
+		if parsed, err := uuid.Parse(id); err != nil || parsed.Version() != 4 || parsed.Variant() != uuid.RFC4122 || len(id) != 36 {
+			t.Errorf("user id %q is not a v4 UUID", id)
+		}
+	}
+
+	var count, sum int
+	err = db.QueryRow("SELECT review_count, rating_sum FROM movie_review_stats WHERE movie_id = '1'").Scan(&count, &sum)
+	if err != nil || count != 3 || sum != 12 {
+		t.Fatalf("expected stats for the 3 kept reviews, got count=%d sum=%d (err %v)", count, sum, err)
+	}
+
+	// Every review must have a user, and the user must be in users.
+	if _, err := db.Exec("INSERT INTO reviews (id, movie_id, rating) VALUES ('r5', '1', 4)"); err == nil || !strings.Contains(err.Error(), "NOT NULL") {
+		t.Errorf("expected a review without a user to be rejected, got %v", err)
+	}
+	var references string
+	err = db.QueryRow(`SELECT "table" FROM pragma_foreign_key_list('reviews') WHERE "from" = 'user_id'`).Scan(&references)
+	if err != nil || references != "users" {
+		t.Errorf("expected reviews.user_id to reference users, got %q (err %v)", references, err)
+	}
+
+	// The triggers dropped with the old table are back.
+	if _, err := db.Exec("UPDATE reviews SET deleted_at = strftime('%Y-%m-%d %H:%M:%f', 'now') WHERE id = 'r2'"); err != nil {
+		t.Fatalf("failed to trash a review: %v", err)
+	}
+	err = db.QueryRow("SELECT review_count, rating_sum FROM movie_review_stats WHERE movie_id = '1'").Scan(&count, &sum)
+	if err != nil || count != 2 || sum != 7 {
+		t.Fatalf("expected stats without the trashed review, got count=%d sum=%d (err %v)", count, sum, err)
+	}
+}
+
+func TestMigrateUpRebuildsSearchIndex(t *testing.T) {
+	db := openTestDB(t)
+	migrateUpBefore(t, db, "movies_fts5")
+
+	// Rows indexed by the FTS4 triggers of 0005_movies_fts and
+	// 0009_soft_delete.
+	if _, err := db.Exec(`
+		INSERT INTO movies (id, title, description, year, rating, duration, genre, director, poster_url)
+			VALUES ('1', 'Heat', 'A heist in Los Angeles', 1995, 8.3, 170, 'Crime', 'Michael Mann', '');
+		INSERT INTO movies (id, title, description, year, rating, duration, genre, director, poster_url)
+			VALUES ('2', 'Ronin', '', 1998, 7.2, 122, 'Action', 'John Frankenheimer', '');
+		INSERT INTO actors (id, name) VALUES ('a1', 'Al Pacino');
+		INSERT INTO movie_actors (movie_id, actor_id) VALUES ('1', 'a1');
+		UPDATE movies SET deleted_at = '2024-01-01 00:00:00.000' WHERE id = '2';`); err != nil {
+		t.Fatalf("failed to insert legacy rows: %v", err)
+	}
+
+	if _, err := MigrateUp(db); err != nil {
+		t.Fatalf("failed to migrate up: %v", err)
+	}
+
+	s := store.NewSQLiteStore(db)
+	search := func(query string) string {
+		t.Helper()
+		movies, total, err := s.SearchMovies(context.Background(), query, nil, 10, 0)
+		if err != nil {
+			t.Fatalf("failed to search %q: %v", query, err)
+		}
+		var titles []string
+		for _, m := range movies {
+			titles = append(titles, m.Title)
+		}
+		return fmt.Sprint(total, titles)
+	}
+	if got := search("pacino"); got != "1 [Heat]" {
+		t.Errorf("search by actor = %s, want the migrated cast indexed", got)
+	}
+	if got := search("ronin"); got != "0 []" {
+		t.Errorf("search for a trashed movie = %s, want nothing", got)
+	}
+
+	// The triggers keep the rebuilt index current.
+	if _, err := db.Exec(`
+		UPDATE actors SET name = 'Robert De Niro' WHERE id = 'a1';
+		UPDATE movies SET deleted_at = NULL WHERE id = '2';
+		INSERT INTO movies (id, title, description, year, rating, duration, genre, director, poster_url)
+			VALUES ('3', 'Heat Wave', '', 2001, 5.0, 90, 'Drama', '', '');`); err != nil {
+		t.Fatalf("failed to update rows: %v", err)
+	}
+	for query, want := range map[string]string{
+		"pacino": "0 []",
+		"niro":   "1 [Heat]",
+		"ronin":  "1 [Ronin]",
+		"wave":   "1 [Heat Wave]",
+	} {
+		if got := search(query); got != want {
+			t.Errorf("search %q = %s, want %s", query, got, want)
+		}
+	}
+
+	if _, err := db.Exec("DELETE FROM movie_actors; DELETE FROM movies WHERE id = '1'"); err != nil {
+		t.Fatalf("failed to delete movie: %v", err)
+	}
+	var ids int
+	if err := db.QueryRow("SELECT COUNT(*) FROM movies_fts_ids WHERE movie_id = '1'").Scan(&ids); err != nil || ids != 0 {
+		t.Errorf("expected the deleted movie's rowid mapping to go, got %d (err %v)", ids, err)
+	}
+	if got := search("heat"); got != "1 [Heat Wave]" {
+		t.Errorf("search after delete = %s", got)
+	}
+}
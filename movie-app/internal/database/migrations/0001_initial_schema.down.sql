@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS reviews;
+DROP TABLE IF EXISTS movie_actors;
+DROP TABLE IF EXISTS actors;
+DROP TABLE IF EXISTS directors;
+DROP TABLE IF EXISTS movies;
@@ -0,0 +1,48 @@
+-- Tables that used to be created by createTables. IF NOT EXISTS lets this
+-- migration adopt databases created before migrations existed.
+CREATE TABLE IF NOT EXISTS movies (
+	id TEXT PRIMARY KEY,
+	title TEXT NOT NULL,
+	description TEXT,
+	year INTEGER,
+	rating REAL,
+	duration INTEGER,
+	genre TEXT,
+	director TEXT,
+	poster_url TEXT,
+	created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+	updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS directors (
+	id TEXT PRIMARY KEY,
+	name TEXT NOT NULL UNIQUE
+);
+
+CREATE TABLE IF NOT EXISTS actors (
+	id TEXT PRIMARY KEY,
+	name TEXT NOT NULL,
+	birth_date TEXT,
+	nationality TEXT,
+	biography TEXT,
+	profile_url TEXT
+);
+
+CREATE TABLE IF NOT EXISTS movie_actors (
+	movie_id TEXT,
+	actor_id TEXT,
+	character_name TEXT,
+	FOREIGN KEY (movie_id) REFERENCES movies(id),
+	FOREIGN KEY (actor_id) REFERENCES actors(id),
+	PRIMARY KEY (movie_id, actor_id)
+);
+
+CREATE TABLE IF NOT EXISTS reviews (
+	id TEXT PRIMARY KEY,
+	movie_id TEXT,
+	user_name TEXT NOT NULL,
+	rating INTEGER CHECK(rating >= 1 AND rating <= 5),
+	comment TEXT,
+	created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+	FOREIGN KEY (movie_id) REFERENCES movies(id)
+);
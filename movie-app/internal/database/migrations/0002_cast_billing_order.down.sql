@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_movie_actors_actor;
+ALTER TABLE movie_actors DROP COLUMN billing_order;
@@ -0,0 +1,9 @@
+ALTER TABLE movie_actors ADD COLUMN billing_order INTEGER NOT NULL DEFAULT 0;
+
+-- Bill existing cast in insertion order, starting at 1 for each movie.
+UPDATE movie_actors SET billing_order = (
+	SELECT COUNT(*) FROM movie_actors AS earlier
+	WHERE earlier.movie_id = movie_actors.movie_id AND earlier.rowid <= movie_actors.rowid
+);
+
+CREATE INDEX IF NOT EXISTS idx_movie_actors_actor ON movie_actors(actor_id);
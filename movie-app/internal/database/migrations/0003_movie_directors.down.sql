@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_movie_directors_director;
+DROP TABLE IF EXISTS movie_directors;
@@ -0,0 +1,49 @@
+CREATE TABLE movie_directors (
+	movie_id TEXT NOT NULL,
+	director_id TEXT NOT NULL,
+	position INTEGER NOT NULL,
+	FOREIGN KEY (movie_id) REFERENCES movies(id),
+	FOREIGN KEY (director_id) REFERENCES directors(id),
+	PRIMARY KEY (movie_id, director_id)
+);
+
+CREATE INDEX idx_movie_directors_director ON movie_directors(director_id);
+
+-- Split free-text credits such as "Lana Wachowski, Lilly Wachowski" into one
+-- row per name, keeping the order they were listed in.
+CREATE TEMP TABLE credited_directors AS
+WITH RECURSIVE split(movie_id, position, name, rest) AS (
+	SELECT id, 0, '', director || ',' FROM movies WHERE TRIM(COALESCE(director, '')) <> ''
+	UNION ALL
+	SELECT movie_id, position + 1,
+	       TRIM(substr(rest, 1, instr(rest, ',') - 1)),
+	       substr(rest, instr(rest, ',') + 1)
+	FROM split WHERE rest <> ''
+)
+SELECT movie_id, position, name FROM split WHERE name <> '';
+
+-- Random v4 UUIDs, matching the IDs the application generates. The hex is
+-- materialized first so each UUID is built from a single random value.
+CREATE TEMP TABLE new_directors AS
+SELECT name, lower(hex(randomblob(16))) AS h
+FROM (SELECT DISTINCT name FROM credited_directors)
+WHERE name NOT IN (SELECT name FROM directors);
+
+INSERT INTO directors (id, name)
+SELECT substr(h, 1, 8) || '-' || substr(h, 9, 4) || '-4' || substr(h, 14, 3) || '-' ||
+       substr('89ab', 1 + (unicode(substr(h, 17, 1)) % 4), 1) || substr(h, 18, 3) || '-' || substr(h, 21, 12),
+       name
+FROM new_directors;
+
+INSERT OR IGNORE INTO movie_directors (movie_id, director_id, position)
+SELECT c.movie_id, d.id, ROW_NUMBER() OVER (PARTITION BY c.movie_id ORDER BY c.position)
+FROM credited_directors c
+INNER JOIN directors d ON d.name = c.name;
+
+-- Combined names recorded by the old EnsureDirector are now split; drop the
+-- ones nothing links to.
+DELETE FROM directors
+WHERE name LIKE '%,%' AND id NOT IN (SELECT director_id FROM movie_directors);
+
+DROP TABLE credited_directors;
+DROP TABLE new_directors;
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_movie_genres_genre;
+DROP TABLE IF EXISTS movie_genres;
+DROP TABLE IF EXISTS genres;
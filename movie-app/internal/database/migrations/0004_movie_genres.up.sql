@@ -0,0 +1,49 @@
+CREATE TABLE genres (
+	id TEXT PRIMARY KEY,
+	name TEXT NOT NULL UNIQUE COLLATE NOCASE
+);
+
+CREATE TABLE movie_genres (
+	movie_id TEXT NOT NULL,
+	genre_id TEXT NOT NULL,
+	position INTEGER NOT NULL,
+	FOREIGN KEY (movie_id) REFERENCES movies(id),
+	FOREIGN KEY (genre_id) REFERENCES genres(id),
+	PRIMARY KEY (movie_id, genre_id)
+);
+
+CREATE INDEX idx_movie_genres_genre ON movie_genres(genre_id);
+
+-- Split comma-joined strings such as "Sci-Fi, Action" into one row per
+-- genre, keeping the order they were listed in.
+CREATE TEMP TABLE listed_genres AS
+WITH RECURSIVE split(movie_id, position, name, rest) AS (
+	SELECT id, 0, '', genre || ',' FROM movies WHERE TRIM(COALESCE(genre, '')) <> ''
+	UNION ALL
+	SELECT movie_id, position + 1,
+	       TRIM(substr(rest, 1, instr(rest, ',') - 1)),
+	       substr(rest, instr(rest, ',') + 1)
+	FROM split WHERE rest <> ''
+)
+SELECT movie_id, position, name FROM split WHERE name <> '';
+
+-- The first spelling seen becomes the genre's name (SQLite takes bare
+-- columns from the MIN row); spellings that differ only in case map onto it
+-- through the NOCASE column. Random v4 UUIDs as in 0003.
+CREATE TEMP TABLE new_genres AS
+SELECT name, lower(hex(randomblob(16))) AS h
+FROM (SELECT name, MIN(rowid) FROM listed_genres GROUP BY name COLLATE NOCASE);
+
+INSERT INTO genres (id, name)
+SELECT substr(h, 1, 8) || '-' || substr(h, 9, 4) || '-4' || substr(h, 14, 3) || '-' ||
+       substr('89ab', 1 + (unicode(substr(h, 17, 1)) % 4), 1) || substr(h, 18, 3) || '-' || substr(h, 21, 12),
+       name
+FROM new_genres;
+
+INSERT OR IGNORE INTO movie_genres (movie_id, genre_id, position)
+SELECT l.movie_id, g.id, ROW_NUMBER() OVER (PARTITION BY l.movie_id ORDER BY l.position)
+FROM listed_genres l
+INNER JOIN genres g ON g.name = l.name;
+
+DROP TABLE listed_genres;
+DROP TABLE new_genres;
@@ -0,0 +1,55 @@
+-- Full-text index over movies for searchMovies and MovieFilter.search.
+--
+-- This is FTS4 rather than FTS5: go-sqlite3 only compiles FTS5 in with the
+-- sqlite_fts5 build tag, while FTS4 is always available. FTS4 has no bm25()
+-- of its own; the store registers one computed from matchinfo().
+--
+-- movie_id is stored but not indexed and joins back to movies.id (rowids of
+-- a table without an INTEGER PRIMARY KEY may change on VACUUM). actors holds
+-- the cast's names so searching for an actor finds their movies.
+CREATE VIRTUAL TABLE movies_fts USING fts4(
+	movie_id, title, description, director, genre, actors,
+	notindexed=movie_id, tokenize=porter, prefix="2,3"
+);
+
+INSERT INTO movies_fts (movie_id, title, description, director, genre, actors)
+SELECT m.id, m.title, m.description, m.director, m.genre,
+       (SELECT group_concat(a.name, ', ') FROM movie_actors ma
+        INNER JOIN actors a ON a.id = ma.actor_id WHERE ma.movie_id = m.id)
+FROM movies m;
+
+CREATE TRIGGER movies_fts_insert AFTER INSERT ON movies BEGIN
+	INSERT INTO movies_fts (movie_id, title, description, director, genre, actors)
+	VALUES (new.id, new.title, new.description, new.director, new.genre, NULL);
+END;
+
+CREATE TRIGGER movies_fts_update AFTER UPDATE OF title, description, director, genre ON movies BEGIN
+	UPDATE movies_fts
+	SET title = new.title, description = new.description, director = new.director, genre = new.genre
+	WHERE movie_id = old.id;
+END;
+
+CREATE TRIGGER movies_fts_delete AFTER DELETE ON movies BEGIN
+	DELETE FROM movies_fts WHERE movie_id = old.id;
+END;
+
+CREATE TRIGGER movies_fts_cast_insert AFTER INSERT ON movie_actors BEGIN
+	UPDATE movies_fts SET actors = (
+		SELECT group_concat(a.name, ', ') FROM movie_actors ma
+		INNER JOIN actors a ON a.id = ma.actor_id WHERE ma.movie_id = new.movie_id
+	) WHERE movie_id = new.movie_id;
+END;
+
+CREATE TRIGGER movies_fts_cast_delete AFTER DELETE ON movie_actors BEGIN
+	UPDATE movies_fts SET actors = (
+		SELECT group_concat(a.name, ', ') FROM movie_actors ma
+		INNER JOIN actors a ON a.id = ma.actor_id WHERE ma.movie_id = old.movie_id
+	) WHERE movie_id = old.movie_id;
+END;
+
+CREATE TRIGGER movies_fts_actor_rename AFTER UPDATE OF name ON actors BEGIN
+	UPDATE movies_fts SET actors = (
+		SELECT group_concat(a.name, ', ') FROM movie_actors ma
+		INNER JOIN actors a ON a.id = ma.actor_id WHERE ma.movie_id = movies_fts.movie_id
+	) WHERE movie_id IN (SELECT movie_id FROM movie_actors WHERE actor_id = new.id);
+END;
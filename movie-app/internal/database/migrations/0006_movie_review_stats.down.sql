@@ -0,0 +1,5 @@
+DROP TRIGGER IF EXISTS movie_review_stats_movie_delete;
+DROP TRIGGER IF EXISTS movie_review_stats_update;
+DROP TRIGGER IF EXISTS movie_review_stats_delete;
+DROP TRIGGER IF EXISTS movie_review_stats_insert;
+DROP TABLE IF EXISTS movie_review_stats;
@@ -0,0 +1,61 @@
+-- Per-movie review aggregates for Movie.review_stats, weighted_score and the
+-- review filters and sorts, so none of them scan reviews. Triggers keep the
+-- counts current as reviews are added, changed and deleted. A movie gets a
+-- row with its first review; no row means no reviews.
+CREATE TABLE movie_review_stats (
+	movie_id TEXT PRIMARY KEY,
+	review_count INTEGER NOT NULL DEFAULT 0,
+	rating_sum INTEGER NOT NULL DEFAULT 0,
+	stars_1 INTEGER NOT NULL DEFAULT 0,
+	stars_2 INTEGER NOT NULL DEFAULT 0,
+	stars_3 INTEGER NOT NULL DEFAULT 0,
+	stars_4 INTEGER NOT NULL DEFAULT 0,
+	stars_5 INTEGER NOT NULL DEFAULT 0
+);
+
+INSERT INTO movie_review_stats (movie_id, review_count, rating_sum, stars_1, stars_2, stars_3, stars_4, stars_5)
+SELECT movie_id, COUNT(*), SUM(rating),
+       SUM(rating = 1), SUM(rating = 2), SUM(rating = 3), SUM(rating = 4), SUM(rating = 5)
+FROM reviews
+WHERE rating IS NOT NULL AND movie_id IN (SELECT id FROM movies)
+GROUP BY movie_id;
+
+CREATE TRIGGER movie_review_stats_insert AFTER INSERT ON reviews WHEN new.rating IS NOT NULL BEGIN
+	INSERT OR IGNORE INTO movie_review_stats (movie_id) VALUES (new.movie_id);
+	UPDATE movie_review_stats
+	SET review_count = review_count + 1, rating_sum = rating_sum + new.rating,
+	    stars_1 = stars_1 + (new.rating = 1), stars_2 = stars_2 + (new.rating = 2),
+	    stars_3 = stars_3 + (new.rating = 3), stars_4 = stars_4 + (new.rating = 4),
+	    stars_5 = stars_5 + (new.rating = 5)
+	WHERE movie_id = new.movie_id;
+END;
+
+CREATE TRIGGER movie_review_stats_delete AFTER DELETE ON reviews WHEN old.rating IS NOT NULL BEGIN
+	UPDATE movie_review_stats
+	SET review_count = review_count - 1, rating_sum = rating_sum - old.rating,
+	    stars_1 = stars_1 - (old.rating = 1), stars_2 = stars_2 - (old.rating = 2),
+	    stars_3 = stars_3 - (old.rating = 3), stars_4 = stars_4 - (old.rating = 4),
+	    stars_5 = stars_5 - (old.rating = 5)
+	WHERE movie_id = old.movie_id;
+END;
+
+CREATE TRIGGER movie_review_stats_update AFTER UPDATE OF movie_id, rating ON reviews BEGIN
+	UPDATE movie_review_stats
+	SET review_count = review_count - 1, rating_sum = rating_sum - old.rating,
+	    stars_1 = stars_1 - (old.rating = 1), stars_2 = stars_2 - (old.rating = 2),
+	    stars_3 = stars_3 - (old.rating = 3), stars_4 = stars_4 - (old.rating = 4),
+	    stars_5 = stars_5 - (old.rating = 5)
+	WHERE movie_id = old.movie_id AND old.rating IS NOT NULL;
+	INSERT OR IGNORE INTO movie_review_stats (movie_id)
+	SELECT new.movie_id WHERE new.rating IS NOT NULL;
+	UPDATE movie_review_stats
+	SET review_count = review_count + 1, rating_sum = rating_sum + new.rating,
+	    stars_1 = stars_1 + (new.rating = 1), stars_2 = stars_2 + (new.rating = 2),
+	    stars_3 = stars_3 + (new.rating = 3), stars_4 = stars_4 + (new.rating = 4),
+	    stars_5 = stars_5 + (new.rating = 5)
+	WHERE movie_id = new.movie_id AND new.rating IS NOT NULL;
+END;
+
+CREATE TRIGGER movie_review_stats_movie_delete AFTER DELETE ON movies BEGIN
+	DELETE FROM movie_review_stats WHERE movie_id = old.id;
+END;
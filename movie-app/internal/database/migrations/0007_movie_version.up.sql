@@ -0,0 +1,4 @@
+-- Optimistic concurrency for movie edits: every write increments version,
+-- and updateMovie, patchMovie and deleteMovie can require the version the
+-- client last read.
+ALTER TABLE movies ADD COLUMN version INTEGER NOT NULL DEFAULT 1;
@@ -0,0 +1,4 @@
+DROP TRIGGER IF EXISTS movie_revisions_no_delete;
+DROP TRIGGER IF EXISTS movie_revisions_no_update;
+DROP INDEX IF EXISTS idx_movie_revisions_movie;
+DROP TABLE IF EXISTS movie_revisions;
@@ -0,0 +1,46 @@
+-- Append-only history of movie edits. before_json and after_json hold the
+-- movie as models.Movie JSON; before_json is NULL for the revision that
+-- created a movie and after_json for the one that deleted it. created_at
+-- keeps milliseconds so revisions made in the same second stay ordered.
+CREATE TABLE movie_revisions (
+	id TEXT PRIMARY KEY,
+	movie_id TEXT NOT NULL,
+	mutation TEXT NOT NULL,
+	actor TEXT NOT NULL,
+	before_json TEXT,
+	after_json TEXT,
+	created_at DATETIME NOT NULL DEFAULT (strftime('%Y-%m-%d %H:%M:%f', 'now'))
+);
+
+CREATE INDEX idx_movie_revisions_movie ON movie_revisions(movie_id, created_at);
+
+CREATE TRIGGER movie_revisions_no_update BEFORE UPDATE ON movie_revisions BEGIN
+	SELECT RAISE(ABORT, 'movie_revisions is append-only');
+END;
+
+CREATE TRIGGER movie_revisions_no_delete BEFORE DELETE ON movie_revisions BEGIN
+	SELECT RAISE(ABORT, 'movie_revisions is append-only');
+END;
+
+-- Existing movies start their history with their current state, as of their
+-- last update. IDs are random v4 UUIDs built as in 0003_movie_directors.
+CREATE TEMP TABLE baseline_revisions AS
+SELECT id AS movie_id, lower(hex(randomblob(16))) AS h FROM movies;
+
+INSERT INTO movie_revisions (id, movie_id, mutation, actor, after_json, created_at)
+SELECT substr(b.h, 1, 8) || '-' || substr(b.h, 9, 4) || '-4' || substr(b.h, 14, 3) || '-' ||
+       substr('89ab', 1 + (unicode(substr(b.h, 17, 1)) % 4), 1) || substr(b.h, 18, 3) || '-' || substr(b.h, 21, 12),
+       m.id, 'migration', 'system',
+       json_object(
+           'id', m.id, 'title', m.title, 'description', COALESCE(m.description, ''),
+           'year', COALESCE(m.year, 0), 'rating', COALESCE(m.rating, 0), 'duration', COALESCE(m.duration, 0),
+           'genre', COALESCE(m.genre, ''), 'director', COALESCE(m.director, ''),
+           'poster_url', COALESCE(m.poster_url, ''),
+           'created_at', strftime('%Y-%m-%dT%H:%M:%SZ', m.created_at),
+           'updated_at', strftime('%Y-%m-%dT%H:%M:%SZ', m.updated_at),
+           'version', m.version),
+       strftime('%Y-%m-%d %H:%M:%f', COALESCE(m.updated_at, m.created_at, 'now'))
+FROM movies m
+INNER JOIN baseline_revisions b ON b.movie_id = m.id;
+
+DROP TABLE baseline_revisions;
@@ -0,0 +1,86 @@
+-- Without deleted_at, trashed rows would come back as live ones, so they are
+-- purged first, while the triggers still know they are not counted.
+DELETE FROM movie_actors WHERE movie_id IN (SELECT id FROM movies WHERE deleted_at IS NOT NULL)
+	OR actor_id IN (SELECT id FROM actors WHERE deleted_at IS NOT NULL);
+DELETE FROM movie_directors WHERE movie_id IN (SELECT id FROM movies WHERE deleted_at IS NOT NULL);
+DELETE FROM movie_genres WHERE movie_id IN (SELECT id FROM movies WHERE deleted_at IS NOT NULL);
+DELETE FROM reviews WHERE deleted_at IS NOT NULL;
+DELETE FROM movies WHERE deleted_at IS NOT NULL;
+DELETE FROM actors WHERE deleted_at IS NOT NULL;
+
+DROP TRIGGER IF EXISTS movies_fts_trash;
+DROP TRIGGER IF EXISTS movies_fts_restore;
+
+-- Back to the triggers from 0005_movies_fts and 0006_movie_review_stats.
+DROP TRIGGER IF EXISTS movies_fts_cast_insert;
+DROP TRIGGER IF EXISTS movies_fts_cast_delete;
+DROP TRIGGER IF EXISTS movies_fts_actor_rename;
+
+CREATE TRIGGER movies_fts_cast_insert AFTER INSERT ON movie_actors BEGIN
+	UPDATE movies_fts SET actors = (
+		SELECT group_concat(a.name, ', ') FROM movie_actors ma
+		INNER JOIN actors a ON a.id = ma.actor_id WHERE ma.movie_id = new.movie_id
+	) WHERE movie_id = new.movie_id;
+END;
+
+CREATE TRIGGER movies_fts_cast_delete AFTER DELETE ON movie_actors BEGIN
+	UPDATE movies_fts SET actors = (
+		SELECT group_concat(a.name, ', ') FROM movie_actors ma
+		INNER JOIN actors a ON a.id = ma.actor_id WHERE ma.movie_id = old.movie_id
+	) WHERE movie_id = old.movie_id;
+END;
+
+CREATE TRIGGER movies_fts_actor_rename AFTER UPDATE OF name ON actors BEGIN
+	UPDATE movies_fts SET actors = (
+		SELECT group_concat(a.name, ', ') FROM movie_actors ma
+		INNER JOIN actors a ON a.id = ma.actor_id WHERE ma.movie_id = movies_fts.movie_id
+	) WHERE movie_id IN (SELECT movie_id FROM movie_actors WHERE actor_id = new.id);
+END;
+
+DROP TRIGGER IF EXISTS movie_review_stats_insert;
+DROP TRIGGER IF EXISTS movie_review_stats_delete;
+DROP TRIGGER IF EXISTS movie_review_stats_update;
+
+CREATE TRIGGER movie_review_stats_insert AFTER INSERT ON reviews WHEN new.rating IS NOT NULL BEGIN
+	INSERT OR IGNORE INTO movie_review_stats (movie_id) VALUES (new.movie_id);
+	UPDATE movie_review_stats
+	SET review_count = review_count + 1, rating_sum = rating_sum + new.rating,
+	    stars_1 = stars_1 + (new.rating = 1), stars_2 = stars_2 + (new.rating = 2),
+	    stars_3 = stars_3 + (new.rating = 3), stars_4 = stars_4 + (new.rating = 4),
+	    stars_5 = stars_5 + (new.rating = 5)
+	WHERE movie_id = new.movie_id;
+END;
+
+CREATE TRIGGER movie_review_stats_delete AFTER DELETE ON reviews WHEN old.rating IS NOT NULL BEGIN
+	UPDATE movie_review_stats
+	SET review_count = review_count - 1, rating_sum = rating_sum - old.rating,
+	    stars_1 = stars_1 - (old.rating = 1), stars_2 = stars_2 - (old.rating = 2),
+	    stars_3 = stars_3 - (old.rating = 3), stars_4 = stars_4 - (old.rating = 4),
+	    stars_5 = stars_5 - (old.rating = 5)
+	WHERE movie_id = old.movie_id;
+END;
+
+CREATE TRIGGER movie_review_stats_update AFTER UPDATE OF movie_id, rating ON reviews BEGIN
+	UPDATE movie_review_stats
+	SET review_count = review_count - 1, rating_sum = rating_sum - old.rating,
+	    stars_1 = stars_1 - (old.rating = 1), stars_2 = stars_2 - (old.rating = 2),
+	    stars_3 = stars_3 - (old.rating = 3), stars_4 = stars_4 - (old.rating = 4),
+	    stars_5 = stars_5 - (old.rating = 5)
+	WHERE movie_id = old.movie_id AND old.rating IS NOT NULL;
+	INSERT OR IGNORE INTO movie_review_stats (movie_id)
+	SELECT new.movie_id WHERE new.rating IS NOT NULL;
+	UPDATE movie_review_stats
+	SET review_count = review_count + 1, rating_sum = rating_sum + new.rating,
+	    stars_1 = stars_1 + (new.rating = 1), stars_2 = stars_2 + (new.rating = 2),
+	    stars_3 = stars_3 + (new.rating = 3), stars_4 = stars_4 + (new.rating = 4),
+	    stars_5 = stars_5 + (new.rating = 5)
+	WHERE movie_id = new.movie_id AND new.rating IS NOT NULL;
+END;
+
+DROP INDEX IF EXISTS idx_reviews_deleted_at;
+DROP INDEX IF EXISTS idx_actors_deleted_at;
+DROP INDEX IF EXISTS idx_movies_deleted_at;
+
+ALTER TABLE reviews DROP COLUMN deleted_at;
+ALTER TABLE actors DROP COLUMN deleted_at;
+ALTER TABLE movies DROP COLUMN deleted_at;
@@ -0,0 +1,102 @@
+-- Soft delete for movies, actors and reviews. deleted_at is NULL for live
+-- rows and holds the time (with milliseconds) a row was moved to the trash.
+-- Deleting a movie trashes its live reviews with the movie's deleted_at, so
+-- restoring the movie can bring back exactly those. Cast, director and genre
+-- links are kept and hidden with the movie or actor they belong to.
+ALTER TABLE movies ADD COLUMN deleted_at DATETIME;
+ALTER TABLE actors ADD COLUMN deleted_at DATETIME;
+ALTER TABLE reviews ADD COLUMN deleted_at DATETIME;
+
+CREATE INDEX idx_movies_deleted_at ON movies(deleted_at);
+CREATE INDEX idx_actors_deleted_at ON actors(deleted_at);
+CREATE INDEX idx_reviews_deleted_at ON reviews(deleted_at);
+
+-- Trashed movies leave the search index and are indexed again on restore.
+CREATE TRIGGER movies_fts_trash AFTER UPDATE OF deleted_at ON movies
+WHEN old.deleted_at IS NULL AND new.deleted_at IS NOT NULL BEGIN
+	DELETE FROM movies_fts WHERE movie_id = old.id;
+END;
+
+CREATE TRIGGER movies_fts_restore AFTER UPDATE OF deleted_at ON movies
+WHEN old.deleted_at IS NOT NULL AND new.deleted_at IS NULL BEGIN
+	INSERT INTO movies_fts (movie_id, title, description, director, genre, actors)
+	VALUES (new.id, new.title, new.description, new.director, new.genre, (
+		SELECT group_concat(a.name, ', ') FROM movie_actors ma
+		INNER JOIN actors a ON a.id = ma.actor_id
+		WHERE ma.movie_id = new.id AND a.deleted_at IS NULL
+	));
+END;
+
+-- The cast triggers from 0005_movies_fts, leaving trashed actors out of the
+-- indexed names.
+DROP TRIGGER movies_fts_cast_insert;
+DROP TRIGGER movies_fts_cast_delete;
+DROP TRIGGER movies_fts_actor_rename;
+
+CREATE TRIGGER movies_fts_cast_insert AFTER INSERT ON movie_actors BEGIN
+	UPDATE movies_fts SET actors = (
+		SELECT group_concat(a.name, ', ') FROM movie_actors ma
+		INNER JOIN actors a ON a.id = ma.actor_id
+		WHERE ma.movie_id = new.movie_id AND a.deleted_at IS NULL
+	) WHERE movie_id = new.movie_id;
+END;
+
+CREATE TRIGGER movies_fts_cast_delete AFTER DELETE ON movie_actors BEGIN
+	UPDATE movies_fts SET actors = (
+		SELECT group_concat(a.name, ', ') FROM movie_actors ma
+		INNER JOIN actors a ON a.id = ma.actor_id
+		WHERE ma.movie_id = old.movie_id AND a.deleted_at IS NULL
+	) WHERE movie_id = old.movie_id;
+END;
+
+CREATE TRIGGER movies_fts_actor_rename AFTER UPDATE OF name, deleted_at ON actors BEGIN
+	UPDATE movies_fts SET actors = (
+		SELECT group_concat(a.name, ', ') FROM movie_actors ma
+		INNER JOIN actors a ON a.id = ma.actor_id
+		WHERE ma.movie_id = movies_fts.movie_id AND a.deleted_at IS NULL
+	) WHERE movie_id IN (SELECT movie_id FROM movie_actors WHERE actor_id = new.id);
+END;
+
+-- The review stats triggers from 0006_movie_review_stats, counting only live
+-- reviews. Trashing or restoring a review is an update of deleted_at.
+DROP TRIGGER movie_review_stats_insert;
+DROP TRIGGER movie_review_stats_delete;
+DROP TRIGGER movie_review_stats_update;
+
+CREATE TRIGGER movie_review_stats_insert AFTER INSERT ON reviews
+WHEN new.rating IS NOT NULL AND new.deleted_at IS NULL BEGIN
+	INSERT OR IGNORE INTO movie_review_stats (movie_id) VALUES (new.movie_id);
+	UPDATE movie_review_stats
+	SET review_count = review_count + 1, rating_sum = rating_sum + new.rating,
+	    stars_1 = stars_1 + (new.rating = 1), stars_2 = stars_2 + (new.rating = 2),
+	    stars_3 = stars_3 + (new.rating = 3), stars_4 = stars_4 + (new.rating = 4),
+	    stars_5 = stars_5 + (new.rating = 5)
+	WHERE movie_id = new.movie_id;
+END;
+
+CREATE TRIGGER movie_review_stats_delete AFTER DELETE ON reviews
+WHEN old.rating IS NOT NULL AND old.deleted_at IS NULL BEGIN
+	UPDATE movie_review_stats
+	SET review_count = review_count - 1, rating_sum = rating_sum - old.rating,
+	    stars_1 = stars_1 - (old.rating = 1), stars_2 = stars_2 - (old.rating = 2),
+	    stars_3 = stars_3 - (old.rating = 3), stars_4 = stars_4 - (old.rating = 4),
+	    stars_5 = stars_5 - (old.rating = 5)
+	WHERE movie_id = old.movie_id;
+END;
+
+CREATE TRIGGER movie_review_stats_update AFTER UPDATE OF movie_id, rating, deleted_at ON reviews BEGIN
+	UPDATE movie_review_stats
+	SET review_count = review_count - 1, rating_sum = rating_sum - old.rating,
+	    stars_1 = stars_1 - (old.rating = 1), stars_2 = stars_2 - (old.rating = 2),
+	    stars_3 = stars_3 - (old.rating = 3), stars_4 = stars_4 - (old.rating = 4),
+	    stars_5 = stars_5 - (old.rating = 5)
+	WHERE movie_id = old.movie_id AND old.rating IS NOT NULL AND old.deleted_at IS NULL;
+	INSERT OR IGNORE INTO movie_review_stats (movie_id)
+	SELECT new.movie_id WHERE new.rating IS NOT NULL AND new.deleted_at IS NULL;
+	UPDATE movie_review_stats
+	SET review_count = review_count + 1, rating_sum = rating_sum + new.rating,
+	    stars_1 = stars_1 + (new.rating = 1), stars_2 = stars_2 + (new.rating = 2),
+	    stars_3 = stars_3 + (new.rating = 3), stars_4 = stars_4 + (new.rating = 4),
+	    stars_5 = stars_5 + (new.rating = 5)
+	WHERE movie_id = new.movie_id AND new.rating IS NOT NULL AND new.deleted_at IS NULL;
+END;
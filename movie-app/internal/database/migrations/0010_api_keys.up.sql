@@ -0,0 +1,11 @@
+-- API keys for clients that cannot obtain a JWT. Only the SHA-256 of each
+-- key is stored; prefix is the start of the key, for telling keys apart in
+-- listings. A revoked key is kept so its name stays reserved.
+CREATE TABLE api_keys (
+	id TEXT PRIMARY KEY,
+	name TEXT NOT NULL UNIQUE,
+	prefix TEXT NOT NULL,
+	key_hash TEXT NOT NULL UNIQUE,
+	created_at DATETIME NOT NULL DEFAULT (strftime('%Y-%m-%d %H:%M:%f', 'now')),
+	revoked_at DATETIME
+);
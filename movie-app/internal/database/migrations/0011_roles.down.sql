@@ -0,0 +1,2 @@
+ALTER TABLE reviews DROP COLUMN created_by;
+ALTER TABLE api_keys DROP COLUMN role;
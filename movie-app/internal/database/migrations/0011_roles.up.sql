@@ -0,0 +1,8 @@
+-- Keys created before roles existed could run every mutation, so they
+-- become editors; new keys name their role.
+ALTER TABLE api_keys ADD COLUMN role TEXT NOT NULL DEFAULT 'viewer';
+UPDATE api_keys SET role = 'editor';
+
+-- The subject of the caller who wrote each review, so reviewers can delete
+-- their own. Earlier reviews have none and only editors can delete them.
+ALTER TABLE reviews ADD COLUMN created_by TEXT;
@@ -0,0 +1,63 @@
+-- Reviews trashed to keep one per user per movie stay in the trash. reviews
+-- is rebuilt as it was after 0011_roles, since user_id cannot be dropped
+-- while it references users.
+CREATE TABLE reviews_old (
+	id TEXT PRIMARY KEY,
+	movie_id TEXT,
+	user_name TEXT NOT NULL,
+	rating INTEGER CHECK(rating >= 1 AND rating <= 5),
+	comment TEXT,
+	created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+	deleted_at DATETIME,
+	created_by TEXT,
+	FOREIGN KEY (movie_id) REFERENCES movies(id)
+);
+
+INSERT INTO reviews_old (id, movie_id, user_name, rating, comment, created_at, deleted_at, created_by)
+SELECT r.id, r.movie_id, COALESCE(u.name, ''), r.rating, r.comment, r.created_at, r.deleted_at, u.subject
+FROM reviews r LEFT JOIN users u ON u.id = r.user_id;
+
+DROP TABLE reviews;
+ALTER TABLE reviews_old RENAME TO reviews;
+
+CREATE INDEX idx_reviews_deleted_at ON reviews(deleted_at);
+
+CREATE TRIGGER movie_review_stats_insert AFTER INSERT ON reviews
+WHEN new.rating IS NOT NULL AND new.deleted_at IS NULL BEGIN
+	INSERT OR IGNORE INTO movie_review_stats (movie_id) VALUES (new.movie_id);
+	UPDATE movie_review_stats
+	SET review_count = review_count + 1, rating_sum = rating_sum + new.rating,
+	    stars_1 = stars_1 + (new.rating = 1), stars_2 = stars_2 + (new.rating = 2),
+	    stars_3 = stars_3 + (new.rating = 3), stars_4 = stars_4 + (new.rating = 4),
+	    stars_5 = stars_5 + (new.rating = 5)
+	WHERE movie_id = new.movie_id;
+END;
+
+CREATE TRIGGER movie_review_stats_delete AFTER DELETE ON reviews
+WHEN old.rating IS NOT NULL AND old.deleted_at IS NULL BEGIN
+	UPDATE movie_review_stats
+	SET review_count = review_count - 1, rating_sum = rating_sum - old.rating,
+	    stars_1 = stars_1 - (old.rating = 1), stars_2 = stars_2 - (old.rating = 2),
+	    stars_3 = stars_3 - (old.rating = 3), stars_4 = stars_4 - (old.rating = 4),
+	    stars_5 = stars_5 - (old.rating = 5)
+	WHERE movie_id = old.movie_id;
+END;
+
+CREATE TRIGGER movie_review_stats_update AFTER UPDATE OF movie_id, rating, deleted_at ON reviews BEGIN
+	UPDATE movie_review_stats
+	SET review_count = review_count - 1, rating_sum = rating_sum - old.rating,
+	    stars_1 = stars_1 - (old.rating = 1), stars_2 = stars_2 - (old.rating = 2),
+	    stars_3 = stars_3 - (old.rating = 3), stars_4 = stars_4 - (old.rating = 4),
+	    stars_5 = stars_5 - (old.rating = 5)
+	WHERE movie_id = old.movie_id AND old.rating IS NOT NULL AND old.deleted_at IS NULL;
+	INSERT OR IGNORE INTO movie_review_stats (movie_id)
+	SELECT new.movie_id WHERE new.rating IS NOT NULL AND new.deleted_at IS NULL;
+	UPDATE movie_review_stats
+	SET review_count = review_count + 1, rating_sum = rating_sum + new.rating,
+	    stars_1 = stars_1 + (new.rating = 1), stars_2 = stars_2 + (new.rating = 2),
+	    stars_3 = stars_3 + (new.rating = 3), stars_4 = stars_4 + (new.rating = 4),
+	    stars_5 = stars_5 + (new.rating = 5)
+	WHERE movie_id = new.movie_id AND new.rating IS NOT NULL AND new.deleted_at IS NULL;
+END;
+
+DROP TABLE users;
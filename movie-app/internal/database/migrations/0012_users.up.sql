@@ -0,0 +1,113 @@
+-- User accounts. Reviews point at their author's user instead of carrying a
+-- free-text user_name. A user signed in through auth has the principal's
+-- subject; users made from the names on older reviews are placeholders with
+-- no subject that nobody can sign in as.
+CREATE TABLE users (
+	id TEXT PRIMARY KEY,
+	subject TEXT UNIQUE,
+	name TEXT NOT NULL,
+	bio TEXT,
+	avatar_url TEXT,
+	created_at DATETIME NOT NULL DEFAULT (strftime('%Y-%m-%d %H:%M:%f', 'now'))
+);
+
+-- Placeholders are found by name, so each name has at most one.
+CREATE UNIQUE INDEX idx_users_placeholder_name ON users(name) WHERE subject IS NULL;
+
+-- Reviews written since roles (0011_roles) belong to the subject that wrote
+-- them; older ones to a placeholder named after their user_name. IDs are
+-- random v4 UUIDs built as in 0003_movie_directors.
+CREATE TEMP TABLE new_users AS
+SELECT created_by AS subject, created_by AS name, lower(hex(randomblob(16))) AS h
+FROM reviews WHERE created_by IS NOT NULL GROUP BY created_by
+UNION ALL
+SELECT NULL, user_name, lower(hex(randomblob(16)))
+FROM reviews WHERE created_by IS NULL GROUP BY user_name;
+
+INSERT INTO users (id, subject, name)
+SELECT substr(h, 1, 8) || '-' || substr(h, 9, 4) || '-4' || substr(h, 14, 3) || '-' ||
+       substr('89ab', 1 + (unicode(substr(h, 17, 1)) % 4), 1) || substr(h, 18, 3) || '-' || substr(h, 21, 12),
+       subject, name
+FROM new_users;
+
+DROP TABLE new_users;
+
+-- reviews is rebuilt rather than altered so user_id can be NOT NULL and
+-- reference users; dropping the old table drops its triggers and indexes,
+-- which are created again below as in 0009_soft_delete.
+CREATE TABLE reviews_new (
+	id TEXT PRIMARY KEY,
+	movie_id TEXT,
+	rating INTEGER CHECK(rating >= 1 AND rating <= 5),
+	comment TEXT,
+	created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+	deleted_at DATETIME,
+	user_id TEXT NOT NULL,
+	FOREIGN KEY (movie_id) REFERENCES movies(id),
+	FOREIGN KEY (user_id) REFERENCES users(id)
+);
+
+INSERT INTO reviews_new (id, movie_id, rating, comment, created_at, deleted_at, user_id)
+SELECT id, movie_id, rating, comment, created_at, deleted_at, CASE
+	WHEN created_by IS NOT NULL THEN (SELECT id FROM users WHERE subject = reviews.created_by)
+	ELSE (SELECT id FROM users WHERE subject IS NULL AND name = reviews.user_name)
+END
+FROM reviews;
+
+DROP TABLE reviews;
+ALTER TABLE reviews_new RENAME TO reviews;
+
+CREATE INDEX idx_reviews_deleted_at ON reviews(deleted_at);
+
+CREATE TRIGGER movie_review_stats_insert AFTER INSERT ON reviews
+WHEN new.rating IS NOT NULL AND new.deleted_at IS NULL BEGIN
+	INSERT OR IGNORE INTO movie_review_stats (movie_id) VALUES (new.movie_id);
+	UPDATE movie_review_stats
+	SET review_count = review_count + 1, rating_sum = rating_sum + new.rating,
+	    stars_1 = stars_1 + (new.rating = 1), stars_2 = stars_2 + (new.rating = 2),
+	    stars_3 = stars_3 + (new.rating = 3), stars_4 = stars_4 + (new.rating = 4),
+	    stars_5 = stars_5 + (new.rating = 5)
+	WHERE movie_id = new.movie_id;
+END;
+
+CREATE TRIGGER movie_review_stats_delete AFTER DELETE ON reviews
+WHEN old.rating IS NOT NULL AND old.deleted_at IS NULL BEGIN
+	UPDATE movie_review_stats
+	SET review_count = review_count - 1, rating_sum = rating_sum - old.rating,
+	    stars_1 = stars_1 - (old.rating = 1), stars_2 = stars_2 - (old.rating = 2),
+	    stars_3 = stars_3 - (old.rating = 3), stars_4 = stars_4 - (old.rating = 4),
+	    stars_5 = stars_5 - (old.rating = 5)
+	WHERE movie_id = old.movie_id;
+END;
+
+CREATE TRIGGER movie_review_stats_update AFTER UPDATE OF movie_id, rating, deleted_at ON reviews BEGIN
+	UPDATE movie_review_stats
+	SET review_count = review_count - 1, rating_sum = rating_sum - old.rating,
+	    stars_1 = stars_1 - (old.rating = 1), stars_2 = stars_2 - (old.rating = 2),
+	    stars_3 = stars_3 - (old.rating = 3), stars_4 = stars_4 - (old.rating = 4),
+	    stars_5 = stars_5 - (old.rating = 5)
+	WHERE movie_id = old.movie_id AND old.rating IS NOT NULL AND old.deleted_at IS NULL;
+	INSERT OR IGNORE INTO movie_review_stats (movie_id)
+	SELECT new.movie_id WHERE new.rating IS NOT NULL AND new.deleted_at IS NULL;
+	UPDATE movie_review_stats
+	SET review_count = review_count + 1, rating_sum = rating_sum + new.rating,
+	    stars_1 = stars_1 + (new.rating = 1), stars_2 = stars_2 + (new.rating = 2),
+	    stars_3 = stars_3 + (new.rating = 3), stars_4 = stars_4 + (new.rating = 4),
+	    stars_5 = stars_5 + (new.rating = 5)
+	WHERE movie_id = new.movie_id AND new.rating IS NOT NULL AND new.deleted_at IS NULL;
+END;
+
+-- One review per user per movie: of the reviews a user has on a movie that
+-- are live or trashed along with the movie, only the newest is kept and the
+-- rest are moved to the trash on their own.
+UPDATE reviews SET deleted_at = strftime('%Y-%m-%d %H:%M:%f', 'now')
+WHERE (deleted_at IS NULL OR deleted_at = (SELECT deleted_at FROM movies WHERE id = reviews.movie_id))
+  AND EXISTS (
+	SELECT 1 FROM reviews newer
+	WHERE newer.movie_id = reviews.movie_id AND newer.user_id = reviews.user_id
+	  AND (newer.deleted_at IS NULL OR newer.deleted_at = (SELECT deleted_at FROM movies WHERE id = newer.movie_id))
+	  AND (newer.created_at > reviews.created_at OR (newer.created_at = reviews.created_at AND newer.id > reviews.id))
+  );
+
+CREATE INDEX idx_reviews_user_id ON reviews(user_id);
+CREATE UNIQUE INDEX idx_reviews_movie_user ON reviews(movie_id, user_id) WHERE deleted_at IS NULL;
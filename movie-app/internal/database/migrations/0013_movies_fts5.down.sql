@@ -0,0 +1,79 @@
+-- Back to the FTS4 index of 0005_movies_fts with the triggers as
+-- 0009_soft_delete left them.
+DROP TRIGGER IF EXISTS movies_fts_insert;
+DROP TRIGGER IF EXISTS movies_fts_update;
+DROP TRIGGER IF EXISTS movies_fts_delete;
+DROP TRIGGER IF EXISTS movies_fts_trash;
+DROP TRIGGER IF EXISTS movies_fts_restore;
+DROP TRIGGER IF EXISTS movies_fts_cast_insert;
+DROP TRIGGER IF EXISTS movies_fts_cast_delete;
+DROP TRIGGER IF EXISTS movies_fts_actor_rename;
+DROP TABLE IF EXISTS movies_fts;
+DROP TABLE IF EXISTS movies_fts_ids;
+
+CREATE VIRTUAL TABLE movies_fts USING fts4(
+	movie_id, title, description, director, genre, actors,
+	notindexed=movie_id, tokenize=porter, prefix="2,3"
+);
+
+INSERT INTO movies_fts (movie_id, title, description, director, genre, actors)
+SELECT m.id, m.title, m.description, m.director, m.genre, (
+	SELECT group_concat(a.name, ', ') FROM movie_actors ma
+	INNER JOIN actors a ON a.id = ma.actor_id
+	WHERE ma.movie_id = m.id AND a.deleted_at IS NULL
+)
+FROM movies m WHERE m.deleted_at IS NULL;
+
+CREATE TRIGGER movies_fts_insert AFTER INSERT ON movies BEGIN
+	INSERT INTO movies_fts (movie_id, title, description, director, genre, actors)
+	VALUES (new.id, new.title, new.description, new.director, new.genre, NULL);
+END;
+
+CREATE TRIGGER movies_fts_update AFTER UPDATE OF title, description, director, genre ON movies BEGIN
+	UPDATE movies_fts
+	SET title = new.title, description = new.description, director = new.director, genre = new.genre
+	WHERE movie_id = old.id;
+END;
+
+CREATE TRIGGER movies_fts_delete AFTER DELETE ON movies BEGIN
+	DELETE FROM movies_fts WHERE movie_id = old.id;
+END;
+
+CREATE TRIGGER movies_fts_trash AFTER UPDATE OF deleted_at ON movies
+WHEN old.deleted_at IS NULL AND new.deleted_at IS NOT NULL BEGIN
+	DELETE FROM movies_fts WHERE movie_id = old.id;
+END;
+
+CREATE TRIGGER movies_fts_restore AFTER UPDATE OF deleted_at ON movies
+WHEN old.deleted_at IS NOT NULL AND new.deleted_at IS NULL BEGIN
+	INSERT INTO movies_fts (movie_id, title, description, director, genre, actors)
+	VALUES (new.id, new.title, new.description, new.director, new.genre, (
+		SELECT group_concat(a.name, ', ') FROM movie_actors ma
+		INNER JOIN actors a ON a.id = ma.actor_id
+		WHERE ma.movie_id = new.id AND a.deleted_at IS NULL
+	));
+END;
+
+CREATE TRIGGER movies_fts_cast_insert AFTER INSERT ON movie_actors BEGIN
+	UPDATE movies_fts SET actors = (
+		SELECT group_concat(a.name, ', ') FROM movie_actors ma
+		INNER JOIN actors a ON a.id = ma.actor_id
+		WHERE ma.movie_id = new.movie_id AND a.deleted_at IS NULL
+	) WHERE movie_id = new.movie_id;
+END;
+
+CREATE TRIGGER movies_fts_cast_delete AFTER DELETE ON movie_actors BEGIN
+	UPDATE movies_fts SET actors = (
+		SELECT group_concat(a.name, ', ') FROM movie_actors ma
+		INNER JOIN actors a ON a.id = ma.actor_id
+		WHERE ma.movie_id = old.movie_id AND a.deleted_at IS NULL
+	) WHERE movie_id = old.movie_id;
+END;
+
+CREATE TRIGGER movies_fts_actor_rename AFTER UPDATE OF name, deleted_at ON actors BEGIN
+	UPDATE movies_fts SET actors = (
+		SELECT group_concat(a.name, ', ') FROM movie_actors ma
+		INNER JOIN actors a ON a.id = ma.actor_id
+		WHERE ma.movie_id = movies_fts.movie_id AND a.deleted_at IS NULL
+	) WHERE movie_id IN (SELECT movie_id FROM movie_actors WHERE actor_id = new.id);
+END;
@@ -0,0 +1,104 @@
+-- Rebuilds movies_fts as FTS5, for its built-in bm25() and snippet().
+-- go-sqlite3 only compiles FTS5 in with the sqlite_fts5 build tag.
+--
+-- Index rows are keyed by rowid so the triggers find a movie's row with a
+-- lookup instead of scanning the index for an unindexed movie_id.
+-- movies_fts_ids maps each movie to its rowid; movies' own rowids would do,
+-- but without an INTEGER PRIMARY KEY they may change on VACUUM.
+DROP TRIGGER movies_fts_insert;
+DROP TRIGGER movies_fts_update;
+DROP TRIGGER movies_fts_delete;
+DROP TRIGGER movies_fts_trash;
+DROP TRIGGER movies_fts_restore;
+DROP TRIGGER movies_fts_cast_insert;
+DROP TRIGGER movies_fts_cast_delete;
+DROP TRIGGER movies_fts_actor_rename;
+DROP TABLE movies_fts;
+
+CREATE TABLE movies_fts_ids (
+	fts_rowid INTEGER PRIMARY KEY,
+	movie_id TEXT NOT NULL UNIQUE
+);
+
+INSERT INTO movies_fts_ids (movie_id) SELECT id FROM movies;
+
+-- actors holds the cast's names so searching for an actor finds their
+-- movies. Trashed movies are left out and trashed actors' names too.
+CREATE VIRTUAL TABLE movies_fts USING fts5(
+	title, description, director, genre, actors,
+	tokenize = 'porter unicode61', prefix = '2 3'
+);
+
+INSERT INTO movies_fts (rowid, title, description, director, genre, actors)
+SELECT i.fts_rowid, m.title, m.description, m.director, m.genre, (
+	SELECT group_concat(a.name, ', ') FROM movie_actors ma
+	INNER JOIN actors a ON a.id = ma.actor_id
+	WHERE ma.movie_id = m.id AND a.deleted_at IS NULL
+)
+FROM movies m INNER JOIN movies_fts_ids i ON i.movie_id = m.id
+WHERE m.deleted_at IS NULL;
+
+CREATE TRIGGER movies_fts_insert AFTER INSERT ON movies BEGIN
+	INSERT INTO movies_fts_ids (movie_id) VALUES (new.id);
+	INSERT INTO movies_fts (rowid, title, description, director, genre, actors)
+	SELECT fts_rowid, new.title, new.description, new.director, new.genre, NULL
+	FROM movies_fts_ids WHERE movie_id = new.id;
+END;
+
+CREATE TRIGGER movies_fts_update AFTER UPDATE OF title, description, director, genre ON movies BEGIN
+	UPDATE movies_fts
+	SET title = new.title, description = new.description, director = new.director, genre = new.genre
+	WHERE rowid = (SELECT fts_rowid FROM movies_fts_ids WHERE movie_id = old.id);
+END;
+
+CREATE TRIGGER movies_fts_delete AFTER DELETE ON movies BEGIN
+	DELETE FROM movies_fts WHERE rowid = (SELECT fts_rowid FROM movies_fts_ids WHERE movie_id = old.id);
+	DELETE FROM movies_fts_ids WHERE movie_id = old.id;
+END;
+
+-- Trashed movies leave the index and come back on restore; they keep their
+-- movies_fts_ids row until they are purged.
+CREATE TRIGGER movies_fts_trash AFTER UPDATE OF deleted_at ON movies
+WHEN old.deleted_at IS NULL AND new.deleted_at IS NOT NULL BEGIN
+	DELETE FROM movies_fts WHERE rowid = (SELECT fts_rowid FROM movies_fts_ids WHERE movie_id = old.id);
+END;
+
+CREATE TRIGGER movies_fts_restore AFTER UPDATE OF deleted_at ON movies
+WHEN old.deleted_at IS NOT NULL AND new.deleted_at IS NULL BEGIN
+	INSERT INTO movies_fts (rowid, title, description, director, genre, actors)
+	SELECT fts_rowid, new.title, new.description, new.director, new.genre, (
+		SELECT group_concat(a.name, ', ') FROM movie_actors ma
+		INNER JOIN actors a ON a.id = ma.actor_id
+		WHERE ma.movie_id = new.id AND a.deleted_at IS NULL
+	)
+	FROM movies_fts_ids WHERE movie_id = new.id;
+END;
+
+CREATE TRIGGER movies_fts_cast_insert AFTER INSERT ON movie_actors BEGIN
+	UPDATE movies_fts SET actors = (
+		SELECT group_concat(a.name, ', ') FROM movie_actors ma
+		INNER JOIN actors a ON a.id = ma.actor_id
+		WHERE ma.movie_id = new.movie_id AND a.deleted_at IS NULL
+	) WHERE rowid = (SELECT fts_rowid FROM movies_fts_ids WHERE movie_id = new.movie_id);
+END;
+
+CREATE TRIGGER movies_fts_cast_delete AFTER DELETE ON movie_actors BEGIN
+	UPDATE movies_fts SET actors = (
+		SELECT group_concat(a.name, ', ') FROM movie_actors ma
+		INNER JOIN actors a ON a.id = ma.actor_id
+		WHERE ma.movie_id = old.movie_id AND a.deleted_at IS NULL
+	) WHERE rowid = (SELECT fts_rowid FROM movies_fts_ids WHERE movie_id = old.movie_id);
+END;
+
+CREATE TRIGGER movies_fts_actor_rename AFTER UPDATE OF name, deleted_at ON actors BEGIN
+	UPDATE movies_fts SET actors = (
+		SELECT group_concat(a.name, ', ') FROM movie_actors ma
+		INNER JOIN actors a ON a.id = ma.actor_id
+		INNER JOIN movies_fts_ids i ON i.movie_id = ma.movie_id
+		WHERE i.fts_rowid = movies_fts.rowid AND a.deleted_at IS NULL
+	) WHERE rowid IN (
+		SELECT i.fts_rowid FROM movie_actors ma
+		INNER JOIN movies_fts_ids i ON i.movie_id = ma.movie_id
+		WHERE ma.actor_id = new.id
+	);
+END;
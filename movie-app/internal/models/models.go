@@ -16,44 +16,234 @@ type Movie struct {
 	PosterURL   string    `json:"poster_url"`
 	CreatedAt   time.Time `json:"created_at"`
 	UpdatedAt   time.Time `json:"updated_at"`
+	Version     int       `json:"version"` // starts at 1, incremented by every update
 	Actors      []Actor   `json:"actors,omitempty"`
 	Reviews     []Review  `json:"reviews,omitempty"`
+	// Highlights is only set on searchMovies results.
+	Highlights []Highlight `json:"highlights,omitempty"`
+}
+
+// Highlight is a snippet of one searched field with the matched terms
+// wrapped in <mark></mark>.
+type Highlight struct {
+	Field   string `json:"field"`
+	Snippet string `json:"snippet"`
 }
 
 type Actor struct {
-	ID         string `json:"id"`
-	Name       string `json:"name"`
-	BirthDate  string `json:"birth_date"`
+	ID          string `json:"id"`
+	Name        string `json:"name"`
+	BirthDate   string `json:"birth_date"`
 	Nationality string `json:"nationality"`
-	Biography  string `json:"biography"`
-	ProfileURL string `json:"profile_url"`
+	Biography   string `json:"biography"`
+	ProfileURL  string `json:"profile_url"`
 }
 
 type Review struct {
 	ID        string    `json:"id"`
 	MovieID   string    `json:"movie_id"`
-	UserName  string    `json:"user_name"`
+	UserID    string    `json:"user_id"`
+	UserName  string    `json:"user_name"` // the author's name, read from their user
 	Rating    int       `json:"rating"`
 	Comment   string    `json:"comment"`
 	CreatedAt time.Time `json:"created_at"`
 }
 
+// User is the author of reviews. Subject is the auth principal the user
+// signs in as; it is empty for placeholder users made from the names on
+// reviews written before accounts existed.
+type User struct {
+	ID        string    `json:"id"`
+	Subject   string    `json:"-"`
+	Name      string    `json:"name"`
+	Bio       string    `json:"bio"`
+	AvatarURL string    `json:"avatar_url"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
 type MovieActor struct {
 	MovieID       string `json:"movie_id"`
 	ActorID       string `json:"actor_id"`
 	CharacterName string `json:"character_name"`
+	BillingOrder  int    `json:"billing_order"`
+}
+
+// CastMember is an actor's role in a movie, as listed on Movie.cast.
+type CastMember struct {
+	Actor         Actor  `json:"actor"`
+	CharacterName string `json:"character_name"`
+	BillingOrder  int    `json:"billing_order"`
+}
+
+// FilmographyEntry is a movie an actor appeared in, as listed on
+// Actor.filmography.
+type FilmographyEntry struct {
+	Movie         Movie  `json:"movie"`
+	CharacterName string `json:"character_name"`
+	BillingOrder  int    `json:"billing_order"`
+}
+
+// Genre is a normalized genre. MovieCount is the number of movies tagged
+// with it.
+type Genre struct {
+	ID         string `json:"id"`
+	Name       string `json:"name"`
+	MovieCount int    `json:"movie_count"`
+}
+
+type Director struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+// DirectorStats aggregates a director's credited movies. The averages and
+// years are nil when the director has no movies (or no reviews).
+type DirectorStats struct {
+	MovieCount         int      `json:"movie_count"`
+	AverageRating      *float64 `json:"average_rating"`
+	FirstYear          *int     `json:"first_year"`
+	LatestYear         *int     `json:"latest_year"`
+	ReviewCount        int      `json:"review_count"`
+	AverageReviewScore *float64 `json:"average_review_score"`
+}
+
+// ReviewStats aggregates a movie's reviews. Histogram counts reviews by
+// star rating, 1 star first. Average is nil for an unreviewed movie.
+// WeightedScore is the Bayesian average exposed as Movie.weighted_score,
+// nil only when no movie has reviews.
+type ReviewStats struct {
+	Count         int      `json:"count"`
+	Average       *float64 `json:"average"`
+	Histogram     []int    `json:"histogram"`
+	WeightedScore *float64 `json:"-"`
+}
+
+// PatchMoviePayload is the result of patchMovie. ChangedFields lists the
+// MoviePatch fields whose value actually changed.
+type PatchMoviePayload struct {
+	Movie         *Movie   `json:"movie"`
+	ChangedFields []string `json:"changed_fields"`
+}
+
+// MovieRevision is one recorded change to a movie. Before is nil for the
+// revision that created the movie and After for the one that deleted it.
+type MovieRevision struct {
+	ID        string    `json:"id"`
+	MovieID   string    `json:"movie_id"`
+	Mutation  string    `json:"mutation"`
+	Actor     string    `json:"actor"`
+	Before    *Movie    `json:"before"`
+	After     *Movie    `json:"after"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Trash item kinds.
+const (
+	TrashMovie  = "MOVIE"
+	TrashActor  = "ACTOR"
+	TrashReview = "REVIEW"
+)
+
+// TrashItem is a soft-deleted movie, actor or review. Only the field
+// matching Kind is set.
+type TrashItem struct {
+	Kind      string    `json:"kind"`
+	ID        string    `json:"id"`
+	DeletedAt time.Time `json:"deleted_at"`
+	Movie     *Movie    `json:"movie"`
+	Actor     *Actor    `json:"actor"`
+	Review    *Review   `json:"review"`
+}
+
+type TrashResult struct {
+	Items      []TrashItem `json:"items"`
+	Pagination Pagination  `json:"pagination"`
 }
 
 type Pagination struct {
-	Page  int `json:"page"`
-	Limit int `json:"limit"`
-	Total int `json:"total"`
+	Page       int `json:"page"`
+	Limit      int `json:"limit"`
+	Total      int `json:"total"`
+	TotalPages int `json:"total_pages"`
+}
+
+type MoviesResult struct {
+	Movies     []Movie    `json:"movies"`
+	Pagination Pagination `json:"pagination"`
+}
+
+// PageArgs are the Relay connection arguments. At most one of First and
+// Last is set; After and Before are opaque cursors from a previous page.
+// Backward pages with Last and Before; it is implied by Last > 0, so it only
+// needs setting for last: 0.
+type PageArgs struct {
+	First    int
+	After    string
+	Last     int
+	Before   string
+	Backward bool
+}
+
+// PageInfo describes a Connection page. The cursors are nil on an empty
+// page.
+type PageInfo struct {
+	HasNextPage     bool    `json:"hasNextPage"`
+	HasPreviousPage bool    `json:"hasPreviousPage"`
+	StartCursor     *string `json:"startCursor"`
+	EndCursor       *string `json:"endCursor"`
+}
+
+type Edge[T any] struct {
+	Node   T      `json:"node"`
+	Cursor string `json:"cursor"`
+}
+
+// Connection is one page of a Relay connection.
+type Connection[T any] struct {
+	Edges      []Edge[T] `json:"edges"`
+	PageInfo   PageInfo  `json:"pageInfo"`
+	TotalCount int       `json:"totalCount"`
+}
+
+type DirectorsResult struct {
+	Directors  []Director `json:"directors"`
+	Pagination Pagination `json:"pagination"`
+}
+
+// MovieSort is one key of a movie listing's order. Field is one of title,
+// year, rating, duration, created_at, average_review_score, review_count or
+// weighted_score.
+type MovieSort struct {
+	Field string
+	Desc  bool
 }
 
 type MovieFilter struct {
-	Genre   string  `json:"genre"`
-	MinYear int     `json:"min_year"`
-	MaxYear int     `json:"max_year"`
+	Genre     string  `json:"genre"`
+	MinYear   int     `json:"min_year"`
+	MaxYear   int     `json:"max_year"`
 	MinRating float64 `json:"min_rating"`
-	Search  string  `json:"search"`
-}
\ No newline at end of file
+	Search    string  `json:"search"`
+	// Exact, case-insensitive genre names. Genre is treated as a one-name
+	// GenresAny.
+	GenresAny     []string `json:"genres_any"`
+	GenresAll     []string `json:"genres_all"`
+	ExcludeGenres []string `json:"exclude_genres"`
+	// Review aggregates; zero means no limit.
+	MinReviewCount   int     `json:"min_review_count"`
+	MinAverageReview float64 `json:"min_average_review"`
+	MinWeightedScore float64 `json:"min_weighted_score"`
+}
+
+// APIKey is a stored API key. The key itself is only shown once, when it is
+// created; KeyHash is its SHA-256 and Prefix its first characters.
+type APIKey struct {
+	ID      string `json:"id"`
+	Name    string `json:"name"`
+	Prefix  string `json:"prefix"`
+	KeyHash string `json:"-"`
+	// Role is the auth.Role name the key grants, such as "editor".
+	Role      string     `json:"role"`
+	CreatedAt time.Time  `json:"created_at"`
+	RevokedAt *time.Time `json:"revoked_at"`
+}
@@ -0,0 +1,236 @@
+package ratelimit
+
+import (
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"movie-app/internal/apperr"
+	"movie-app/internal/auth"
+	"movie-app/internal/request"
+
+	"github.com/graphql-go/graphql/language/ast"
+)
+
+// Config sets the budgets of a Limiter. Every client gets its own buckets.
+type Config struct {
+	// Queries and Mutations are the budgets of each operation type.
+	// Subscriptions and requests that do not parse count as queries.
+	Queries   Limit
+	Mutations Limit
+	// Fields adds budgets for root fields by name, such as createReview.
+	// Each time a request selects one, aliases included, it is charged to
+	// the field's bucket as well as the query or mutation budget.
+	Fields map[string]Limit
+	// PerAddress is the budget of every request from one IP address,
+	// checked before credentials are, so guessing API keys or JWTs is
+	// throttled too. Zero turns it off.
+	PerAddress Limit
+	// TrustedProxies is how many proxies in front of the server append the
+	// address they got a request from to X-Forwarded-For. Requests are
+	// keyed by the entry that many from the right, the one the outermost
+	// proxy added; entries left of it are whatever the client sent. Zero
+	// ignores the header.
+	TrustedProxies int
+}
+
+// Limiter throttles GraphQL requests by client and operation.
+type Limiter struct {
+	config  Config
+	buckets *buckets
+}
+
+// New returns a Limiter with the budgets of config.
+func New(config Config) *Limiter {
+	return &Limiter{config: config, buckets: newBuckets()}
+}
+
+// Middleware charges each request to its client's buckets and reports what
+// is left in RateLimit-Limit, RateLimit-Remaining, RateLimit-Reset and
+// RateLimit-Policy headers. Requests over budget are answered with 429, a
+// Retry-After header and a RATE_LIMITED error. It must run after the
+// request middleware, which parses the operation, and the auth middleware,
+// which identifies the client.
+func (l *Limiter) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		decision := l.buckets.take(l.charges(r))
+		writeHeaders(w, decision)
+		if !decision.Allowed {
+			deny(w, decision)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// AddressMiddleware charges each request to the PerAddress budget of its IP
+// address and answers those over it like Middleware does. It runs before
+// the auth middleware, so it throttles requests whatever their credentials.
+// The headers of allowed requests are left to Middleware.
+func (l *Limiter) AddressMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if l.config.PerAddress.Requests > 0 {
+			key := "addr:" + clientAddress(r, l.config.TrustedProxies)
+			decision := l.buckets.take([]charge{{key: key, limit: l.config.PerAddress, tokens: 1}})
+			if !decision.Allowed {
+				writeHeaders(w, decision)
+				deny(w, decision)
+				return
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func writeHeaders(w http.ResponseWriter, decision Decision) {
+	h := w.Header()
+	h.Set("RateLimit-Limit", strconv.Itoa(decision.Limit.Requests))
+	h.Set("RateLimit-Remaining", strconv.Itoa(decision.Remaining))
+	h.Set("RateLimit-Reset", strconv.Itoa(ceilSeconds(decision.Reset)))
+	h.Set("RateLimit-Policy", fmt.Sprintf("%d;w=%d", decision.Limit.Requests, ceilSeconds(decision.Limit.Per)))
+}
+
+// deny answers a request over budget with 429 and a RATE_LIMITED error.
+func deny(w http.ResponseWriter, decision Decision) {
+	if decision.TooLarge {
+		apperr.WriteHTTP(w, http.StatusTooManyRequests, apperr.New(apperr.RateLimited,
+			"request costs more than the rate limit of %s allows; split it into smaller requests", decision.Limit))
+		return
+	}
+	retryAfter := ceilSeconds(decision.RetryAfter)
+	w.Header().Set("Retry-After", strconv.Itoa(retryAfter))
+	apperr.WriteHTTP(w, http.StatusTooManyRequests, &apperr.Error{
+		Code:    apperr.RateLimited,
+		Message: fmt.Sprintf("rate limit of %s exceeded; retry in %ds", decision.Limit, retryAfter),
+		Details: map[string]interface{}{"retry_after": retryAfter},
+	})
+}
+
+// charges lists the buckets a request is charged to: its operation type's,
+// one token per root field, and each overridden root field's, one token per
+// time the field is selected.
+func (l *Limiter) charges(r *http.Request) []charge {
+	client := clientKey(r, l.config.TrustedProxies)
+	operation, fields := requestOperation(r)
+
+	limit := l.config.Queries
+	if operation == ast.OperationTypeMutation {
+		limit = l.config.Mutations
+	}
+	charges := []charge{{key: client + "|" + operation, limit: limit, tokens: max(1, len(fields))}}
+
+	index := map[string]int{}
+	for _, field := range fields {
+		limit, ok := l.config.Fields[field]
+		if !ok {
+			continue
+		}
+		if i, ok := index[field]; ok {
+			charges[i].tokens++
+			continue
+		}
+		index[field] = len(charges)
+		charges = append(charges, charge{key: client + "|field:" + field, limit: limit, tokens: 1})
+	}
+	return charges
+}
+
+// clientKey identifies who a request is charged to: its API key or user,
+// or its IP address when it is anonymous.
+func clientKey(r *http.Request, trustedProxies int) string {
+	if principal, ok := auth.PrincipalFrom(r.Context()); ok {
+		if principal.Method == auth.MethodAPIKey {
+			return principal.Subject
+		}
+		return "user:" + principal.Subject
+	}
+	return "ip:" + clientAddress(r, trustedProxies)
+}
+
+// clientAddress is the IP address a request came from: the X-Forwarded-For
+// entry trustedProxies from the right, or else the peer's. A header with
+// fewer entries did not pass through every proxy, so the peer is used.
+func clientAddress(r *http.Request, trustedProxies int) string {
+	if trustedProxies > 0 {
+		var entries []string
+		for _, header := range r.Header.Values("X-Forwarded-For") {
+			entries = append(entries, strings.Split(header, ",")...)
+		}
+		if i := len(entries) - trustedProxies; i >= 0 {
+			if address := strings.TrimSpace(entries[i]); address != "" {
+				return address
+			}
+		}
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// requestOperation returns the type of the operation a request runs and
+// the names of its root fields, as read by the request middleware.
+func requestOperation(r *http.Request) (string, []string) {
+	parsed, ok := request.From(r.Context())
+	if !ok || parsed.Document == nil {
+		return ast.OperationTypeQuery, nil
+	}
+	opts := parsed.Options
+
+	var operation *ast.OperationDefinition
+	fragments := map[string]*ast.FragmentDefinition{}
+	for _, def := range parsed.Document.Definitions {
+		switch def := def.(type) {
+		case *ast.OperationDefinition:
+			named := def.Name != nil && def.Name.Value == opts.OperationName
+			if operation == nil && (opts.OperationName == "" || named) {
+				operation = def
+			}
+		case *ast.FragmentDefinition:
+			fragments[def.Name.Value] = def
+		}
+	}
+	if operation == nil {
+		return ast.OperationTypeQuery, nil
+	}
+
+	kind := operation.Operation
+	if kind != ast.OperationTypeMutation {
+		kind = ast.OperationTypeQuery
+	}
+	return kind, rootFields(operation.SelectionSet, fragments, map[string]bool{})
+}
+
+// rootFields names the fields of set, following fragments; visited guards
+// against fragments that spread themselves.
+func rootFields(set *ast.SelectionSet, fragments map[string]*ast.FragmentDefinition, visited map[string]bool) []string {
+	if set == nil {
+		return nil
+	}
+	var names []string
+	for _, selection := range set.Selections {
+		switch s := selection.(type) {
+		case *ast.Field:
+			names = append(names, s.Name.Value)
+		case *ast.InlineFragment:
+			names = append(names, rootFields(s.SelectionSet, fragments, visited)...)
+		case *ast.FragmentSpread:
+			name := s.Name.Value
+			if fragment, ok := fragments[name]; ok && !visited[name] {
+				visited[name] = true
+				names = append(names, rootFields(fragment.SelectionSet, fragments, visited)...)
+			}
+		}
+	}
+	return names
+}
+
+// ceilSeconds rounds d up to whole seconds, as the headers are reported.
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
@@ -0,0 +1,178 @@
+// Package ratelimit throttles GraphQL requests per client with token
+// buckets. Clients are told their budget in RateLimit-* response headers
+// and are turned away with a RATE_LIMITED error once it is spent.
+package ratelimit
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Limit allows Requests per Per, in bursts of up to Requests.
+type Limit struct {
+	Requests int
+	Per      time.Duration
+}
+
+// ParseLimit parses a limit written as requests/duration, such as "60/1m".
+func ParseLimit(s string) (Limit, error) {
+	requests, per, ok := strings.Cut(strings.TrimSpace(s), "/")
+	n, err := strconv.Atoi(requests)
+	if !ok || err != nil || n < 1 {
+		return Limit{}, fmt.Errorf("invalid limit %q; expected requests/duration such as 60/1m", s)
+	}
+	d, err := time.ParseDuration(per)
+	if err != nil || d <= 0 {
+		return Limit{}, fmt.Errorf("invalid limit %q; expected requests/duration such as 60/1m", s)
+	}
+	return Limit{Requests: n, Per: d}, nil
+}
+
+// String formats l as ParseLimit reads it, without zero minutes and seconds.
+func (l Limit) String() string {
+	per := l.Per.String()
+	if strings.HasSuffix(per, "m0s") {
+		per = strings.TrimSuffix(per, "0s")
+	}
+	if strings.HasSuffix(per, "h0m") {
+		per = strings.TrimSuffix(per, "0m")
+	}
+	return fmt.Sprintf("%d/%s", l.Requests, per)
+}
+
+// perSecond is how fast a bucket refills.
+func (l Limit) perSecond() float64 {
+	return float64(l.Requests) / l.Per.Seconds()
+}
+
+// bucket holds the tokens of one client under one limit as of updated.
+type bucket struct {
+	tokens  float64
+	updated time.Time
+}
+
+// refill adds the tokens earned since the bucket was last updated.
+func (b *bucket) refill(limit Limit, now time.Time) {
+	earned := now.Sub(b.updated).Seconds() * limit.perSecond()
+	b.tokens = math.Min(float64(limit.Requests), b.tokens+earned)
+	b.updated = now
+}
+
+// charge is tokens taken from the bucket key, which follows limit.
+type charge struct {
+	key    string
+	limit  Limit
+	tokens int
+}
+
+// Decision is the outcome of charging a request. Limit, Remaining and Reset
+// describe the charged bucket closest to empty.
+type Decision struct {
+	Allowed   bool
+	Limit     Limit
+	Remaining int
+	// Reset is how long until the bucket is full again.
+	Reset time.Duration
+	// RetryAfter is how long until the request would be allowed; zero when
+	// it is.
+	RetryAfter time.Duration
+	// TooLarge is set when the request costs more than a bucket holds, so
+	// it will never be allowed.
+	TooLarge bool
+}
+
+// sweepInterval is how often buckets that have refilled are dropped, so
+// clients that went away do not hold memory.
+const sweepInterval = time.Minute
+
+// buckets holds a Limiter's buckets by key.
+type buckets struct {
+	now func() time.Time
+
+	mu        sync.Mutex
+	byKey     map[string]*bucket
+	limits    map[string]Limit
+	lastSweep time.Time
+}
+
+func newBuckets() *buckets {
+	return &buckets{
+		now:    time.Now,
+		byKey:  map[string]*bucket{},
+		limits: map[string]Limit{},
+	}
+}
+
+// take charges every bucket in charges, or none of them if any lacks the
+// tokens.
+func (b *buckets) take(charges []charge) Decision {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := b.now()
+	if now.Sub(b.lastSweep) >= sweepInterval {
+		b.sweep(now)
+	}
+
+	decision := Decision{Allowed: true}
+	tightest, share := 0, math.Inf(1)
+	for i, c := range charges {
+		bkt, ok := b.byKey[c.key]
+		if !ok {
+			bkt = &bucket{tokens: float64(c.limit.Requests), updated: now}
+			b.byKey[c.key] = bkt
+			b.limits[c.key] = c.limit
+		}
+		bkt.refill(c.limit, now)
+
+		need := float64(c.tokens)
+		switch {
+		case c.tokens > c.limit.Requests:
+			decision.Allowed, decision.TooLarge = false, true
+		case bkt.tokens < need:
+			decision.Allowed = false
+			if wait := seconds((need - bkt.tokens) / c.limit.perSecond()); wait > decision.RetryAfter {
+				decision.RetryAfter = wait
+			}
+		}
+		// Buckets are compared by the share of their budget the request
+		// would leave, so the one reported is the one that denies it.
+		if left := (bkt.tokens - need) / float64(c.limit.Requests); left < share {
+			tightest, share = i, left
+		}
+	}
+
+	for i, c := range charges {
+		bkt := b.byKey[c.key]
+		if decision.Allowed {
+			bkt.tokens -= float64(c.tokens)
+		}
+		if i == tightest {
+			decision.Limit = c.limit
+			decision.Remaining = int(math.Max(0, math.Floor(bkt.tokens)))
+			decision.Reset = seconds((float64(c.limit.Requests) - bkt.tokens) / c.limit.perSecond())
+		}
+	}
+	return decision
+}
+
+// sweep drops the buckets that are full again. Callers must hold b.mu.
+func (b *buckets) sweep(now time.Time) {
+	for key, bkt := range b.byKey {
+		limit := b.limits[key]
+		bkt.refill(limit, now)
+		if bkt.tokens >= float64(limit.Requests) {
+			delete(b.byKey, key)
+			delete(b.limits, key)
+		}
+	}
+	b.lastSweep = now
+}
+
+func seconds(s float64) time.Duration {
+	return time.Duration(s * float64(time.Second))
+}
@@ -0,0 +1,325 @@
+package ratelimit
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"movie-app/internal/auth"
+	"movie-app/internal/request"
+)
+
+func TestParseLimit(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    Limit
+		wantErr bool
+	}{
+		{in: "60/1m", want: Limit{Requests: 60, Per: time.Minute}},
+		{in: " 5/10s ", want: Limit{Requests: 5, Per: 10 * time.Second}},
+		{in: "1000/1h", want: Limit{Requests: 1000, Per: time.Hour}},
+		{in: "60", wantErr: true},
+		{in: "0/1m", wantErr: true},
+		{in: "x/1m", wantErr: true},
+		{in: "60/0s", wantErr: true},
+		{in: "60/minute", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := ParseLimit(tt.in)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseLimit(%q) error = %v, wantErr %v", tt.in, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParseLimit(%q) = %v, want %v", tt.in, got, tt.want)
+		}
+		if !tt.wantErr && strings.TrimSpace(tt.in) != got.String() {
+			t.Errorf("ParseLimit(%q).String() = %q", tt.in, got)
+		}
+	}
+}
+
+func TestBucketsRefill(t *testing.T) {
+	now := time.Unix(0, 0)
+	b := newBuckets()
+	b.now = func() time.Time { return now }
+	limit := Limit{Requests: 2, Per: 10 * time.Second}
+	charges := []charge{{key: "a", limit: limit, tokens: 1}}
+
+	for i := 0; i < 2; i++ {
+		if d := b.take(charges); !d.Allowed {
+			t.Fatalf("request %d denied", i+1)
+		}
+	}
+	d := b.take(charges)
+	if d.Allowed || d.Remaining != 0 || d.RetryAfter != 5*time.Second {
+		t.Fatalf("third request = %+v, want denied with 5s to wait", d)
+	}
+
+	now = now.Add(5 * time.Second)
+	if d := b.take(charges); !d.Allowed || d.Remaining != 0 {
+		t.Fatalf("after 5s = %+v, want allowed with none left", d)
+	}
+
+	// Full buckets are swept so idle clients do not hold memory.
+	now = now.Add(time.Hour)
+	b.take([]charge{{key: "b", limit: limit, tokens: 1}})
+	if _, ok := b.byKey["a"]; ok {
+		t.Errorf("idle bucket was not swept")
+	}
+}
+
+func TestBucketsChargeAllOrNone(t *testing.T) {
+	b := newBuckets()
+	wide := charge{key: "wide", limit: Limit{Requests: 10, Per: time.Minute}, tokens: 1}
+	narrow := charge{key: "narrow", limit: Limit{Requests: 1, Per: time.Minute}, tokens: 1}
+
+	d := b.take([]charge{wide, narrow})
+	if !d.Allowed || d.Limit != narrow.limit || d.Remaining != 0 {
+		t.Fatalf("first = %+v, want allowed and reporting the narrow bucket", d)
+	}
+	if d := b.take([]charge{wide, narrow}); d.Allowed {
+		t.Fatalf("second = %+v, want denied", d)
+	}
+	if got := b.byKey["wide"].tokens; got < 8.9 || got > 9.1 {
+		t.Errorf("wide bucket has %v tokens; a denied request must not be charged", got)
+	}
+}
+
+func TestMiddleware(t *testing.T) {
+	limiter := New(Config{
+		Queries:   Limit{Requests: 3, Per: time.Minute},
+		Mutations: Limit{Requests: 1, Per: time.Minute},
+		Fields:    map[string]Limit{"searchMovies": {Requests: 1, Per: time.Minute}},
+	})
+	var served int
+	h := limiter.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		served++
+		// The handler still gets the body that was parsed.
+		if body, _ := io.ReadAll(r.Body); len(body) == 0 {
+			t.Errorf("handler got an empty body")
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	do := func(query, remoteAddr string, principal *auth.Principal) *httptest.ResponseRecorder {
+		return post(h, query, remoteAddr, principal)
+	}
+
+	const ip = "192.0.2.1:1234"
+	w := do(`{ movies { id } }`, ip, nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("first query: status %d", w.Code)
+	}
+	for header, want := range map[string]string{
+		"RateLimit-Limit":     "3",
+		"RateLimit-Remaining": "2",
+		"RateLimit-Reset":     "20",
+		"RateLimit-Policy":    "3;w=60",
+	} {
+		if got := w.Header().Get(header); got != want {
+			t.Errorf("%s = %q, want %q", header, got, want)
+		}
+	}
+
+	// Mutations have their own budget.
+	if w := do(`mutation { deleteMovie(id: "1") }`, ip, nil); w.Code != http.StatusOK {
+		t.Fatalf("first mutation: status %d", w.Code)
+	}
+	w = do(`mutation M { deleteMovie(id: "1") }`, ip, nil)
+	if w.Code != http.StatusTooManyRequests {
+		t.Fatalf("second mutation: status %d, want 429", w.Code)
+	}
+	if got := w.Header().Get("Retry-After"); got != "60" {
+		t.Errorf("Retry-After = %q, want 60", got)
+	}
+	if extensions := errorExtensions(t, w); extensions["code"] != "RATE_LIMITED" || extensions["retry_after"] != float64(60) {
+		t.Fatalf("429 extensions = %v", extensions)
+	}
+
+	// An overridden field is charged to its own bucket and the query budget.
+	if w := do(`query { ...F } fragment F on Query { searchMovies(query: "x") { id } }`, ip, nil); w.Code != http.StatusOK {
+		t.Fatalf("first search: status %d", w.Code)
+	}
+	if w := do(`{ searchMovies(query: "y") { id } }`, ip, nil); w.Code != http.StatusTooManyRequests {
+		t.Fatalf("second search: status %d, want 429", w.Code)
+	}
+	if w := do(`{ movies { id } }`, ip, nil); w.Header().Get("RateLimit-Remaining") != "0" {
+		t.Errorf("RateLimit-Remaining = %q, want the search and no more charged to the query budget", w.Header().Get("RateLimit-Remaining"))
+	}
+
+	// Other clients have their own buckets.
+	apiKey := &auth.Principal{Subject: "api-key:ci", Method: auth.MethodAPIKey, Role: auth.Editor}
+	user := &auth.Principal{Subject: "alice", Method: auth.MethodJWT, Role: auth.Reviewer}
+	for _, c := range []struct {
+		remoteAddr string
+		principal  *auth.Principal
+	}{
+		{"198.51.100.7:999", nil},
+		{ip, apiKey},
+		{ip, user},
+	} {
+		if w := do(`mutation { deleteMovie(id: "1") }`, c.remoteAddr, c.principal); w.Code != http.StatusOK {
+			t.Errorf("mutation from %s %v: status %d", c.remoteAddr, c.principal, w.Code)
+		}
+	}
+
+	if served != 7 {
+		t.Errorf("handler served %d requests, want 7", served)
+	}
+}
+
+func TestMiddlewareAliasedFields(t *testing.T) {
+	limiter := New(Config{
+		Queries:   Limit{Requests: 10, Per: time.Minute},
+		Mutations: Limit{Requests: 10, Per: time.Minute},
+		Fields:    map[string]Limit{"createReview": {Requests: 3, Per: time.Minute}},
+	})
+	h := limiter.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	aliases := func(n int, field string) string {
+		var b strings.Builder
+		b.WriteString("mutation {")
+		for i := 0; i < n; i++ {
+			fmt.Fprintf(&b, " a%d: %s", i, field)
+		}
+		b.WriteString(" }")
+		return b.String()
+	}
+	const ip = "192.0.2.1:1234"
+	const review = `createReview(input: { movieId: "1", rating: 5 }) { id }`
+
+	// Each alias is charged to the field's bucket.
+	w := post(h, aliases(2, review), ip, nil)
+	if w.Code != http.StatusOK || w.Header().Get("RateLimit-Remaining") != "1" {
+		t.Fatalf("two reviews: status %d, RateLimit-Remaining %q", w.Code, w.Header().Get("RateLimit-Remaining"))
+	}
+	if w := post(h, aliases(2, review), ip, nil); w.Code != http.StatusTooManyRequests || w.Header().Get("Retry-After") != "20" {
+		t.Fatalf("two more reviews: status %d, Retry-After %q, want 429 after 20s", w.Code, w.Header().Get("Retry-After"))
+	}
+
+	// And every root field to the mutation budget, so a request that could
+	// never fit is refused outright.
+	w = post(h, aliases(500, `deleteMovie(id: "1")`), ip, nil)
+	if w.Code != http.StatusTooManyRequests || w.Header().Get("Retry-After") != "" {
+		t.Fatalf("500 deletes: status %d, Retry-After %q, want 429 with none", w.Code, w.Header().Get("Retry-After"))
+	}
+	if extensions := errorExtensions(t, w); extensions["code"] != "RATE_LIMITED" {
+		t.Errorf("500 deletes: extensions %v", extensions)
+	}
+}
+
+func TestAddressMiddleware(t *testing.T) {
+	limiter := New(Config{
+		Queries:    Limit{Requests: 100, Per: time.Minute},
+		Mutations:  Limit{Requests: 100, Per: time.Minute},
+		PerAddress: Limit{Requests: 2, Per: time.Minute},
+	})
+	// The address limiter runs before credentials are checked, so failed
+	// logins use up its budget too.
+	h := limiter.AddressMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+
+	const ip = "192.0.2.1:1234"
+	for i := 0; i < 2; i++ {
+		if w := post(h, `{ movies { id } }`, ip, nil); w.Code != http.StatusUnauthorized {
+			t.Fatalf("request %d: status %d, want it passed on", i+1, w.Code)
+		}
+	}
+	w := post(h, `{ movies { id } }`, ip, nil)
+	if w.Code != http.StatusTooManyRequests || w.Header().Get("Retry-After") != "30" {
+		t.Fatalf("third request: status %d, Retry-After %q, want 429 after 30s", w.Code, w.Header().Get("Retry-After"))
+	}
+	if w := post(h, `{ movies { id } }`, "198.51.100.7:999", nil); w.Code != http.StatusUnauthorized {
+		t.Errorf("other address: status %d, want it passed on", w.Code)
+	}
+}
+
+func TestClientKeyForwardedFor(t *testing.T) {
+	tests := []struct {
+		name           string
+		forwardedFor   []string
+		trustedProxies int
+		want           string
+	}{
+		{name: "untrusted", forwardedFor: []string{"203.0.113.9"}, want: "ip:10.0.0.1"},
+		{name: "one proxy", forwardedFor: []string{"203.0.113.9"}, trustedProxies: 1, want: "ip:203.0.113.9"},
+		{name: "spoofed by the client", forwardedFor: []string{"198.51.100.66, 203.0.113.9"}, trustedProxies: 1, want: "ip:203.0.113.9"},
+		{name: "two proxies", forwardedFor: []string{"198.51.100.66, 203.0.113.9, 10.0.0.2"}, trustedProxies: 2, want: "ip:203.0.113.9"},
+		{name: "split across headers", forwardedFor: []string{"198.51.100.66", "203.0.113.9, 10.0.0.2"}, trustedProxies: 2, want: "ip:203.0.113.9"},
+		{name: "fewer entries than proxies", forwardedFor: []string{"203.0.113.9"}, trustedProxies: 2, want: "ip:10.0.0.1"},
+		{name: "no header", trustedProxies: 1, want: "ip:10.0.0.1"},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest(http.MethodPost, "/graphql", nil)
+		r.RemoteAddr = "10.0.0.1:5555"
+		for _, value := range tt.forwardedFor {
+			r.Header.Add("X-Forwarded-For", value)
+		}
+		if got := clientKey(r, tt.trustedProxies); got != tt.want {
+			t.Errorf("%s: clientKey = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestAddressMiddlewareIgnoresSpoofedForwardedFor(t *testing.T) {
+	limiter := New(Config{PerAddress: Limit{Requests: 2, Per: time.Minute}, TrustedProxies: 1})
+	h := limiter.AddressMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	// A client that invents a new address on every request is still keyed
+	// by the address the proxy appended.
+	var codes []int
+	for i := 0; i < 3; i++ {
+		r := httptest.NewRequest(http.MethodPost, "/graphql", nil)
+		r.RemoteAddr = "10.0.0.1:5555"
+		r.Header.Set("X-Forwarded-For", fmt.Sprintf("198.51.100.%d, 203.0.113.9", i))
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		codes = append(codes, w.Code)
+	}
+	if fmt.Sprint(codes) != "[200 200 429]" {
+		t.Errorf("statuses = %v, want the third request refused", codes)
+	}
+}
+
+// post sends query to h from remoteAddr, as principal if it is not nil,
+// through the request middleware that parses it for the limiter.
+func post(h http.Handler, query, remoteAddr string, principal *auth.Principal) *httptest.ResponseRecorder {
+	body, _ := json.Marshal(map[string]string{"query": query})
+	r := httptest.NewRequest(http.MethodPost, "/graphql", strings.NewReader(string(body)))
+	r.Header.Set("Content-Type", "application/json")
+	r.RemoteAddr = remoteAddr
+	if principal != nil {
+		r = r.WithContext(auth.WithPrincipal(context.Background(), principal))
+	}
+	w := httptest.NewRecorder()
+	request.Middleware(1<<20, h).ServeHTTP(w, r)
+	return w
+}
+
+// errorExtensions decodes the extensions of the one error in w's body.
+func errorExtensions(t *testing.T, w *httptest.ResponseRecorder) map[string]interface{} {
+	t.Helper()
+	var resp struct {
+		Errors []struct {
+			Extensions map[string]interface{} `json:"extensions"`
+		} `json:"errors"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatal(err)
+	}
+	if len(resp.Errors) != 1 {
+		t.Fatalf("body has %d errors, want 1", len(resp.Errors))
+	}
+	return resp.Errors[0].Extensions
+}
@@ -0,0 +1,72 @@
+// Package request reads and parses GraphQL HTTP requests for the middlewares
+// in front of the GraphQL handler, so they share one size-capped read of
+// the body. The handler still reads the body and parses the query itself.
+package request
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+
+	"movie-app/internal/apperr"
+
+	"github.com/graphql-go/graphql/language/ast"
+	"github.com/graphql-go/graphql/language/parser"
+	"github.com/graphql-go/handler"
+)
+
+// Parsed is what a request asks to run.
+type Parsed struct {
+	// Options are the query, operation name and variables as the client
+	// sent them.
+	Options *handler.RequestOptions
+	// Document is the parsed query, or nil if it does not parse; the
+	// handler reports why.
+	Document *ast.Document
+}
+
+type parsedKey struct{}
+
+// WithParsed attaches a parsed request to ctx.
+func WithParsed(ctx context.Context, parsed *Parsed) context.Context {
+	return context.WithValue(ctx, parsedKey{}, parsed)
+}
+
+// From returns the request attached by WithParsed, if any.
+func From(ctx context.Context) (*Parsed, bool) {
+	parsed, _ := ctx.Value(parsedKey{}).(*Parsed)
+	return parsed, parsed != nil
+}
+
+// Middleware reads and parses each request's body, up to maxBodyBytes, and
+// attaches the result to the request's context for the middlewares after
+// it. Larger bodies are answered with 413 and a REQUEST_TOO_LARGE error. The
+// body is left in place for the GraphQL handler, which parses it again.
+func Middleware(maxBodyBytes int64, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		clone := r.Clone(r.Context())
+		if r.Body != nil && r.Body != http.NoBody {
+			body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxBodyBytes))
+			var tooLarge *http.MaxBytesError
+			switch {
+			case errors.As(err, &tooLarge):
+				apperr.WriteHTTP(w, http.StatusRequestEntityTooLarge, apperr.New(apperr.RequestTooLarge,
+					"request body exceeds the maximum of %d bytes", maxBodyBytes))
+				return
+			case err != nil:
+				apperr.WriteHTTP(w, http.StatusBadRequest, apperr.New(apperr.ValidationFailed, "could not read the request body"))
+				return
+			}
+			r.Body = io.NopCloser(bytes.NewReader(body))
+			clone.Body = io.NopCloser(bytes.NewReader(body))
+		}
+
+		parsed := &Parsed{Options: handler.NewRequestOptions(clone)}
+		if doc, err := parser.Parse(parser.ParseParams{Source: parsed.Options.Query}); err == nil {
+			parsed.Document = doc
+		}
+		next.ServeHTTP(w, r.WithContext(WithParsed(r.Context(), parsed)))
+	})
+}
@@ -0,0 +1,63 @@
+package request
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestMiddleware(t *testing.T) {
+	body := `{"query": "query Q { movies { pagination { total } } }", "operationName": "Q", "variables": {"patch": {"genre": null}}}`
+	var parsed *Parsed
+	h := Middleware(1<<10, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		parsed, _ = From(r.Context())
+		if rest, _ := io.ReadAll(r.Body); string(rest) != body {
+			t.Errorf("expected the body to be left for the handler, got %q", rest)
+		}
+	}))
+
+	r := httptest.NewRequest(http.MethodPost, "/graphql", strings.NewReader(body))
+	r.Header.Set("Content-Type", "application/json")
+	h.ServeHTTP(httptest.NewRecorder(), r)
+	if parsed == nil || parsed.Document == nil || parsed.Options.OperationName != "Q" {
+		t.Fatalf("expected the parsed request in the context, got %+v", parsed)
+	}
+	if patch, ok := parsed.Options.Variables["patch"].(map[string]interface{}); !ok || patch["genre"] != nil || len(patch) != 1 {
+		t.Fatalf("expected the null genre to survive, got %v", parsed.Options.Variables)
+	}
+
+	// Queries that do not parse are passed on for the handler to report.
+	r = httptest.NewRequest(http.MethodGet, "/graphql?query=%7B+movies%28+%7D", nil)
+	parsed = nil
+	h = Middleware(1<<10, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		parsed, _ = From(r.Context())
+	}))
+	h.ServeHTTP(httptest.NewRecorder(), r)
+	if parsed == nil || parsed.Options.Query != "{ movies( }" || parsed.Document != nil {
+		t.Fatalf("expected an unparsed query, got %+v", parsed)
+	}
+}
+
+func TestMiddlewareBodyTooLarge(t *testing.T) {
+	h := Middleware(64, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("handler ran for an oversized body")
+	}))
+	body, _ := json.Marshal(map[string]string{"query": "{ movies { movies { title } } }" + strings.Repeat(" ", 64)})
+	r := httptest.NewRequest(http.MethodPost, "/graphql", strings.NewReader(string(body)))
+	r.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+
+	var resp struct {
+		Errors []struct {
+			Extensions map[string]interface{} `json:"extensions"`
+		} `json:"errors"`
+	}
+	json.NewDecoder(w.Body).Decode(&resp)
+	if w.Code != http.StatusRequestEntityTooLarge || len(resp.Errors) != 1 || resp.Errors[0].Extensions["code"] != "REQUEST_TOO_LARGE" {
+		t.Fatalf("status %d, body %+v", w.Code, resp)
+	}
+}
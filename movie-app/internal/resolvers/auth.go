@@ -0,0 +1,114 @@
+package resolvers
+
+import (
+	"fmt"
+	"log"
+	"os"
+
+	"movie-app/internal/apperr"
+	"movie-app/internal/auth"
+	"movie-app/internal/schema"
+
+	"github.com/graphql-go/graphql"
+)
+
+// permission says who may call a root field: callers with at least role
+// or, when owner is set, callers with at least own acting on a row that
+// owner says is theirs.
+type permission struct {
+	role  auth.Role
+	own   auth.Role
+	owner func(r *Resolver, p graphql.ResolveParams) (string, error)
+}
+
+// permissions lists every Query and Mutation field. Fields missing from it
+// are left to admins.
+var permissions = map[string]map[string]permission{
+	"Query": {
+		"movie":                  {role: auth.Viewer},
+		"movieAt":                {role: auth.Viewer},
+		"movies":                 {role: auth.Viewer},
+		"searchMovies":           {role: auth.Viewer},
+		"moviesConnection":       {role: auth.Viewer},
+		"searchMoviesConnection": {role: auth.Viewer},
+		"reviewsConnection":      {role: auth.Viewer},
+		"actor":                  {role: auth.Viewer},
+		"director":               {role: auth.Viewer},
+		"directors":              {role: auth.Viewer},
+		"genres":                 {role: auth.Viewer},
+		"reviews":                {role: auth.Viewer},
+		"me":                     {role: auth.Viewer},
+		"trash":                  {role: auth.Editor},
+	},
+	"Mutation": {
+		"createMovie":            {role: auth.Editor},
+		"patchMovie":             {role: auth.Editor},
+		"revertMovie":            {role: auth.Editor},
+		"updateMovie":            {role: auth.Editor},
+		"deleteMovie":            {role: auth.Editor},
+		"restoreMovie":           {role: auth.Editor},
+		"createActor":            {role: auth.Editor},
+		"deleteActor":            {role: auth.Editor},
+		"restoreActor":           {role: auth.Editor},
+		"addCastMember":          {role: auth.Editor},
+		"reorderCast":            {role: auth.Editor},
+		"removeCastMember":       {role: auth.Editor},
+		"createReview":           {role: auth.Reviewer},
+		"upsertReview":           {role: auth.Reviewer},
+		"deleteReview":           {role: auth.Editor, own: auth.Reviewer, owner: (*Resolver).reviewOwner},
+		"restoreReview":          {role: auth.Editor},
+		"createMovieWithDetails": {role: auth.Admin},
+		"purgeTrash":             {role: auth.Admin},
+		"updateProfile":          {role: auth.Viewer},
+	},
+}
+
+// auditLog records every denied call.
+var auditLog = log.New(os.Stderr, "audit: ", log.LstdFlags)
+
+// authorize wraps every Query and Mutation resolver so that it only runs
+// for callers that permissions allows. Anonymous callers are viewers.
+func (r *Resolver) authorize(resolvers schema.Resolvers) schema.Resolvers {
+	for _, typeName := range []string{"Query", "Mutation"} {
+		for field, resolve := range resolvers[typeName] {
+			perm, ok := permissions[typeName][field]
+			if !ok {
+				perm = permission{role: auth.Admin}
+			}
+			resolvers[typeName][field] = r.authorized(typeName, perm, resolve)
+		}
+	}
+	return resolvers
+}
+
+func (r *Resolver) authorized(typeName string, perm permission, resolve graphql.FieldResolveFn) graphql.FieldResolveFn {
+	return func(p graphql.ResolveParams) (interface{}, error) {
+		principal, authenticated := auth.PrincipalFrom(p.Context)
+		if !authenticated {
+			principal = &auth.Principal{Subject: anonymousActor, Method: "none", Role: auth.Viewer}
+		}
+		if principal.Role >= perm.role {
+			return resolve(p)
+		}
+		if authenticated && perm.owner != nil && principal.Role >= perm.own {
+			owner, err := perm.owner(r, p)
+			if err != nil {
+				return nil, err
+			}
+			if owner == principal.Subject {
+				return resolve(p)
+			}
+		}
+
+		required := fmt.Sprintf("the %s role", perm.role)
+		if perm.owner != nil {
+			required += fmt.Sprintf(", or the %s role on your own rows", perm.own)
+		}
+		auditLog.Printf("denied %s.%s to %q (%s, role %s): requires %s",
+			typeName, p.Info.FieldName, principal.Subject, principal.Method, principal.Role, required)
+		if !authenticated {
+			return nil, apperr.New(apperr.Unauthenticated, "%s requires authentication", p.Info.FieldName)
+		}
+		return nil, apperr.New(apperr.Forbidden, "%s requires %s", p.Info.FieldName, required)
+	}
+}
@@ -0,0 +1,113 @@
+package resolvers
+
+import (
+	"movie-app/internal/apperr"
+	"movie-app/internal/models"
+	"movie-app/internal/store"
+
+	"github.com/graphql-go/graphql"
+)
+
+func (r *Resolver) AddCastMember(p graphql.ResolveParams) (interface{}, error) {
+	movieID, _ := p.Args["movie_id"].(string)
+	actorID, _ := p.Args["actor_id"].(string)
+	billingOrder, _ := p.Args["billing_order"].(int)
+	if billingOrder < 0 {
+		return nil, apperr.Invalid("billing_order", "billing_order must be positive")
+	}
+
+	var member *models.CastMember
+	err := r.store.InTx(p.Context, func(tx store.Store) error {
+		if _, err := r.requireMovie(p, tx, movieID); err != nil {
+			return err
+		}
+		if _, err := tx.GetActor(p.Context, actorID); err == store.ErrNotFound {
+			return apperr.NotFoundf("actor not found")
+		} else if err != nil {
+			return err
+		}
+
+		err := tx.AddCastMember(p.Context, models.MovieActor{
+			MovieID:       movieID,
+			ActorID:       actorID,
+			CharacterName: stringArg(p.Args, "character_name"),
+			BillingOrder:  billingOrder,
+		})
+		if err == store.ErrDuplicate {
+			return apperr.Conflictf(nil, "actor %s is already in the cast of movie %s", actorID, movieID)
+		}
+		if err != nil {
+			return err
+		}
+
+		cast, err := castOf(p, tx, movieID)
+		if err != nil {
+			return err
+		}
+		for i := range cast {
+			if cast[i].Actor.ID == actorID {
+				member = &cast[i]
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return member, nil
+}
+
+func (r *Resolver) ReorderCast(p graphql.ResolveParams) (interface{}, error) {
+	movieID, _ := p.Args["movie_id"].(string)
+	actorIDs := stringsArg(p.Args, "actor_ids")
+
+	var cast []models.CastMember
+	err := r.store.InTx(p.Context, func(tx store.Store) error {
+		if _, err := r.requireMovie(p, tx, movieID); err != nil {
+			return err
+		}
+		if err := tx.ReorderCast(p.Context, movieID, actorIDs); err != nil {
+			return err
+		}
+
+		var err error
+		cast, err = castOf(p, tx, movieID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return cast, nil
+}
+
+func (r *Resolver) RemoveCastMember(p graphql.ResolveParams) (interface{}, error) {
+	movieID, _ := p.Args["movie_id"].(string)
+	actorID, _ := p.Args["actor_id"].(string)
+
+	var removed bool
+	err := r.store.InTx(p.Context, func(tx store.Store) error {
+		var err error
+		removed, err = tx.RemoveCastMember(p.Context, movieID, actorID)
+		return err
+	})
+	return removed, err
+}
+
+func (r *Resolver) requireMovie(p graphql.ResolveParams, s store.Store, id string) (*models.Movie, error) {
+	movie, err := s.GetMovie(p.Context, id)
+	if err == store.ErrNotFound {
+		return nil, apperr.NotFoundf("movie not found")
+	}
+	return movie, err
+}
+
+func castOf(p graphql.ResolveParams, s store.Store, movieID string) ([]models.CastMember, error) {
+	byMovie, err := s.CastForMovies(p.Context, []string{movieID})
+	if err != nil {
+		return nil, err
+	}
+	if byMovie[movieID] == nil {
+		return []models.CastMember{}, nil
+	}
+	return byMovie[movieID], nil
+}
@@ -0,0 +1,31 @@
+package resolvers
+
+import (
+	"movie-app/internal/apperr"
+
+	"github.com/graphql-go/graphql"
+)
+
+// expectedVersionArg reads the optional expected_version argument; 0 means
+// the caller did not ask for a version check.
+func expectedVersionArg(p graphql.ResolveParams) (int, error) {
+	version, ok := p.Args["expected_version"].(int)
+	if ok && version < 1 {
+		return 0, apperr.Invalid("expected_version", "expected_version must be positive")
+	}
+	return version, nil
+}
+
+// movieConflict builds the CONFLICT error for a stale write to movie id.
+// Its extensions carry the movie as currently stored so clients can merge
+// their edit and retry.
+func (r *Resolver) movieConflict(p graphql.ResolveParams, id string, expected int) error {
+	current, err := r.requireMovie(p, r.store, id)
+	if err != nil {
+		return err
+	}
+	return apperr.Conflictf(map[string]interface{}{
+		"expected_version": expected,
+		"current":          current,
+	}, "movie %s was modified: expected version %d, current version %d", current.ID, expected, current.Version)
+}
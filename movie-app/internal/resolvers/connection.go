@@ -0,0 +1,100 @@
+package resolvers
+
+import (
+	"movie-app/internal/apperr"
+	"movie-app/internal/models"
+	"movie-app/internal/store"
+
+	"github.com/graphql-go/graphql"
+)
+
+// DefaultPageSize is the limit of page-based fields, and the page size of
+// connection fields that get neither first nor last.
+const DefaultPageSize = 10
+
+// MaxPageSize caps limit, first and last, so one request cannot fetch a
+// whole table. Larger values are clamped rather than rejected.
+const MaxPageSize = 100
+
+func (r *Resolver) GetMoviesConnection(p graphql.ResolveParams) (interface{}, error) {
+	page, err := connectionArgs(p)
+	if err != nil {
+		return nil, err
+	}
+
+	conn, err := r.store.MoviesConnection(p.Context, movieFilterArg(p), movieSortArg(p), page)
+	return connectionResult(conn, page, err)
+}
+
+func (r *Resolver) SearchMoviesConnection(p graphql.ResolveParams) (interface{}, error) {
+	query, ok := p.Args["query"].(string)
+	if !ok {
+		return nil, apperr.Invalid("query", "query is required")
+	}
+
+	page, err := connectionArgs(p)
+	if err != nil {
+		return nil, err
+	}
+
+	conn, err := r.store.SearchMoviesConnection(p.Context, query, movieSortArg(p), page)
+	return connectionResult(conn, page, err)
+}
+
+func (r *Resolver) GetReviewsConnection(p graphql.ResolveParams) (interface{}, error) {
+	movieID, ok := p.Args["movie_id"].(string)
+	if !ok {
+		return nil, apperr.Invalid("movie_id", "movie_id is required")
+	}
+
+	page, err := connectionArgs(p)
+	if err != nil {
+		return nil, err
+	}
+
+	conn, err := r.store.ReviewsConnection(p.Context, movieID, page)
+	return connectionResult(conn, page, err)
+}
+
+// connectionArgs reads and checks the Relay first/after/last/before
+// arguments.
+func connectionArgs(p graphql.ResolveParams) (models.PageArgs, error) {
+	page := models.PageArgs{
+		After:  stringArg(p.Args, "after"),
+		Before: stringArg(p.Args, "before"),
+	}
+	first, hasFirst := p.Args["first"].(int)
+	last, hasLast := p.Args["last"].(int)
+
+	switch {
+	case hasFirst && hasLast:
+		return page, apperr.Invalid("last", "first and last cannot be used together")
+	case hasFirst && page.Before != "", hasLast && page.After != "":
+		return page, apperr.Invalid("after", "use first with after, or last with before")
+	case hasFirst && first < 0, hasLast && last < 0:
+		return page, apperr.Invalid("first", "first and last must not be negative")
+	case hasLast:
+		page.Last, page.Backward = min(last, MaxPageSize), true
+	case hasFirst:
+		page.First = min(first, MaxPageSize)
+	case page.Before != "":
+		page.Last, page.Backward = DefaultPageSize, true
+	default:
+		page.First = DefaultPageSize
+	}
+	return page, nil
+}
+
+func connectionResult[T any](conn *models.Connection[T], page models.PageArgs, err error) (interface{}, error) {
+	if err == store.ErrInvalidCursor {
+		field := "after"
+		if page.Before != "" {
+			field = "before"
+		}
+		return nil, apperr.Invalid(field, "invalid cursor")
+	}
+	if err != nil {
+		return nil, err
+	}
+	return conn, nil
+}
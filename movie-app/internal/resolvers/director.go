@@ -0,0 +1,73 @@
+package resolvers
+
+import (
+	"movie-app/internal/apperr"
+	"movie-app/internal/models"
+	"movie-app/internal/store"
+
+	"github.com/graphql-go/graphql"
+)
+
+func (r *Resolver) GetDirector(p graphql.ResolveParams) (interface{}, error) {
+	id, ok := p.Args["id"].(string)
+	if !ok {
+		return nil, apperr.Invalid("id", "id is required")
+	}
+
+	director, err := r.store.GetDirector(p.Context, id)
+	if err == store.ErrNotFound {
+		return nil, apperr.NotFoundf("director not found")
+	}
+	return director, err
+}
+
+func (r *Resolver) GetDirectors(p graphql.ResolveParams) (interface{}, error) {
+	page, limit, err := pageArgs(p)
+	if err != nil {
+		return nil, err
+	}
+
+	directors, total, err := r.store.ListDirectors(p.Context, stringArg(p.Args, "search"), limit, (page-1)*limit)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.DirectorsResult{
+		Directors:  directors,
+		Pagination: newPagination(page, limit, total),
+	}, nil
+}
+
+func (r *Resolver) resolveMovieDirectors(p graphql.ResolveParams) (interface{}, error) {
+	movie, ok := movieFromSource(p.Source)
+	if !ok {
+		return []models.Director{}, nil
+	}
+	return r.loadersFor(p.Context).directors.load(p.Context, movie.ID), nil
+}
+
+func (r *Resolver) resolveDirectorFilmography(p graphql.ResolveParams) (interface{}, error) {
+	director, ok := directorFromSource(p.Source)
+	if !ok {
+		return []models.Movie{}, nil
+	}
+	return r.loadersFor(p.Context).directorFilmography.load(p.Context, director.ID), nil
+}
+
+func (r *Resolver) resolveDirectorStats(p graphql.ResolveParams) (interface{}, error) {
+	director, ok := directorFromSource(p.Source)
+	if !ok {
+		return models.DirectorStats{}, nil
+	}
+	return r.loadersFor(p.Context).directorStats.load(p.Context, director.ID), nil
+}
+
+func directorFromSource(source interface{}) (*models.Director, bool) {
+	switch director := source.(type) {
+	case *models.Director:
+		return director, true
+	case models.Director:
+		return &director, true
+	}
+	return nil, false
+}
@@ -0,0 +1,94 @@
+package resolvers
+
+import (
+	"errors"
+	"fmt"
+	"movie-app/internal/apperr"
+	"movie-app/internal/schema"
+	"movie-app/internal/store"
+
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/gqlerrors"
+)
+
+// publicError turns an error from a resolver into an *apperr.Error.
+// Store errors map to their codes; anything else is masked as INTERNAL so
+// SQL and driver text never reach clients.
+func publicError(err error) *apperr.Error {
+	var appErr *apperr.Error
+	var invalid *store.ValidationError
+	switch {
+	case errors.As(err, &appErr):
+		return appErr
+	case errors.As(err, &invalid):
+		return apperr.Invalid(invalid.Field, "%s", invalid.Message)
+	case errors.Is(err, store.ErrNotFound):
+		return apperr.NotFoundf("not found")
+	case errors.Is(err, store.ErrInvalidCursor):
+		return apperr.Invalid("after", "invalid cursor")
+	case errors.Is(err, store.ErrVersionConflict), errors.Is(err, store.ErrDuplicate):
+		return apperr.New(apperr.Conflict, "%v", err)
+	default:
+		return apperr.Mask(err)
+	}
+}
+
+// withPublicErrors wraps every resolver so that the errors it returns,
+// including those of deferred batch-loaded results, go through publicError.
+// A panicking resolver is reported as an INTERNAL error too.
+func withPublicErrors(resolvers schema.Resolvers) schema.Resolvers {
+	for _, fields := range resolvers {
+		for name, resolve := range fields {
+			fields[name] = publicResolver(resolve)
+		}
+	}
+	return resolvers
+}
+
+func publicResolver(resolve graphql.FieldResolveFn) graphql.FieldResolveFn {
+	return func(p graphql.ResolveParams) (result interface{}, err error) {
+		defer func() {
+			if r := recover(); r != nil {
+				result, err = nil, apperr.Mask(fmt.Errorf("panic in %s.%s: %v", p.Info.ParentType.Name(), p.Info.FieldName, r))
+			}
+		}()
+
+		result, err = resolve(p)
+		if err != nil {
+			return nil, publicError(err)
+		}
+		if thunk, ok := result.(func() (interface{}, error)); ok {
+			return func() (interface{}, error) {
+				value, err := thunk()
+				if err != nil {
+					return nil, publicError(err)
+				}
+				return value, nil
+			}, nil
+		}
+		return result, nil
+	}
+}
+
+// FormatError formats the errors graphql-go reports. Resolver errors are
+// already *apperr.Error values; errors graphql-go raises itself are coded
+// VALIDATION_FAILED when the request was at fault (a syntax error, an
+// unknown field, a bad argument) and masked as INTERNAL otherwise. Use it
+// as the handler's FormatErrorFn.
+func FormatError(err error) gqlerrors.FormattedError {
+	formatted := gqlerrors.FormatError(err)
+	if _, ok := formatted.Extensions["code"]; ok {
+		return formatted
+	}
+
+	var located *gqlerrors.Error
+	if errors.As(err, &located) && located.OriginalError == nil {
+		formatted.Extensions = map[string]interface{}{"code": string(apperr.ValidationFailed)}
+		return formatted
+	}
+
+	masked := apperr.Mask(err)
+	formatted.Message = masked.Message
+	formatted.Extensions = masked.Extensions()
+	return formatted
+}
@@ -0,0 +1,19 @@
+package resolvers
+
+import (
+	"movie-app/internal/models"
+
+	"github.com/graphql-go/graphql"
+)
+
+func (r *Resolver) GetGenres(p graphql.ResolveParams) (interface{}, error) {
+	return r.store.ListGenres(p.Context)
+}
+
+func (r *Resolver) resolveMovieGenres(p graphql.ResolveParams) (interface{}, error) {
+	movie, ok := movieFromSource(p.Source)
+	if !ok {
+		return []models.Genre{}, nil
+	}
+	return r.loadersFor(p.Context).genres.load(p.Context, movie.ID), nil
+}
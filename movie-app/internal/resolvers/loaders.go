@@ -0,0 +1,162 @@
+package resolvers
+
+import (
+	"context"
+	"sync"
+
+	"movie-app/internal/models"
+	"movie-app/internal/store"
+)
+
+// batchLoader collects the keys requested while a GraphQL response is being
+// built and fetches them with a single call once the first result is needed.
+//
+// Resolvers return the thunk from load; graphql-go resolves thunks only after
+// every sibling field has been visited, so all movies in a list enqueue their
+// IDs before the batch is fetched.
+type batchLoader[V any] struct {
+	fetch func(ctx context.Context, keys []string) (map[string]V, error)
+
+	mu      sync.Mutex
+	pending []string
+	queued  map[string]bool
+	results map[string]V
+	errs    map[string]error
+}
+
+func newBatchLoader[V any](fetch func(ctx context.Context, keys []string) (map[string]V, error)) *batchLoader[V] {
+	return &batchLoader[V]{
+		fetch:   fetch,
+		queued:  map[string]bool{},
+		results: map[string]V{},
+		errs:    map[string]error{},
+	}
+}
+
+func (l *batchLoader[V]) load(ctx context.Context, key string) func() (interface{}, error) {
+	l.mu.Lock()
+	if !l.queued[key] {
+		l.queued[key] = true
+		l.pending = append(l.pending, key)
+	}
+	l.mu.Unlock()
+
+	return func() (interface{}, error) {
+		l.mu.Lock()
+		defer l.mu.Unlock()
+
+		if len(l.pending) > 0 {
+			l.dispatch(ctx)
+		}
+		if err := l.errs[key]; err != nil {
+			return nil, err
+		}
+		return l.results[key], nil
+	}
+}
+
+// dispatch fetches every pending key. Callers must hold l.mu.
+func (l *batchLoader[V]) dispatch(ctx context.Context) {
+	keys := l.pending
+	l.pending = nil
+
+	results, err := l.fetch(ctx, keys)
+	for _, key := range keys {
+		if err != nil {
+			l.errs[key] = err
+			continue
+		}
+		l.results[key] = results[key]
+	}
+}
+
+// Loaders are the per-request batch loaders used by Movie, Actor, Director,
+// Review and User field resolvers.
+type Loaders struct {
+	cast                *batchLoader[[]models.CastMember]
+	filmography         *batchLoader[[]models.FilmographyEntry]
+	reviews             *batchLoader[[]models.Review]
+	reviewStats         *batchLoader[models.ReviewStats]
+	history             *batchLoader[[]models.MovieRevision]
+	directors           *batchLoader[[]models.Director]
+	directorFilmography *batchLoader[[]models.Movie]
+	directorStats       *batchLoader[models.DirectorStats]
+	genres              *batchLoader[[]models.Genre]
+	users               *batchLoader[*models.User]
+	userReviews         *batchLoader[[]models.Review]
+}
+
+func NewLoaders(s store.Store) *Loaders {
+	return &Loaders{
+		cast: newBatchLoader(func(ctx context.Context, movieIDs []string) (map[string][]models.CastMember, error) {
+			byKey, err := s.CastForMovies(ctx, movieIDs)
+			return fillEmpty(movieIDs, byKey, err)
+		}),
+		filmography: newBatchLoader(func(ctx context.Context, actorIDs []string) (map[string][]models.FilmographyEntry, error) {
+			byKey, err := s.FilmographyForActors(ctx, actorIDs)
+			return fillEmpty(actorIDs, byKey, err)
+		}),
+		reviews: newBatchLoader(func(ctx context.Context, movieIDs []string) (map[string][]models.Review, error) {
+			byKey, err := s.ReviewsForMovies(ctx, movieIDs)
+			return fillEmpty(movieIDs, byKey, err)
+		}),
+		reviewStats: newBatchLoader(s.ReviewStatsForMovies),
+		history: newBatchLoader(func(ctx context.Context, movieIDs []string) (map[string][]models.MovieRevision, error) {
+			byKey, err := s.RevisionsForMovies(ctx, movieIDs)
+			return fillEmpty(movieIDs, byKey, err)
+		}),
+		directors: newBatchLoader(func(ctx context.Context, movieIDs []string) (map[string][]models.Director, error) {
+			byKey, err := s.DirectorsForMovies(ctx, movieIDs)
+			return fillEmpty(movieIDs, byKey, err)
+		}),
+		directorFilmography: newBatchLoader(func(ctx context.Context, directorIDs []string) (map[string][]models.Movie, error) {
+			byKey, err := s.FilmographyForDirectors(ctx, directorIDs)
+			return fillEmpty(directorIDs, byKey, err)
+		}),
+		// Directors without movies are missing from the result and load as
+		// zero-valued stats.
+		directorStats: newBatchLoader(s.StatsForDirectors),
+		genres: newBatchLoader(func(ctx context.Context, movieIDs []string) (map[string][]models.Genre, error) {
+			byKey, err := s.GenresForMovies(ctx, movieIDs)
+			return fillEmpty(movieIDs, byKey, err)
+		}),
+		users: newBatchLoader(s.UsersByIDs),
+		userReviews: newBatchLoader(func(ctx context.Context, userIDs []string) (map[string][]models.Review, error) {
+			byKey, err := s.ReviewsForUsers(ctx, userIDs)
+			return fillEmpty(userIDs, byKey, err)
+		}),
+	}
+}
+
+// fillEmpty gives every requested key a non-nil slice so list fields
+// resolve to [] rather than null when nothing matched.
+func fillEmpty[T any](keys []string, byKey map[string][]T, err error) (map[string][]T, error) {
+	if err != nil {
+		return nil, err
+	}
+	for _, key := range keys {
+		if byKey[key] == nil {
+			byKey[key] = []T{}
+		}
+	}
+	return byKey, nil
+}
+
+type loadersKey struct{}
+
+// WithLoaders attaches a fresh set of loaders to ctx. It must be called once
+// per GraphQL request so cached results never outlive the request.
+func WithLoaders(ctx context.Context, s store.Store) context.Context {
+	return context.WithValue(ctx, loadersKey{}, NewLoaders(s))
+}
+
+// loadersFor returns the request's loaders, or a throwaway set when the
+// caller did not go through WithLoaders (batches then hold a single key).
+func (r *Resolver) loadersFor(ctx context.Context) *Loaders {
+	if ctx != nil {
+		if loaders, ok := ctx.Value(loadersKey{}).(*Loaders); ok {
+			return loaders
+		}
+	}
+	return NewLoaders(r.store)
+}
@@ -0,0 +1,120 @@
+package resolvers
+
+import (
+	"movie-app/internal/apperr"
+	"movie-app/internal/models"
+	"movie-app/internal/store"
+
+	"github.com/graphql-go/graphql"
+)
+
+// moviePatchField is one MoviePatch field. set with a nil value clears the
+// field, which only nullable fields allow.
+type moviePatchField struct {
+	name     string
+	nullable bool
+	get      func(m *models.Movie) interface{}
+	set      func(m *models.Movie, v interface{})
+}
+
+var moviePatchFields = []moviePatchField{
+	{"title", false,
+		func(m *models.Movie) interface{} { return m.Title },
+		func(m *models.Movie, v interface{}) { m.Title, _ = v.(string) }},
+	{"description", true,
+		func(m *models.Movie) interface{} { return m.Description },
+		func(m *models.Movie, v interface{}) { m.Description, _ = v.(string) }},
+	{"year", false,
+		func(m *models.Movie) interface{} { return m.Year },
+		func(m *models.Movie, v interface{}) { m.Year, _ = v.(int) }},
+	{"rating", false,
+		func(m *models.Movie) interface{} { return m.Rating },
+		func(m *models.Movie, v interface{}) { m.Rating, _ = v.(float64) }},
+	{"duration", false,
+		func(m *models.Movie) interface{} { return m.Duration },
+		func(m *models.Movie, v interface{}) { m.Duration, _ = v.(int) }},
+	{"genre", true,
+		func(m *models.Movie) interface{} { return m.Genre },
+		func(m *models.Movie, v interface{}) { m.Genre, _ = v.(string) }},
+	{"director", true,
+		func(m *models.Movie) interface{} { return m.Director },
+		func(m *models.Movie, v interface{}) { m.Director, _ = v.(string) }},
+	{"poster_url", true,
+		func(m *models.Movie) interface{} { return m.PosterURL },
+		func(m *models.Movie, v interface{}) { m.PosterURL, _ = v.(string) }},
+}
+
+// applyMoviePatch sets the fields present in patch and clears those in
+// nulls, returning the names of the fields whose value changed.
+func applyMoviePatch(movie *models.Movie, patch map[string]interface{}, nulls map[string]bool) ([]string, error) {
+	changed := []string{}
+	for _, field := range moviePatchFields {
+		value, set := patch[field.name]
+		if nulls[field.name] {
+			if !field.nullable {
+				return nil, apperr.Invalid("patch."+field.name, "%s cannot be null", field.name)
+			}
+			value, set = nil, true
+		}
+		if !set {
+			continue
+		}
+
+		before := field.get(movie)
+		field.set(movie, value)
+		if field.get(movie) != before {
+			changed = append(changed, field.name)
+		}
+	}
+	return changed, nil
+}
+
+func (r *Resolver) PatchMovie(p graphql.ResolveParams) (interface{}, error) {
+	id, ok := p.Args["id"].(string)
+	if !ok {
+		return nil, apperr.Invalid("id", "id is required")
+	}
+	patch, _ := p.Args["patch"].(map[string]interface{})
+	nulls := nullFields(p, "patch")
+	if err := validationError(validate("patch", patch, movieRules)); err != nil {
+		return nil, err
+	}
+	expected, err := expectedVersionArg(p)
+	if err != nil {
+		return nil, err
+	}
+
+	var result models.PatchMoviePayload
+	err = r.store.InTx(p.Context, func(tx store.Store) error {
+		movie, err := r.requireMovie(p, tx, id)
+		if err != nil {
+			return err
+		}
+		if expected != 0 && movie.Version != expected {
+			return store.ErrVersionConflict
+		}
+
+		before := *movie
+		result.ChangedFields, err = applyMoviePatch(movie, patch, nulls)
+		if err != nil {
+			return err
+		}
+		result.Movie = movie
+		if len(result.ChangedFields) == 0 {
+			return nil
+		}
+
+		result.Movie, err = tx.UpdateMovie(p.Context, *movie, expected)
+		if err != nil {
+			return err
+		}
+		return recordRevision(p, tx, id, &before, result.Movie)
+	})
+	if err == store.ErrVersionConflict {
+		return nil, r.movieConflict(p, id, expected)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
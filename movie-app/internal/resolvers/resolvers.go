@@ -1,478 +1,307 @@
 package resolvers
 
 import (
-	"database/sql"
 	"fmt"
-	"log"
-	"movie-app/internal/database"
+	"movie-app/internal/apperr"
 	"movie-app/internal/models"
+	"movie-app/internal/scalars"
+	"movie-app/internal/schema"
+	"movie-app/internal/store"
+	"strings"
 
-	"github.com/google/uuid"
 	"github.com/graphql-go/graphql"
 )
 
-var actorType = graphql.NewObject(graphql.ObjectConfig{
-	Name: "Actor",
-	Fields: graphql.Fields{
-		"id":          &graphql.Field{Type: graphql.ID},
-		"name":        &graphql.Field{Type: graphql.String},
-		"birth_date":  &graphql.Field{Type: graphql.String},
-		"nationality": &graphql.Field{Type: graphql.String},
-		"biography":   &graphql.Field{Type: graphql.String},
-		"profile_url": &graphql.Field{Type: graphql.String},
-	},
-})
-
-var reviewType = graphql.NewObject(graphql.ObjectConfig{
-	Name: "Review",
-	Fields: graphql.Fields{
-		"id":         &graphql.Field{Type: graphql.ID},
-		"movie_id":   &graphql.Field{Type: graphql.ID},
-		"user_name":  &graphql.Field{Type: graphql.String},
-		"rating":     &graphql.Field{Type: graphql.Int},
-		"comment":    &graphql.Field{Type: graphql.String},
-		"created_at": &graphql.Field{Type: graphql.String},
-	},
-})
-
-var movieType = graphql.NewObject(graphql.ObjectConfig{
-	Name: "Movie",
-	Fields: graphql.Fields{
-		"id":          &graphql.Field{Type: graphql.ID},
-		"title":       &graphql.Field{Type: graphql.String},
-		"description": &graphql.Field{Type: graphql.String},
-		"year":        &graphql.Field{Type: graphql.Int},
-		"rating":      &graphql.Field{Type: graphql.Float},
-		"duration":    &graphql.Field{Type: graphql.Int},
-		"genre":       &graphql.Field{Type: graphql.String},
-		"director":    &graphql.Field{Type: graphql.String},
-		"poster_url":  &graphql.Field{Type: graphql.String},
-		"created_at":  &graphql.Field{Type: graphql.String},
-		"updated_at":  &graphql.Field{Type: graphql.String},
-		"actors": &graphql.Field{
-			Type: graphql.NewList(actorType),
-			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
-				movie, ok := p.Source.(*models.Movie)
-				if !ok {
-					return []models.Actor{}, nil
-				}
-				return getActorsForMovie(movie.ID)
+// Resolver holds the dependencies shared by every resolver.
+type Resolver struct {
+	store store.Store
+}
+
+func CreateSchema(s store.Store) (graphql.Schema, error) {
+	r := &Resolver{store: s}
+
+	return schema.Build(schema.SDL, schema.Config{
+		Resolvers: withPublicErrors(r.authorize(schema.Resolvers{
+			"Query": {
+				"movie":                  r.GetMovie,
+				"movieAt":                r.GetMovieAt,
+				"movies":                 r.GetMovies,
+				"searchMovies":           r.SearchMovies,
+				"moviesConnection":       r.GetMoviesConnection,
+				"searchMoviesConnection": r.SearchMoviesConnection,
+				"reviewsConnection":      r.GetReviewsConnection,
+				"actor":                  r.GetActor,
+				"director":               r.GetDirector,
+				"directors":              r.GetDirectors,
+				"genres":                 r.GetGenres,
+				"reviews":                r.GetReviews,
+				"trash":                  r.GetTrash,
+				"me":                     r.GetMe,
 			},
-		},
-		"reviews": &graphql.Field{
-			Type: graphql.NewList(reviewType),
-			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
-				movie, ok := p.Source.(*models.Movie)
-				if !ok {
-					return []models.Review{}, nil
-				}
-				return getReviewsForMovie(movie.ID)
+			"Mutation": {
+				"createMovie":            r.CreateMovie,
+				"patchMovie":             r.PatchMovie,
+				"revertMovie":            r.RevertMovie,
+				"updateMovie":            r.UpdateMovie,
+				"deleteMovie":            r.DeleteMovie,
+				"restoreMovie":           r.RestoreMovie,
+				"createActor":            r.CreateActor,
+				"deleteActor":            r.DeleteActor,
+				"restoreActor":           r.RestoreActor,
+				"addCastMember":          r.AddCastMember,
+				"reorderCast":            r.ReorderCast,
+				"removeCastMember":       r.RemoveCastMember,
+				"createReview":           r.CreateReview,
+				"upsertReview":           r.UpsertReview,
+				"deleteReview":           r.DeleteReview,
+				"restoreReview":          r.RestoreReview,
+				"createMovieWithDetails": r.CreateMovieWithDetails,
+				"purgeTrash":             r.PurgeTrash,
+				"updateProfile":          r.UpdateProfile,
+			},
+			"Movie": {
+				"actors":         r.resolveMovieActors,
+				"reviews":        r.resolveMovieReviews,
+				"history":        r.resolveMovieHistory,
+				"review_stats":   r.resolveMovieReviewStats,
+				"weighted_score": r.resolveMovieWeightedScore,
+				"cast":           r.resolveMovieCast,
+				"directors":      r.resolveMovieDirectors,
+				"genres":         r.resolveMovieGenres,
+				"highlights":     resolveMovieHighlights,
+			},
+			"Actor": {
+				"filmography": r.resolveActorFilmography,
+			},
+			"Director": {
+				"filmography": r.resolveDirectorFilmography,
+				"stats":       r.resolveDirectorStats,
+			},
+			"Review": {
+				"user": r.resolveReviewUser,
 			},
+			"User": {
+				"reviews": r.resolveUserReviews,
+			},
+		})),
+		Models: schema.Models{
+			"Movie":             models.Movie{},
+			"Actor":             models.Actor{},
+			"CastMember":        models.CastMember{},
+			"FilmographyEntry":  models.FilmographyEntry{},
+			"Highlight":         models.Highlight{},
+			"Genre":             models.Genre{},
+			"Director":          models.Director{},
+			"ReviewStats":       models.ReviewStats{},
+			"PatchMoviePayload": models.PatchMoviePayload{},
+			"MovieRevision":     models.MovieRevision{},
+			"MovieSnapshot":     models.Movie{},
+			"DirectorStats":     models.DirectorStats{},
+			"Review":            models.Review{},
+			"User":              models.User{},
+			"PaginationInfo":    models.Pagination{},
+			"MoviesResult":      models.MoviesResult{},
+			"DirectorsResult":   models.DirectorsResult{},
+			"TrashItem":         models.TrashItem{},
+			"TrashResult":       models.TrashResult{},
+			"PurgeResult":       store.PurgeCounts{},
+			"PageInfo":          models.PageInfo{},
+			"MovieEdge":         models.Edge[models.Movie]{},
+			"MovieConnection":   models.Connection[models.Movie]{},
+			"ReviewEdge":        models.Edge[models.Review]{},
+			"ReviewConnection":  models.Connection[models.Review]{},
 		},
-	},
-})
-
-var paginationInfoType = graphql.NewObject(graphql.ObjectConfig{
-	Name: "PaginationInfo",
-	Fields: graphql.Fields{
-		"page":        &graphql.Field{Type: graphql.Int},
-		"limit":       &graphql.Field{Type: graphql.Int},
-		"total":       &graphql.Field{Type: graphql.Int},
-		"total_pages": &graphql.Field{Type: graphql.Int},
-	},
-})
-
-var moviesResultType = graphql.NewObject(graphql.ObjectConfig{
-	Name: "MoviesResult",
-	Fields: graphql.Fields{
-		"movies":     &graphql.Field{Type: graphql.NewList(movieType)},
-		"pagination": &graphql.Field{Type: paginationInfoType},
-	},
-})
-
-var movieInputType = graphql.NewInputObject(graphql.InputObjectConfig{
-	Name: "MovieInput",
-	Fields: graphql.InputObjectConfigFieldMap{
-		"title":       &graphql.InputObjectFieldConfig{Type: graphql.NewNonNull(graphql.String)},
-		"description": &graphql.InputObjectFieldConfig{Type: graphql.String},
-		"year":        &graphql.InputObjectFieldConfig{Type: graphql.NewNonNull(graphql.Int)},
-		"rating":      &graphql.InputObjectFieldConfig{Type: graphql.NewNonNull(graphql.Float)},
-		"duration":    &graphql.InputObjectFieldConfig{Type: graphql.NewNonNull(graphql.Int)},
-		"genre":       &graphql.InputObjectFieldConfig{Type: graphql.String},
-		"director":    &graphql.InputObjectFieldConfig{Type: graphql.String},
-		"poster_url":  &graphql.InputObjectFieldConfig{Type: graphql.String},
-	},
-})
-
-var movieFilterType = graphql.NewInputObject(graphql.InputObjectConfig{
-	Name: "MovieFilter",
-	Fields: graphql.InputObjectConfigFieldMap{
-		"genre":      &graphql.InputObjectFieldConfig{Type: graphql.String},
-		"min_year":   &graphql.InputObjectFieldConfig{Type: graphql.Int},
-		"max_year":   &graphql.InputObjectFieldConfig{Type: graphql.Int},
-		"min_rating": &graphql.InputObjectFieldConfig{Type: graphql.Float},
-		"search":     &graphql.InputObjectFieldConfig{Type: graphql.String},
-	},
-})
-
-var reviewInputType = graphql.NewInputObject(graphql.InputObjectConfig{
-	Name: "ReviewInput",
-	Fields: graphql.InputObjectConfigFieldMap{
-		"movie_id":  &graphql.InputObjectFieldConfig{Type: graphql.NewNonNull(graphql.ID)},
-		"user_name": &graphql.InputObjectFieldConfig{Type: graphql.NewNonNull(graphql.String)},
-		"rating":    &graphql.InputObjectFieldConfig{Type: graphql.NewNonNull(graphql.Int)},
-		"comment":   &graphql.InputObjectFieldConfig{Type: graphql.String},
-	},
-})
-
-var actorInputType = graphql.NewInputObject(graphql.InputObjectConfig{
-	Name: "ActorInput",
-	Fields: graphql.InputObjectConfigFieldMap{
-		"name":        &graphql.InputObjectFieldConfig{Type: graphql.NewNonNull(graphql.String)},
-		"birth_date":  &graphql.InputObjectFieldConfig{Type: graphql.String},
-		"nationality": &graphql.InputObjectFieldConfig{Type: graphql.String},
-		"biography":   &graphql.InputObjectFieldConfig{Type: graphql.String},
-		"profile_url": &graphql.InputObjectFieldConfig{Type: graphql.String},
-	},
-})
-
-var reviewCreateInputType = graphql.NewInputObject(graphql.InputObjectConfig{
-	Name: "ReviewCreateInput",
-	Fields: graphql.InputObjectConfigFieldMap{
-		"user_name": &graphql.InputObjectFieldConfig{Type: graphql.NewNonNull(graphql.String)},
-		"rating":    &graphql.InputObjectFieldConfig{Type: graphql.NewNonNull(graphql.Int)},
-		"comment":   &graphql.InputObjectFieldConfig{Type: graphql.String},
-	},
-})
-
-var movieWithDetailsInputType = graphql.NewInputObject(graphql.InputObjectConfig{
-	Name: "MovieWithDetailsInput",
-	Fields: graphql.InputObjectConfigFieldMap{
-		"movie":   &graphql.InputObjectFieldConfig{Type: graphql.NewNonNull(movieInputType)},
-		"actors":  &graphql.InputObjectFieldConfig{Type: graphql.NewList(graphql.NewNonNull(actorInputType))},
-		"reviews": &graphql.InputObjectFieldConfig{Type: graphql.NewList(graphql.NewNonNull(reviewCreateInputType))},
-	},
-})
-
-func GetMovie(p graphql.ResolveParams) (interface{}, error) {
+		Scalars: scalars.All,
+	})
+}
+
+func (r *Resolver) GetMovie(p graphql.ResolveParams) (interface{}, error) {
 	id, ok := p.Args["id"].(string)
 	if !ok {
-		return nil, fmt.Errorf("id is required")
+		return nil, apperr.Invalid("id", "id is required")
 	}
 
-	movie, err := getMovieByID(id)
-	if err != nil {
-		return nil, err
-	}
+	return r.getMovieByID(p, id)
+}
 
-	// Load actors for the movie
-	actors, err := getActorsForMovie(id)
+func (r *Resolver) GetMovies(p graphql.ResolveParams) (interface{}, error) {
+	page, limit, err := pageArgs(p)
 	if err != nil {
-		log.Printf("Failed to load actors: %v", err)
+		return nil, err
 	}
-	movie.Actors = actors
 
-	// Load reviews for the movie
-	reviews, err := getReviewsForMovie(id)
+	movies, total, err := r.store.ListMovies(p.Context, movieFilterArg(p), movieSortArg(p), limit, (page-1)*limit)
 	if err != nil {
-		log.Printf("Failed to load reviews: %v", err)
+		return nil, err
 	}
-	movie.Reviews = reviews
 
-	return movie, nil
+	return newMoviesResult(movies, page, limit, total), nil
 }
 
-func GetMovies(p graphql.ResolveParams) (interface{}, error) {
-	page := 1
-	limit := 10
-
-	if p.Args["page"] != nil {
-		page = p.Args["page"].(int)
-	}
-	if p.Args["limit"] != nil {
-		limit = p.Args["limit"].(int)
+func (r *Resolver) SearchMovies(p graphql.ResolveParams) (interface{}, error) {
+	query, ok := p.Args["query"].(string)
+	if !ok {
+		return nil, apperr.Invalid("query", "query is required")
 	}
 
-	offset := (page - 1) * limit
-
-	// Build query with filters
-	query := "SELECT * FROM movies WHERE 1=1"
-	countQuery := "SELECT COUNT(*) FROM movies WHERE 1=1"
-	args := []interface{}{}
-	countArgs := []interface{}{}
-
-	if p.Args["filter"] != nil {
-		filter := p.Args["filter"].(map[string]interface{})
-
-		if genre, ok := filter["genre"].(string); ok && genre != "" {
-			query += " AND genre LIKE ?"
-			countQuery += " AND genre LIKE ?"
-			args = append(args, "%"+genre+"%")
-			countArgs = append(countArgs, "%"+genre+"%")
-		}
+	page, limit, err := pageArgs(p)
+	if err != nil {
+		return nil, err
+	}
 
-		if minYear, ok := filter["min_year"].(int); ok && minYear > 0 {
-			query += " AND year >= ?"
-			countQuery += " AND year >= ?"
-			args = append(args, minYear)
-			countArgs = append(countArgs, minYear)
-		}
+	movies, total, err := r.store.SearchMovies(p.Context, query, movieSortArg(p), limit, (page-1)*limit)
+	if err != nil {
+		return nil, err
+	}
 
-		if maxYear, ok := filter["max_year"].(int); ok && maxYear > 0 {
-			query += " AND year <= ?"
-			countQuery += " AND year <= ?"
-			args = append(args, maxYear)
-			countArgs = append(countArgs, maxYear)
-		}
+	return newMoviesResult(movies, page, limit, total), nil
+}
 
-		if minRating, ok := filter["min_rating"].(float64); ok && minRating > 0 {
-			query += " AND rating >= ?"
-			countQuery += " AND rating >= ?"
-			args = append(args, minRating)
-			countArgs = append(countArgs, minRating)
-		}
+// movieFilterArg reads the optional MovieFilter argument "filter".
+func movieFilterArg(p graphql.ResolveParams) models.MovieFilter {
+	var filter models.MovieFilter
+	if input, ok := p.Args["filter"].(map[string]interface{}); ok {
+		filter.Genre, _ = input["genre"].(string)
+		filter.MinYear, _ = input["min_year"].(int)
+		filter.MaxYear, _ = input["max_year"].(int)
+		filter.MinRating, _ = input["min_rating"].(float64)
+		filter.Search, _ = input["search"].(string)
+		filter.GenresAny = stringsArg(input, "genres_any")
+		filter.GenresAll = stringsArg(input, "genres_all")
+		filter.ExcludeGenres = stringsArg(input, "exclude_genres")
+		filter.MinReviewCount, _ = input["min_review_count"].(int)
+		filter.MinAverageReview, _ = input["min_average_review"].(float64)
+		filter.MinWeightedScore, _ = input["min_weighted_score"].(float64)
+	}
+	return filter
+}
 
-		if search, ok := filter["search"].(string); ok && search != "" {
-			query += " AND (title LIKE ? OR description LIKE ? OR director LIKE ?)"
-			countQuery += " AND (title LIKE ? OR description LIKE ? OR director LIKE ?)"
-			searchTerm := "%" + search + "%"
-			args = append(args, searchTerm, searchTerm, searchTerm)
-			countArgs = append(countArgs, searchTerm, searchTerm, searchTerm)
+// movieSortArg reads the optional [MovieSort!] argument "sort".
+func movieSortArg(p graphql.ResolveParams) []models.MovieSort {
+	list, _ := p.Args["sort"].([]interface{})
+	sorts := make([]models.MovieSort, 0, len(list))
+	for _, item := range list {
+		input, ok := item.(map[string]interface{})
+		if !ok {
+			continue
 		}
+		field, _ := input["field"].(string)
+		direction, _ := input["direction"].(string)
+		sorts = append(sorts, models.MovieSort{Field: strings.ToLower(field), Desc: direction == "DESC"})
 	}
+	return sorts
+}
 
-	// Get total count
-	var total int
-	err := database.DB.QueryRow(countQuery, countArgs...).Scan(&total)
-	if err != nil {
-		return nil, fmt.Errorf("failed to count movies: %v", err)
+func (r *Resolver) resolveMovieActors(p graphql.ResolveParams) (interface{}, error) {
+	movie, ok := movieFromSource(p.Source)
+	if !ok {
+		return []models.Actor{}, nil
 	}
-
-	// Add pagination
-	query += " ORDER BY created_at DESC LIMIT ? OFFSET ?"
-	args = append(args, limit, offset)
-
-	rows, err := database.DB.Query(query, args...)
-	if err != nil {
-		return nil, fmt.Errorf("failed to query movies: %v", err)
-	}
-	defer rows.Close()
-
-	var movies []models.Movie
-	for rows.Next() {
-		var movie models.Movie
-		err := rows.Scan(
-			&movie.ID, &movie.Title, &movie.Description, &movie.Year, &movie.Rating,
-			&movie.Duration, &movie.Genre, &movie.Director, &movie.PosterURL,
-			&movie.CreatedAt, &movie.UpdatedAt,
-		)
+	cast := r.loadersFor(p.Context).cast.load(p.Context, movie.ID)
+	return func() (interface{}, error) {
+		members, err := cast()
 		if err != nil {
-			return nil, fmt.Errorf("failed to scan movie: %v", err)
+			return nil, err
 		}
-		movies = append(movies, movie)
-	}
-
-	totalPages := (total + limit - 1) / limit
+		actors := []models.Actor{}
+		for _, m := range members.([]models.CastMember) {
+			actors = append(actors, m.Actor)
+		}
+		return actors, nil
+	}, nil
+}
 
-	result := map[string]interface{}{
-		"movies": movies,
-		"pagination": map[string]interface{}{
-			"page":        page,
-			"limit":       limit,
-			"total":       total,
-			"total_pages": totalPages,
-		},
+func (r *Resolver) resolveMovieCast(p graphql.ResolveParams) (interface{}, error) {
+	movie, ok := movieFromSource(p.Source)
+	if !ok {
+		return []models.CastMember{}, nil
 	}
-
-	return result, nil
+	return r.loadersFor(p.Context).cast.load(p.Context, movie.ID), nil
 }
 
-func SearchMovies(p graphql.ResolveParams) (interface{}, error) {
-	query, ok := p.Args["query"].(string)
+func (r *Resolver) resolveMovieReviews(p graphql.ResolveParams) (interface{}, error) {
+	movie, ok := movieFromSource(p.Source)
 	if !ok {
-		return nil, fmt.Errorf("query is required")
+		return []models.Review{}, nil
 	}
+	return r.loadersFor(p.Context).reviews.load(p.Context, movie.ID), nil
+}
 
-	page := 1
-	limit := 10
-	if p.Args["page"] != nil {
-		page = p.Args["page"].(int)
-	}
-	if p.Args["limit"] != nil {
-		limit = p.Args["limit"].(int)
+func (r *Resolver) resolveMovieReviewStats(p graphql.ResolveParams) (interface{}, error) {
+	movie, ok := movieFromSource(p.Source)
+	if !ok {
+		return models.ReviewStats{Histogram: make([]int, 5)}, nil
 	}
+	return r.loadersFor(p.Context).reviewStats.load(p.Context, movie.ID), nil
+}
 
-	offset := (page - 1) * limit
-
-	searchTerm := "%" + query + "%"
-	sqlQuery := `
-		SELECT * FROM movies 
-		WHERE title LIKE ? OR description LIKE ? OR director LIKE ? OR genre LIKE ?
-		ORDER BY created_at DESC LIMIT ? OFFSET ?
-	`
-
-	rows, err := database.DB.Query(sqlQuery, searchTerm, searchTerm, searchTerm, searchTerm, limit, offset)
-	if err != nil {
-		return nil, fmt.Errorf("failed to search movies: %v", err)
-	}
-	defer rows.Close()
-
-	var movies []models.Movie
-	for rows.Next() {
-		var movie models.Movie
-		err := rows.Scan(
-			&movie.ID, &movie.Title, &movie.Description, &movie.Year, &movie.Rating,
-			&movie.Duration, &movie.Genre, &movie.Director, &movie.PosterURL,
-			&movie.CreatedAt, &movie.UpdatedAt,
-		)
+func (r *Resolver) resolveMovieWeightedScore(p graphql.ResolveParams) (interface{}, error) {
+	movie, ok := movieFromSource(p.Source)
+	if !ok {
+		return nil, nil
+	}
+	load := r.loadersFor(p.Context).reviewStats.load(p.Context, movie.ID)
+	return func() (interface{}, error) {
+		stats, err := load()
 		if err != nil {
-			return nil, fmt.Errorf("failed to scan movie: %v", err)
+			return nil, err
 		}
-		movies = append(movies, movie)
-	}
+		return stats.(models.ReviewStats).WeightedScore, nil
+	}, nil
+}
 
-	// Get total count
-	countQuery := `
-		SELECT COUNT(*) FROM movies 
-		WHERE title LIKE ? OR description LIKE ? OR director LIKE ? OR genre LIKE ?
-	`
-	var total int
-	err = database.DB.QueryRow(countQuery, searchTerm, searchTerm, searchTerm, searchTerm).Scan(&total)
-	if err != nil {
-		return nil, fmt.Errorf("failed to count movies: %v", err)
+func (r *Resolver) resolveActorFilmography(p graphql.ResolveParams) (interface{}, error) {
+	actor, ok := actorFromSource(p.Source)
+	if !ok {
+		return []models.FilmographyEntry{}, nil
 	}
+	return r.loadersFor(p.Context).filmography.load(p.Context, actor.ID), nil
+}
 
-	totalPages := (total + limit - 1) / limit
-
-	result := map[string]interface{}{
-		"movies": movies,
-		"pagination": map[string]interface{}{
-			"page":        page,
-			"limit":       limit,
-			"total":       total,
-			"total_pages": totalPages,
-		},
+// resolveMovieHighlights returns null rather than [] for movies that did not
+// come from searchMovies.
+func resolveMovieHighlights(p graphql.ResolveParams) (interface{}, error) {
+	movie, ok := movieFromSource(p.Source)
+	if !ok || movie.Highlights == nil {
+		return nil, nil
 	}
-
-	return result, nil
+	return movie.Highlights, nil
 }
 
-func CreateSchema() (graphql.Schema, error) {
-	rootQuery := graphql.NewObject(graphql.ObjectConfig{
-		Name: "Query",
-		Fields: graphql.Fields{
-			"movie": &graphql.Field{
-				Type: movieType,
-				Args: graphql.FieldConfigArgument{
-					"id": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.ID)},
-				},
-				Resolve: GetMovie,
-			},
-			"movies": &graphql.Field{
-				Type: moviesResultType,
-				Args: graphql.FieldConfigArgument{
-					"page":   &graphql.ArgumentConfig{Type: graphql.Int},
-					"limit":  &graphql.ArgumentConfig{Type: graphql.Int},
-					"filter": &graphql.ArgumentConfig{Type: movieFilterType},
-				},
-				Resolve: GetMovies,
-			},
-			"searchMovies": &graphql.Field{
-				Type: moviesResultType,
-				Args: graphql.FieldConfigArgument{
-					"query": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)},
-					"page":  &graphql.ArgumentConfig{Type: graphql.Int},
-					"limit": &graphql.ArgumentConfig{Type: graphql.Int},
-				},
-				Resolve: SearchMovies,
-			},
-		},
-	})
+func (r *Resolver) CreateMovie(p graphql.ResolveParams) (interface{}, error) {
+	input, ok := p.Args["input"].(map[string]interface{})
+	if !ok {
+		return nil, apperr.Invalid("input", "input is required")
+	}
+	if err := validationError(validate("input", input, movieRules)); err != nil {
+		return nil, err
+	}
 
-	rootMutation := graphql.NewObject(graphql.ObjectConfig{
-		Name: "Mutation",
-		Fields: graphql.Fields{
-			"createMovie": &graphql.Field{
-				Type: movieType,
-				Args: graphql.FieldConfigArgument{
-					"input": &graphql.ArgumentConfig{Type: graphql.NewNonNull(movieInputType)},
-				},
-				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
-					input, ok := p.Args["input"].(map[string]interface{})
-					if !ok {
-						return nil, fmt.Errorf("input is required")
-					}
-
-					id := uuid.New().String()
-
-					if director, ok := input["director"].(string); ok && director != "" {
-						if _, err := database.EnsureDirector(director); err != nil {
-							return nil, fmt.Errorf("failed to ensure director: %v", err)
-						}
-					}
-
-					_, err := database.DB.Exec(`
-                        INSERT INTO movies (id, title, description, year, rating, duration, genre, director, poster_url, created_at, updated_at)
-                        VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP)`,
-						id,
-						input["title"].(string),
-						input["description"],
-						input["year"].(int),
-						input["rating"].(float64),
-						input["duration"].(int),
-						input["genre"],
-						input["director"],
-						input["poster_url"],
-					)
-					if err != nil {
-						return nil, fmt.Errorf("failed to create movie: %v", err)
-					}
-
-					return getMovieByID(id)
-				},
-			},
-			"updateMovie": &graphql.Field{
-				Type: movieType,
-				Args: graphql.FieldConfigArgument{
-					"id":    &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.ID)},
-					"input": &graphql.ArgumentConfig{Type: graphql.NewNonNull(movieInputType)},
-				},
-				Resolve: UpdateMovie,
-			},
-			"deleteMovie": &graphql.Field{
-				Type: graphql.Boolean,
-				Args: graphql.FieldConfigArgument{
-					"id": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.ID)},
-				},
-				Resolve: DeleteMovie,
-			},
-			"createReview": &graphql.Field{
-				Type: reviewType,
-				Args: graphql.FieldConfigArgument{
-					"input": &graphql.ArgumentConfig{Type: graphql.NewNonNull(reviewInputType)},
-				},
-				Resolve: CreateReview,
-			},
-			"createMovieWithDetails": &graphql.Field{
-				Type: movieType,
-				Args: graphql.FieldConfigArgument{
-					"input": &graphql.ArgumentConfig{Type: graphql.NewNonNull(movieWithDetailsInputType)},
-				},
-				Resolve: CreateMovieWithDetails,
-			},
-		},
-	})
+	movie := movieFromInput(input)
 
-	return graphql.NewSchema(graphql.SchemaConfig{
-		Query:    rootQuery,
-		Mutation: rootMutation,
+	// The movie row, its director credits and its first revision are
+	// written together.
+	var created *models.Movie
+	err := r.store.InTx(p.Context, func(tx store.Store) error {
+		var err error
+		created, err = tx.CreateMovie(p.Context, movie)
+		if err != nil {
+			return err
+		}
+		return recordRevision(p, tx, created.ID, nil, created)
 	})
+	if err != nil {
+		return nil, err
+	}
+	return created, nil
 }
 
-func CreateMovieWithDetails(p graphql.ResolveParams) (interface{}, error) {
+// CreateMovieWithDetails inserts a movie with its cast and reviews in one
+// transaction, so a failure on any row leaves nothing behind.
+func (r *Resolver) CreateMovieWithDetails(p graphql.ResolveParams) (interface{}, error) {
 	input, ok := p.Args["input"].(map[string]interface{})
 	if !ok {
-		return nil, fmt.Errorf("input is required")
+		return nil, apperr.Invalid("input", "input is required")
 	}
 
 	movieInput := input["movie"].(map[string]interface{})
@@ -489,236 +318,376 @@ func CreateMovieWithDetails(p graphql.ResolveParams) (interface{}, error) {
 		}
 	}
 
-	movieID := uuid.New().String()
-
-	if director, ok := movieInput["director"].(string); ok && director != "" {
-		if _, err := database.EnsureDirector(director); err != nil {
-			return nil, fmt.Errorf("failed to ensure director: %v", err)
-		}
+	violations := validate("input.movie", movieInput, movieRules)
+	violations = append(violations, validateList("input.actors", actorsInput, actorRules)...)
+	violations = append(violations, validateList("input.reviews", reviewsInput, reviewRules)...)
+	if err := validationError(violations); err != nil {
+		return nil, err
 	}
 
-	// Insert movie
-	_, err := database.DB.Exec(`
-        INSERT INTO movies (id, title, description, year, rating, duration, genre, director, poster_url) 
-        VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
-		movieID, movieInput["title"], movieInput["description"], movieInput["year"], movieInput["rating"],
-		movieInput["duration"], movieInput["genre"], movieInput["director"], movieInput["poster_url"],
-	)
-	if err != nil {
-		return nil, fmt.Errorf("failed to insert movie: %v", err)
-	}
-
-	// Insert actors
-	for _, actor := range actorsInput {
-		actorMap := actor.(map[string]interface{})
-		actorID := uuid.New().String()
-		_, err := database.DB.Exec(`
-            INSERT INTO actors (id, name, birth_date, nationality, biography, profile_url) 
-            VALUES (?, ?, ?, ?, ?, ?)`,
-			actorID, actorMap["name"], actorMap["birth_date"], actorMap["nationality"], actorMap["biography"], actorMap["profile_url"],
-		)
+	movie := movieFromInput(movieInput)
+
+	var created *models.Movie
+	err := r.store.InTx(p.Context, func(tx store.Store) error {
+		// Insert movie and credit its directors
+		var err error
+		created, err = tx.CreateMovie(p.Context, movie)
 		if err != nil {
-			return nil, fmt.Errorf("failed to insert actor: %v", err)
+			return err
+		}
+		if err := recordRevision(p, tx, created.ID, nil, created); err != nil {
+			return err
 		}
 
-		// Link actor to movie
-		_, err = database.DB.Exec(`
-            INSERT INTO movie_actors (movie_id, actor_id) 
-            VALUES (?, ?)`,
-			movieID, actorID,
-		)
-		if err != nil {
-			return nil, fmt.Errorf("failed to link actor to movie: %v", err)
+		// Insert or reuse actors and link them to the movie
+		for i, actor := range actorsInput {
+			actorMap := actor.(map[string]interface{})
+			field := fmt.Sprintf("input.actors[%d]", i)
+			a, err := findOrCreateActor(p, tx, actorMap, field)
+			if err != nil {
+				return err
+			}
+
+			err = tx.AddCastMember(p.Context, models.MovieActor{
+				MovieID:       created.ID,
+				ActorID:       a.ID,
+				CharacterName: stringArg(actorMap, "character_name"),
+			})
+			if err == store.ErrDuplicate {
+				return apperr.Invalid(field, "actor %s is listed more than once", a.ID)
+			}
+			if err != nil {
+				return err
+			}
 		}
+
+		// Insert reviews, each by the placeholder user named user_name
+		for i, review := range reviewsInput {
+			reviewMap := review.(map[string]interface{})
+			user, err := tx.EnsurePlaceholderUser(p.Context, reviewMap["user_name"].(string))
+			if err != nil {
+				return err
+			}
+			_, err = tx.CreateReview(p.Context, models.Review{
+				MovieID: created.ID,
+				UserID:  user.ID,
+				Rating:  reviewMap["rating"].(int),
+				Comment: stringArg(reviewMap, "comment"),
+			})
+			if err == store.ErrDuplicate {
+				return apperr.Invalid(fmt.Sprintf("input.reviews[%d]", i), "%s already has a review of this movie", user.Name)
+			}
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
 
-	// Insert reviews
-	for _, review := range reviewsInput {
-		reviewMap := review.(map[string]interface{})
-		reviewID := uuid.New().String()
-		_, err := database.DB.Exec(`
-            INSERT INTO reviews (id, movie_id, user_name, rating, comment) 
-            VALUES (?, ?, ?, ?, ?)`,
-			reviewID, movieID, reviewMap["user_name"], reviewMap["rating"], reviewMap["comment"],
-		)
-		if err != nil {
-			return nil, fmt.Errorf("failed to insert review: %v", err)
+	return created, nil
+}
+
+// findOrCreateActor resolves an ActorInput to an actor row: by id if given,
+// otherwise by name, creating a new actor only when no name matches. field
+// is the input's path, for errors.
+func findOrCreateActor(p graphql.ResolveParams, s store.Store, input map[string]interface{}, field string) (*models.Actor, error) {
+	if id := stringArg(input, "id"); id != "" {
+		actor, err := s.GetActor(p.Context, id)
+		if err == store.ErrNotFound {
+			return nil, apperr.Invalid(field+".id", "actor %s not found", id)
 		}
+		return actor, err
+	}
+
+	name := stringArg(input, "name")
+	if name == "" {
+		return nil, apperr.Invalid(field+".name", "actor name or id is required")
 	}
 
-	return getMovieByID(movieID)
+	actor, err := s.FindActorByName(p.Context, name)
+	if err != store.ErrNotFound {
+		return actor, err
+	}
+	return s.CreateActor(p.Context, actorFromInput(input))
 }
 
-func UpdateMovie(p graphql.ResolveParams) (interface{}, error) {
+func (r *Resolver) UpdateMovie(p graphql.ResolveParams) (interface{}, error) {
 	id, ok := p.Args["id"].(string)
 	if !ok {
-		return nil, fmt.Errorf("id is required")
+		return nil, apperr.Invalid("id", "id is required")
 	}
 
 	input, ok := p.Args["input"].(map[string]interface{})
 	if !ok {
-		return nil, fmt.Errorf("input is required")
+		return nil, apperr.Invalid("input", "input is required")
 	}
-
-	if director, ok := input["director"].(string); ok && director != "" {
-		if _, err := database.EnsureDirector(director); err != nil {
-			return nil, fmt.Errorf("failed to ensure director: %v", err)
-		}
+	if err := validationError(validate("input", input, movieRules)); err != nil {
+		return nil, err
 	}
 
-	_, err := database.DB.Exec(`
-		UPDATE movies 
-		SET title = ?, description = ?, year = ?, rating = ?, duration = ?, 
-		    genre = ?, director = ?, poster_url = ?, updated_at = CURRENT_TIMESTAMP
-		WHERE id = ?`,
-		input["title"].(string),
-		input["description"],
-		input["year"].(int),
-		input["rating"].(float64),
-		input["duration"].(int),
-		input["genre"],
-		input["director"],
-		input["poster_url"],
-		id,
-	)
+	expected, err := expectedVersionArg(p)
 	if err != nil {
-		return nil, fmt.Errorf("failed to update movie: %v", err)
+		return nil, err
 	}
 
-	return getMovieByID(id)
+	movie := movieFromInput(input)
+	movie.ID = id
+
+	var updated *models.Movie
+	err = r.store.InTx(p.Context, func(tx store.Store) error {
+		before, err := r.requireMovie(p, tx, id)
+		if err != nil {
+			return err
+		}
+		updated, err = tx.UpdateMovie(p.Context, movie, expected)
+		if err != nil {
+			return err
+		}
+		return recordRevision(p, tx, id, before, updated)
+	})
+	if err == store.ErrVersionConflict {
+		return nil, r.movieConflict(p, id, expected)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return updated, nil
 }
 
-func DeleteMovie(p graphql.ResolveParams) (interface{}, error) {
+func (r *Resolver) DeleteMovie(p graphql.ResolveParams) (interface{}, error) {
 	id, ok := p.Args["id"].(string)
 	if !ok {
-		return nil, fmt.Errorf("id is required")
+		return nil, apperr.Invalid("id", "id is required")
 	}
 
-	// Delete related records first
-	_, err := database.DB.Exec("DELETE FROM movie_actors WHERE movie_id = ?", id)
+	expected, err := expectedVersionArg(p)
 	if err != nil {
-		return false, fmt.Errorf("failed to delete movie actors: %v", err)
+		return nil, err
 	}
 
-	_, err = database.DB.Exec("DELETE FROM reviews WHERE movie_id = ?", id)
+	var deleted bool
+	err = r.store.InTx(p.Context, func(tx store.Store) error {
+		before, err := tx.GetMovie(p.Context, id)
+		if err == store.ErrNotFound {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		deleted, err = tx.DeleteMovie(p.Context, id, expected)
+		if err != nil || !deleted {
+			return err
+		}
+		return recordRevision(p, tx, id, before, nil)
+	})
+	if err == store.ErrVersionConflict {
+		return nil, r.movieConflict(p, id, expected)
+	}
 	if err != nil {
-		return false, fmt.Errorf("failed to delete reviews: %v", err)
+		return nil, err
 	}
+	return deleted, nil
+}
 
-	// Delete the movie
-	result, err := database.DB.Exec("DELETE FROM movies WHERE id = ?", id)
+func (r *Resolver) CreateReview(p graphql.ResolveParams) (interface{}, error) {
+	review, err := r.reviewFromInput(p)
 	if err != nil {
-		return false, fmt.Errorf("failed to delete movie: %v", err)
+		return nil, err
 	}
 
-	rowsAffected, _ := result.RowsAffected()
-	return rowsAffected > 0, nil
+	created, err := r.store.CreateReview(p.Context, review)
+	if err == store.ErrDuplicate {
+		return nil, apperr.Conflictf(map[string]interface{}{"movie_id": review.MovieID},
+			"you have already reviewed movie %s; use upsertReview to change your review", review.MovieID)
+	}
+	return created, err
+}
+
+func (r *Resolver) UpsertReview(p graphql.ResolveParams) (interface{}, error) {
+	review, err := r.reviewFromInput(p)
+	if err != nil {
+		return nil, err
+	}
+	return r.store.UpsertReview(p.Context, review)
 }
 
-func CreateReview(p graphql.ResolveParams) (interface{}, error) {
+// reviewFromInput validates a ReviewInput and returns it as a review by the
+// caller.
+func (r *Resolver) reviewFromInput(p graphql.ResolveParams) (models.Review, error) {
 	input, ok := p.Args["input"].(map[string]interface{})
 	if !ok {
-		return nil, fmt.Errorf("input is required")
+		return models.Review{}, apperr.Invalid("input", "input is required")
 	}
 
-	// Validate rating
-	rating := input["rating"].(int)
-	if rating < 1 || rating > 5 {
-		return nil, fmt.Errorf("rating must be between 1 and 5")
+	if err := validationError(validate("input", input, reviewRules)); err != nil {
+		return models.Review{}, err
 	}
 
-	id := uuid.New().String()
+	movieID := input["movie_id"].(string)
+	if _, err := r.getMovieByID(p, movieID); err != nil {
+		return models.Review{}, err
+	}
 
-	_, err := database.DB.Exec(`
-		INSERT INTO reviews (id, movie_id, user_name, rating, comment)
-		VALUES (?, ?, ?, ?, ?)`,
-		id,
-		input["movie_id"].(string),
-		input["user_name"].(string),
-		rating,
-		input["comment"],
-	)
+	user, err := r.currentUser(p)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create review: %v", err)
+		return models.Review{}, err
 	}
 
-	return getReviewByID(id)
+	return models.Review{
+		MovieID: movieID,
+		UserID:  user.ID,
+		Rating:  input["rating"].(int),
+		Comment: stringArg(input, "comment"),
+	}, nil
 }
 
-func getMovieByID(id string) (*models.Movie, error) {
-	var movie models.Movie
-	err := database.DB.QueryRow(`
-		SELECT id, title, description, year, rating, duration, genre, director, poster_url, created_at, updated_at
-		FROM movies WHERE id = ?`, id).Scan(
-		&movie.ID, &movie.Title, &movie.Description, &movie.Year, &movie.Rating,
-		&movie.Duration, &movie.Genre, &movie.Director, &movie.PosterURL,
-		&movie.CreatedAt, &movie.UpdatedAt,
-	)
-	if err == sql.ErrNoRows {
-		return nil, fmt.Errorf("movie not found")
+func (r *Resolver) DeleteReview(p graphql.ResolveParams) (interface{}, error) {
+	id, ok := p.Args["id"].(string)
+	if !ok {
+		return nil, apperr.Invalid("id", "id is required")
 	}
-	if err != nil {
-		return nil, fmt.Errorf("failed to query movie: %v", err)
+
+	return r.store.DeleteReview(p.Context, id)
+}
+
+func (r *Resolver) GetReviews(p graphql.ResolveParams) (interface{}, error) {
+	movieID, ok := p.Args["movie_id"].(string)
+	if !ok {
+		return nil, apperr.Invalid("movie_id", "movie_id is required")
 	}
-	return &movie, nil
+
+	return r.store.ReviewsForMovie(p.Context, movieID)
 }
 
-func getActorsForMovie(movieID string) ([]models.Actor, error) {
-	rows, err := database.DB.Query(`
-		SELECT a.id, a.name, a.birth_date, a.nationality, a.biography, a.profile_url
-		FROM actors a
-		INNER JOIN movie_actors ma ON a.id = ma.actor_id
-		WHERE ma.movie_id = ?`, movieID)
-	if err != nil {
-		return nil, err
+func (r *Resolver) GetActor(p graphql.ResolveParams) (interface{}, error) {
+	id, ok := p.Args["id"].(string)
+	if !ok {
+		return nil, apperr.Invalid("id", "id is required")
 	}
-	defer rows.Close()
 
-	var actors []models.Actor
-	for rows.Next() {
-		var actor models.Actor
-		err := rows.Scan(&actor.ID, &actor.Name, &actor.BirthDate, &actor.Nationality, &actor.Biography, &actor.ProfileURL)
-		if err != nil {
-			return nil, err
-		}
-		actors = append(actors, actor)
+	actor, err := r.store.GetActor(p.Context, id)
+	if err == store.ErrNotFound {
+		return nil, apperr.NotFoundf("actor not found")
 	}
-	return actors, nil
+	return actor, err
 }
 
-func getReviewsForMovie(movieID string) ([]models.Review, error) {
-	rows, err := database.DB.Query(`
-		SELECT id, movie_id, user_name, rating, comment, created_at
-		FROM reviews WHERE movie_id = ? ORDER BY created_at DESC`, movieID)
-	if err != nil {
+func (r *Resolver) CreateActor(p graphql.ResolveParams) (interface{}, error) {
+	name, ok := p.Args["name"].(string)
+	if !ok || name == "" {
+		return nil, apperr.Invalid("name", "name is required")
+	}
+	if err := validationError(validate("", p.Args, actorRules)); err != nil {
 		return nil, err
 	}
-	defer rows.Close()
 
-	var reviews []models.Review
-	for rows.Next() {
-		var review models.Review
-		err := rows.Scan(&review.ID, &review.MovieID, &review.UserName, &review.Rating, &review.Comment, &review.CreatedAt)
-		if err != nil {
-			return nil, err
-		}
-		reviews = append(reviews, review)
+	return r.store.CreateActor(p.Context, actorFromInput(p.Args))
+}
+
+func (r *Resolver) getMovieByID(p graphql.ResolveParams, id string) (*models.Movie, error) {
+	movie, err := r.store.GetMovie(p.Context, id)
+	if err == store.ErrNotFound {
+		return nil, apperr.NotFoundf("movie not found")
 	}
-	return reviews, nil
+	return movie, err
 }
 
-func getReviewByID(id string) (*models.Review, error) {
-	var review models.Review
-	err := database.DB.QueryRow(`
-		SELECT id, movie_id, user_name, rating, comment, created_at
-		FROM reviews WHERE id = ?`, id).Scan(
-		&review.ID, &review.MovieID, &review.UserName, &review.Rating, &review.Comment, &review.CreatedAt,
-	)
-	if err != nil {
-		if err == sql.ErrNoRows {
-			return nil, fmt.Errorf("review with id %s not found", id)
-		}
-		return nil, fmt.Errorf("error retrieving review: %v", err)
+// movieFromSource accepts both the *models.Movie returned by single-movie
+// resolvers and the models.Movie values inside a MoviesResult.
+func movieFromSource(source interface{}) (*models.Movie, bool) {
+	switch movie := source.(type) {
+	case *models.Movie:
+		return movie, true
+	case models.Movie:
+		return &movie, true
+	}
+	return nil, false
+}
+
+func actorFromSource(source interface{}) (*models.Actor, bool) {
+	switch actor := source.(type) {
+	case *models.Actor:
+		return actor, true
+	case models.Actor:
+		return &actor, true
+	}
+	return nil, false
+}
+
+func movieFromInput(input map[string]interface{}) models.Movie {
+	return models.Movie{
+		Title:       input["title"].(string),
+		Description: stringArg(input, "description"),
+		Year:        input["year"].(int),
+		Rating:      input["rating"].(float64),
+		Duration:    input["duration"].(int),
+		Genre:       stringArg(input, "genre"),
+		Director:    stringArg(input, "director"),
+		PosterURL:   stringArg(input, "poster_url"),
+	}
+}
+
+func actorFromInput(input map[string]interface{}) models.Actor {
+	return models.Actor{
+		Name:        stringArg(input, "name"),
+		BirthDate:   stringArg(input, "birth_date"),
+		Nationality: stringArg(input, "nationality"),
+		Biography:   stringArg(input, "biography"),
+		ProfileURL:  stringArg(input, "profile_url"),
+	}
+}
+
+// stringArg returns the optional string argument key, or "" when omitted.
+func stringArg(args map[string]interface{}, key string) string {
+	s, _ := args[key].(string)
+	return s
+}
+
+// stringsArg returns the optional [String!] argument key, or nil when omitted.
+func stringsArg(args map[string]interface{}, key string) []string {
+	values, _ := args[key].([]interface{})
+	var strs []string
+	for _, v := range values {
+		strs = append(strs, v.(string))
+	}
+	return strs
+}
+
+// pageArgs reads page and limit, clamping limit to between 1 and
+// MaxPageSize. Pages are numbered from 1.
+func pageArgs(p graphql.ResolveParams) (page, limit int, err error) {
+	page = 1
+	limit = DefaultPageSize
+
+	if p.Args["page"] != nil {
+		page = p.Args["page"].(int)
+	}
+	if page < 1 {
+		return 0, 0, apperr.Invalid("page", "page must be at least 1")
+	}
+	if p.Args["limit"] != nil {
+		limit = max(1, min(p.Args["limit"].(int), MaxPageSize))
+	}
+	return page, limit, nil
+}
+
+func newMoviesResult(movies []models.Movie, page, limit, total int) *models.MoviesResult {
+	return &models.MoviesResult{
+		Movies:     movies,
+		Pagination: newPagination(page, limit, total),
+	}
+}
+
+func newPagination(page, limit, total int) models.Pagination {
+	totalPages := (total + limit - 1) / limit
+
+	return models.Pagination{
+		Page:       page,
+		Limit:      limit,
+		Total:      total,
+		TotalPages: totalPages,
 	}
-	return &review, nil
 }
@@ -0,0 +1,1168 @@
+package resolvers
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"fmt"
+	"movie-app/internal/auth"
+	"movie-app/internal/database"
+	"movie-app/internal/models"
+	"movie-app/internal/store"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/language/ast"
+	"github.com/graphql-go/graphql/language/parser"
+	"github.com/graphql-go/graphql/language/printer"
+)
+
+// sdlSignatures flattens every object/input field and argument declared in
+// schema.graphql into "Type.field" / "Type.field(arg)" keys mapped to their
+// printed type, e.g. "Query.movie(id)" => "ID!".
+func sdlSignatures(t *testing.T, path string) map[string]string {
+	t.Helper()
+
+	body, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("failed to read %s: %v", path, err)
+	}
+
+	doc, err := parser.Parse(parser.ParseParams{Source: string(body)})
+	if err != nil {
+		t.Fatalf("failed to parse %s: %v", path, err)
+	}
+
+	sigs := map[string]string{}
+	for _, def := range doc.Definitions {
+		switch def := def.(type) {
+		case *ast.ObjectDefinition:
+			for _, f := range def.Fields {
+				key := def.Name.Value + "." + f.Name.Value
+				sigs[key] = printer.Print(f.Type).(string)
+				for _, arg := range f.Arguments {
+					sigs[key+"("+arg.Name.Value+")"] = printer.Print(arg.Type).(string)
+				}
+			}
+		case *ast.InputObjectDefinition:
+			for _, f := range def.Fields {
+				sigs[def.Name.Value+"."+f.Name.Value] = printer.Print(f.Type).(string)
+			}
+		}
+	}
+	return sigs
+}
+
+// schemaSignatures produces the same flattening as sdlSignatures from the
+// executable schema's type map.
+func schemaSignatures(schema graphql.Schema) map[string]string {
+	sigs := map[string]string{}
+	for name, typ := range schema.TypeMap() {
+		if strings.HasPrefix(name, "__") {
+			continue
+		}
+		switch typ := typ.(type) {
+		case *graphql.Object:
+			for fieldName, f := range typ.Fields() {
+				key := name + "." + fieldName
+				sigs[key] = f.Type.String()
+				for _, arg := range f.Args {
+					sigs[key+"("+arg.Name()+")"] = arg.Type.String()
+				}
+			}
+		case *graphql.InputObject:
+			for fieldName, f := range typ.Fields() {
+				sigs[name+"."+fieldName] = f.Type.String()
+			}
+		}
+	}
+	return sigs
+}
+
+// newTestSchema builds the executable schema against a fresh SQLite file.
+func newTestSchema(t *testing.T) (graphql.Schema, *sql.DB) {
+	t.Helper()
+
+	db, err := database.Open(filepath.Join(t.TempDir(), "movies.db"))
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	schema, err := CreateSchema(store.NewSQLiteStore(db))
+	if err != nil {
+		t.Fatalf("failed to create schema: %v", err)
+	}
+	return schema, db
+}
+
+// execute runs query against schema and fails the test on any GraphQL error.
+func execute(t *testing.T, schema graphql.Schema, query string, vars map[string]interface{}) map[string]interface{} {
+	t.Helper()
+	return executeAs(t, schema, "tester", query, vars)
+}
+
+// executeAs is execute with subject, an admin, as the caller.
+func executeAs(t *testing.T, schema graphql.Schema, subject, query string, vars map[string]interface{}) map[string]interface{} {
+	t.Helper()
+
+	result := graphql.Do(graphql.Params{
+		Schema:         schema,
+		RequestString:  query,
+		VariableValues: vars,
+		Context:        WithVariables(contextAs(subject), vars),
+	})
+	if len(result.Errors) > 0 {
+		t.Fatalf("unexpected errors: %v", result.Errors)
+	}
+	return result.Data.(map[string]interface{})
+}
+
+// testContext authenticates a request as the principal every test mutation
+// runs as.
+func testContext() context.Context {
+	return contextAs("tester")
+}
+
+func contextAs(subject string) context.Context {
+	return auth.WithPrincipal(context.Background(), &auth.Principal{Subject: subject, Method: auth.MethodAPIKey, Role: auth.Admin})
+}
+
+func TestSchemaMatchesSDL(t *testing.T) {
+	schema, _ := newTestSchema(t)
+
+	want := sdlSignatures(t, "../schema/schema.graphql")
+	got := schemaSignatures(schema)
+
+	var diffs []string
+	for key, wantType := range want {
+		gotType, ok := got[key]
+		switch {
+		case !ok:
+			diffs = append(diffs, "missing from executable schema: "+key+": "+wantType)
+		case gotType != wantType:
+			diffs = append(diffs, "type mismatch for "+key+": schema.graphql has "+wantType+", executable schema has "+gotType)
+		}
+	}
+	for key, gotType := range got {
+		if _, ok := want[key]; !ok {
+			diffs = append(diffs, "not declared in schema.graphql: "+key+": "+gotType)
+		}
+	}
+
+	if len(diffs) > 0 {
+		sort.Strings(diffs)
+		t.Fatalf("executable schema drifted from schema.graphql:\n  %s", strings.Join(diffs, "\n  "))
+	}
+}
+
+func TestActorAndReviewOperations(t *testing.T) {
+	schema, _ := newTestSchema(t)
+
+	movie := execute(t, schema, `mutation {
+		createMovie(input: {title: "Heat", year: 1995, rating: 8.3, duration: 170}) { id }
+	}`, nil)["createMovie"].(map[string]interface{})
+
+	actor := execute(t, schema, `mutation {
+		createActor(name: "Al Pacino", nationality: "American") { id name nationality birth_date }
+	}`, nil)["createActor"].(map[string]interface{})
+	if actor["name"] != "Al Pacino" || actor["nationality"] != "American" {
+		t.Fatalf("unexpected actor: %v", actor)
+	}
+
+	got := execute(t, schema, `query($id: ID!) { actor(id: $id) { name } }`,
+		map[string]interface{}{"id": actor["id"]})["actor"].(map[string]interface{})
+	if got["name"] != "Al Pacino" {
+		t.Fatalf("unexpected actor lookup: %v", got)
+	}
+
+	review := execute(t, schema, `mutation($movie: ID!) {
+		createReview(input: {movie_id: $movie, rating: 4}) { id }
+	}`, map[string]interface{}{"movie": movie["id"]})["createReview"].(map[string]interface{})
+
+	reviews := execute(t, schema, `query($movie: ID!) { reviews(movie_id: $movie) { id } }`,
+		map[string]interface{}{"movie": movie["id"]})["reviews"].([]interface{})
+	if len(reviews) != 1 {
+		t.Fatalf("expected 1 review, got %d", len(reviews))
+	}
+
+	deleted := execute(t, schema, `mutation($id: ID!) { deleteReview(id: $id) }`,
+		map[string]interface{}{"id": review["id"]})["deleteReview"]
+	if deleted != true {
+		t.Fatalf("expected deleteReview to return true, got %v", deleted)
+	}
+
+	reviews = execute(t, schema, `query($movie: ID!) { reviews(movie_id: $movie) { id } }`,
+		map[string]interface{}{"movie": movie["id"]})["reviews"].([]interface{})
+	if len(reviews) != 0 {
+		t.Fatalf("expected no reviews after delete, got %d", len(reviews))
+	}
+}
+
+// countingDB counts every statement issued through it.
+type countingDB struct {
+	store.DBTX
+	statements int
+}
+
+func (c *countingDB) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	c.statements++
+	return c.DBTX.ExecContext(ctx, query, args...)
+}
+
+func (c *countingDB) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	c.statements++
+	return c.DBTX.QueryContext(ctx, query, args...)
+}
+
+func (c *countingDB) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	c.statements++
+	return c.DBTX.QueryRowContext(ctx, query, args...)
+}
+
+func TestMovieListBatchesActorsAndReviews(t *testing.T) {
+	_, db := newTestSchema(t)
+	seed := store.NewSQLiteStore(db)
+	ctx := context.Background()
+
+	for i := 0; i < 25; i++ {
+		movie, err := seed.CreateMovie(ctx, models.Movie{Title: fmt.Sprintf("Movie %d", i), Year: 2000 + i, Rating: 7, Duration: 100})
+		if err != nil {
+			t.Fatalf("failed to create movie: %v", err)
+		}
+		actor, err := seed.CreateActor(ctx, models.Actor{Name: fmt.Sprintf("Actor %d", i)})
+		if err != nil {
+			t.Fatalf("failed to create actor: %v", err)
+		}
+		if err := seed.AddCastMember(ctx, models.MovieActor{MovieID: movie.ID, ActorID: actor.ID}); err != nil {
+			t.Fatalf("failed to link actor: %v", err)
+		}
+		if _, err := seed.CreateReview(ctx, models.Review{MovieID: movie.ID, UserName: "alice", Rating: 4}); err != nil {
+			t.Fatalf("failed to create review: %v", err)
+		}
+	}
+
+	counter := &countingDB{DBTX: db}
+	s := store.NewSQLiteStore(counter)
+	schema, err := CreateSchema(s)
+	if err != nil {
+		t.Fatalf("failed to create schema: %v", err)
+	}
+
+	result := graphql.Do(graphql.Params{
+		Schema:        schema,
+		RequestString: `{ movies(page: 2, limit: 20) { movies { id actors { name } reviews { rating } } } }`,
+		Context:       WithLoaders(testContext(), s),
+	})
+	if len(result.Errors) > 0 {
+		t.Fatalf("unexpected errors: %v", result.Errors)
+	}
+
+	movies := result.Data.(map[string]interface{})["movies"].(map[string]interface{})["movies"].([]interface{})
+	if len(movies) != 5 {
+		t.Fatalf("expected 5 movies on page 2, got %d", len(movies))
+	}
+	for _, m := range movies {
+		movie := m.(map[string]interface{})
+		if len(movie["actors"].([]interface{})) != 1 || len(movie["reviews"].([]interface{})) != 1 {
+			t.Fatalf("expected one actor and one review for %v", movie)
+		}
+	}
+
+	// count + page + one IN (...) query each for actors and reviews
+	if counter.statements != 4 {
+		t.Fatalf("expected 4 statements, got %d", counter.statements)
+	}
+}
+
+func countRows(t *testing.T, db *sql.DB, table string) int {
+	t.Helper()
+
+	var n int
+	if err := db.QueryRow("SELECT COUNT(*) FROM " + table).Scan(&n); err != nil {
+		t.Fatalf("failed to count %s: %v", table, err)
+	}
+	return n
+}
+
+const createMovieWithDetailsMutation = `mutation($input: MovieWithDetailsInput!) {
+	createMovieWithDetails(input: $input) { id actors { id name } reviews { rating } }
+}`
+
+func TestCreateMovieWithDetailsRollsBackOnFailure(t *testing.T) {
+	schema, db := newTestSchema(t)
+
+	result := graphql.Do(graphql.Params{
+		Schema:        schema,
+		RequestString: createMovieWithDetailsMutation,
+		VariableValues: map[string]interface{}{"input": map[string]interface{}{
+			"movie": map[string]interface{}{"title": "Broken", "year": 2020, "rating": 5.0, "duration": 90, "director": "Someone"},
+			"actors": []interface{}{
+				map[string]interface{}{"name": "New Actor"},
+				map[string]interface{}{"name": "new actor"},
+			},
+			"reviews": []interface{}{
+				map[string]interface{}{"user_name": "a", "rating": 5},
+			},
+		}},
+		Context: testContext(),
+	})
+	if len(result.Errors) == 0 {
+		t.Fatalf("expected the actor listed twice to fail the mutation")
+	}
+
+	for _, table := range []string{"movies", "actors", "movie_actors", "reviews", "directors"} {
+		if n := countRows(t, db, table); n != 0 {
+			t.Errorf("expected %s to be empty after rollback, got %d rows", table, n)
+		}
+	}
+}
+
+func TestInputValidation(t *testing.T) {
+	schema, db := newTestSchema(t)
+
+	fieldErrors := func(query string) string {
+		t.Helper()
+		result := graphql.Do(graphql.Params{Schema: schema, RequestString: query, Context: testContext()})
+		if len(result.Errors) != 1 || result.Errors[0].Extensions["code"] != "VALIDATION_FAILED" {
+			t.Fatalf("expected VALIDATION_FAILED, got %+v", result.Errors)
+		}
+		return fmt.Sprint(result.Errors[0].Extensions["fields"])
+	}
+
+	got := fieldErrors(`mutation { createMovie(input: {title: " ", year: 1995, rating: 42, duration: -5}) { id } }`)
+	want := "[{input.title title must not be blank} {input.rating rating must be between 0 and 10} " +
+		"{input.duration duration must be greater than 0}]"
+	if got != want {
+		t.Fatalf("expected every violation at once:\n got %s\nwant %s", got, want)
+	}
+
+	got = fieldErrors(`mutation { createMovieWithDetails(input: {
+		movie: {title: "", year: 1979, rating: 8.5, duration: 117},
+		actors: [{name: "Sigourney Weaver", birth_date: "2999-10-08"}],
+		reviews: [{user_name: "alice", rating: 5}, {user_name: "bob", rating: 9}]
+	}) { id } }`)
+	want = "[{input.movie.title title must not be blank} " +
+		"{input.actors[0].birth_date birth_date must not be in the future} " +
+		"{input.reviews[1].rating rating must be between 1 and 5}]"
+	if got != want {
+		t.Fatalf("expected nested paths:\n got %s\nwant %s", got, want)
+	}
+
+	got = fieldErrors(`mutation { createActor(name: "  ") { id } }`)
+	if got != "[{name name must not be blank}]" {
+		t.Fatalf("unexpected createActor violations: %s", got)
+	}
+
+	movie := execute(t, schema, `mutation {
+		createMovie(input: {title: "Heat", year: 1995, rating: 8.3, duration: 170, poster_url: "https://example.com/heat.jpg"}) { id }
+	}`, nil)["createMovie"].(map[string]interface{})
+	got = fieldErrors(`mutation { patchMovie(id: "` + movie["id"].(string) + `", patch: {duration: 0}) { changed_fields } }`)
+	if got != "[{patch.duration duration must be greater than 0}]" {
+		t.Fatalf("unexpected patchMovie violations: %s", got)
+	}
+
+	if n := countRows(t, db, "movies"); n != 1 {
+		t.Fatalf("expected only the valid movie to be created, got %d", n)
+	}
+}
+
+func TestCustomScalars(t *testing.T) {
+	schema, _ := newTestSchema(t)
+
+	// Variables arrive JSON-decoded, so the year is a float64.
+	movie := execute(t, schema, `mutation($year: Year!, $poster: URL) {
+		createMovie(input: {title: "Heat", year: $year, rating: 8.3, duration: 170, poster_url: $poster}) {
+			year poster_url created_at
+		}
+	}`, map[string]interface{}{"year": 1995.0, "poster": "https://example.com/heat.jpg"})["createMovie"].(map[string]interface{})
+	if movie["year"] != 1995 || movie["poster_url"] != "https://example.com/heat.jpg" {
+		t.Fatalf("unexpected movie: %v", movie)
+	}
+	createdAt, _ := movie["created_at"].(string)
+	if _, err := time.Parse(time.RFC3339, createdAt); err != nil || !strings.HasSuffix(createdAt, "Z") {
+		t.Fatalf("expected an RFC 3339 UTC created_at, got %q (err %v)", createdAt, err)
+	}
+
+	actor := execute(t, schema, `mutation { createActor(name: "Al Pacino", birth_date: "1940-04-25") { birth_date profile_url } }`,
+		nil)["createActor"].(map[string]interface{})
+	if actor["birth_date"] != "1940-04-25" || actor["profile_url"] != nil {
+		t.Fatalf("unexpected actor: %v", actor)
+	}
+
+	for _, tc := range []struct{ query, want string }{
+		{`mutation { createMovie(input: {title: "Heat", year: 3000, rating: 8, duration: 170}) { id } }`, `Expected type "Year"`},
+		{`mutation { createMovie(input: {title: "Heat", year: 1995, rating: 8, duration: 170, poster_url: "poster.jpg"}) { id } }`, `Expected type "URL"`},
+		{`mutation { createActor(name: "Al Pacino", birth_date: "yesterday") { id } }`, `Expected type "Date"`},
+		{`mutation { createActor(name: "Al Pacino", profile_url: "ftp://example.com/al.jpg") { id } }`, `Expected type "URL"`},
+		{`{ movieAt(id: "1", timestamp: "2024-05-01") { id } }`, `Expected type "DateTime"`},
+	} {
+		result := graphql.Do(graphql.Params{Schema: schema, RequestString: tc.query, Context: testContext()})
+		if len(result.Errors) != 1 || !strings.Contains(result.Errors[0].Message, tc.want) {
+			t.Fatalf("expected %s to be rejected with %q, got %v", tc.query, tc.want, result.Errors)
+		}
+		if formatted := FormatError(result.Errors[0].OriginalError()); formatted.Extensions["code"] != "VALIDATION_FAILED" {
+			t.Fatalf("expected VALIDATION_FAILED, got %+v", formatted)
+		}
+	}
+}
+
+func TestCreateMovieWithDetailsReusesActors(t *testing.T) {
+	schema, db := newTestSchema(t)
+
+	pacino := execute(t, schema, `mutation { createActor(name: "Al Pacino") { id } }`, nil)["createActor"].(map[string]interface{})
+	deniro := execute(t, schema, `mutation { createActor(name: "Robert De Niro") { id } }`, nil)["createActor"].(map[string]interface{})
+
+	movie := execute(t, schema, createMovieWithDetailsMutation, map[string]interface{}{"input": map[string]interface{}{
+		"movie": map[string]interface{}{"title": "Heat", "year": 1995, "rating": 8.3, "duration": 170},
+		"actors": []interface{}{
+			map[string]interface{}{"name": "al pacino", "character_name": "Vincent Hanna"},
+			map[string]interface{}{"id": deniro["id"], "character_name": "Neil McCauley"},
+			map[string]interface{}{"name": "Val Kilmer", "character_name": "Chris Shiherlis"},
+		},
+	}})["createMovieWithDetails"].(map[string]interface{})
+
+	if n := countRows(t, db, "actors"); n != 3 {
+		t.Fatalf("expected only Val Kilmer to be created (3 actors total), got %d", n)
+	}
+
+	var character string
+	err := db.QueryRow("SELECT character_name FROM movie_actors WHERE movie_id = ? AND actor_id = ?",
+		movie["id"], pacino["id"]).Scan(&character)
+	if err != nil || character != "Vincent Hanna" {
+		t.Fatalf("expected Al Pacino to be linked as Vincent Hanna, got %q (err %v)", character, err)
+	}
+}
+
+func TestCastMutations(t *testing.T) {
+	schema, _ := newTestSchema(t)
+
+	movie := execute(t, schema, `mutation {
+		createMovie(input: {title: "Heat", year: 1995, rating: 8.3, duration: 170}) { id }
+	}`, nil)["createMovie"].(map[string]interface{})
+
+	var ids []interface{}
+	for _, name := range []string{"Al Pacino", "Robert De Niro"} {
+		actor := execute(t, schema, `mutation($name: String!) { createActor(name: $name) { id } }`,
+			map[string]interface{}{"name": name})["createActor"].(map[string]interface{})
+		ids = append(ids, actor["id"])
+	}
+
+	const add = `mutation($movie: ID!, $actor: ID!, $character: String, $order: Int) {
+		addCastMember(movie_id: $movie, actor_id: $actor, character_name: $character, billing_order: $order) {
+			actor { name } character_name billing_order
+		}
+	}`
+	execute(t, schema, add, map[string]interface{}{"movie": movie["id"], "actor": ids[0], "character": "Vincent Hanna"})
+	member := execute(t, schema, add, map[string]interface{}{"movie": movie["id"], "actor": ids[1], "character": "Neil McCauley", "order": 1})["addCastMember"].(map[string]interface{})
+	if member["billing_order"] != 1 || member["character_name"] != "Neil McCauley" {
+		t.Fatalf("unexpected cast member: %v", member)
+	}
+
+	cast := execute(t, schema, `mutation($movie: ID!, $actors: [ID!]!) {
+		reorderCast(movie_id: $movie, actor_ids: $actors) { actor { name } billing_order }
+	}`, map[string]interface{}{"movie": movie["id"], "actors": ids})["reorderCast"].([]interface{})
+	if first := cast[0].(map[string]interface{}); first["actor"].(map[string]interface{})["name"] != "Al Pacino" {
+		t.Fatalf("expected Al Pacino to be billed first, got %v", cast)
+	}
+
+	removed := execute(t, schema, `mutation($movie: ID!, $actor: ID!) { removeCastMember(movie_id: $movie, actor_id: $actor) }`,
+		map[string]interface{}{"movie": movie["id"], "actor": ids[0]})["removeCastMember"]
+	if removed != true {
+		t.Fatalf("expected removeCastMember to return true, got %v", removed)
+	}
+
+	got := execute(t, schema, `query($movie: ID!, $actor: ID!) {
+		movie(id: $movie) { cast { actor { name } character_name billing_order } }
+		actor(id: $actor) { filmography { movie { title } character_name } }
+	}`, map[string]interface{}{"movie": movie["id"], "actor": ids[1]})
+	cast = got["movie"].(map[string]interface{})["cast"].([]interface{})
+	if len(cast) != 1 || cast[0].(map[string]interface{})["billing_order"] != 1 {
+		t.Fatalf("expected De Niro alone at billing 1, got %v", cast)
+	}
+	films := got["actor"].(map[string]interface{})["filmography"].([]interface{})
+	if len(films) != 1 || films[0].(map[string]interface{})["character_name"] != "Neil McCauley" {
+		t.Fatalf("unexpected filmography: %v", films)
+	}
+}
+
+func TestDirectorsAreCreditedAndAggregated(t *testing.T) {
+	schema, _ := newTestSchema(t)
+
+	matrix := execute(t, schema, `mutation {
+		createMovie(input: {title: "The Matrix", year: 1999, rating: 8.7, duration: 136, director: "Lana Wachowski, Lilly Wachowski"}) {
+			id directors { id name }
+		}
+	}`, nil)["createMovie"].(map[string]interface{})
+	directors := matrix["directors"].([]interface{})
+	if len(directors) != 2 || directors[1].(map[string]interface{})["name"] != "Lilly Wachowski" {
+		t.Fatalf("expected both Wachowskis in credit order, got %v", directors)
+	}
+	lana := directors[0].(map[string]interface{})["id"]
+
+	execute(t, schema, `mutation {
+		createMovie(input: {title: "Cloud Atlas", year: 2012, rating: 7.4, duration: 172, director: "Lana Wachowski"}) { id }
+	}`, nil)
+	execute(t, schema, `mutation($movie: ID!) {
+		createReview(input: {movie_id: $movie, rating: 4}) { id }
+	}`, map[string]interface{}{"movie": matrix["id"]})
+
+	director := execute(t, schema, `query($id: ID!) {
+		director(id: $id) {
+			name
+			filmography { title }
+			stats { movie_count average_rating first_year latest_year review_count average_review_score }
+		}
+	}`, map[string]interface{}{"id": lana})["director"].(map[string]interface{})
+
+	films := director["filmography"].([]interface{})
+	if len(films) != 2 || films[0].(map[string]interface{})["title"] != "Cloud Atlas" {
+		t.Fatalf("expected newest-first filmography, got %v", films)
+	}
+	stats := director["stats"].(map[string]interface{})
+	if stats["movie_count"] != 2 || stats["first_year"] != 1999 || stats["latest_year"] != 2012 ||
+		stats["review_count"] != 1 || stats["average_review_score"] != 4.0 {
+		t.Fatalf("unexpected stats: %v", stats)
+	}
+
+	// Re-crediting the movie drops Lilly from it; she stays listed with no movies.
+	execute(t, schema, `mutation($id: ID!) {
+		updateMovie(id: $id, input: {title: "The Matrix", year: 1999, rating: 8.7, duration: 136, director: "Lana Wachowski"}) { id }
+	}`, map[string]interface{}{"id": matrix["id"]})
+
+	result := execute(t, schema, `{
+		directors(search: "lilly") { directors { name stats { movie_count average_rating } } pagination { total } }
+	}`, nil)["directors"].(map[string]interface{})
+	listed := result["directors"].([]interface{})
+	if len(listed) != 1 {
+		t.Fatalf("expected one director matching lilly, got %v", listed)
+	}
+	stats = listed[0].(map[string]interface{})["stats"].(map[string]interface{})
+	if stats["movie_count"] != 0 || stats["average_rating"] != nil {
+		t.Fatalf("expected empty stats for a director without movies, got %v", stats)
+	}
+}
+
+func TestMovieGenresAndGenreFilter(t *testing.T) {
+	schema, _ := newTestSchema(t)
+
+	movie := execute(t, schema, `mutation {
+		createMovie(input: {title: "Inception", year: 2010, rating: 8.8, duration: 148, genre: "Sci-Fi, Action"}) {
+			genre genres { name }
+		}
+	}`, nil)["createMovie"].(map[string]interface{})
+	genres := movie["genres"].([]interface{})
+	if movie["genre"] != "Sci-Fi, Action" || len(genres) != 2 || genres[0].(map[string]interface{})["name"] != "Sci-Fi" {
+		t.Fatalf("unexpected genres: %v", movie)
+	}
+	execute(t, schema, `mutation {
+		createMovie(input: {title: "Rush Hour", year: 1998, rating: 7.0, duration: 98, genre: "Action-Comedy"}) { id }
+	}`, nil)
+
+	result := execute(t, schema, `{
+		movies(filter: {genres_any: ["action"]}) { movies { title } }
+		genres { name movie_count }
+	}`, nil)
+	movies := result["movies"].(map[string]interface{})["movies"].([]interface{})
+	if len(movies) != 1 || movies[0].(map[string]interface{})["title"] != "Inception" {
+		t.Fatalf("expected only Inception to match action, got %v", movies)
+	}
+	if listed := result["genres"].([]interface{}); len(listed) != 3 {
+		t.Fatalf("expected 3 genres, got %v", listed)
+	}
+}
+
+func TestSearchMoviesReturnsHighlights(t *testing.T) {
+	schema, _ := newTestSchema(t)
+
+	movie := execute(t, schema, `mutation {
+		createMovie(input: {title: "Interstellar", description: "A wormhole journey.", year: 2014, rating: 8.6, duration: 169}) { id highlights { field } }
+	}`, nil)["createMovie"].(map[string]interface{})
+	if movie["highlights"] != nil {
+		t.Fatalf("expected no highlights outside search, got %v", movie["highlights"])
+	}
+
+	result := execute(t, schema, `{
+		searchMovies(query: "worm*") { movies { title highlights { field snippet } } pagination { total } }
+	}`, nil)["searchMovies"].(map[string]interface{})
+	movies := result["movies"].([]interface{})
+	if len(movies) != 1 {
+		t.Fatalf("expected one match, got %v", movies)
+	}
+	highlights := movies[0].(map[string]interface{})["highlights"].([]interface{})
+	want := map[string]interface{}{"field": "description", "snippet": "A <mark>wormhole</mark> journey."}
+	if len(highlights) != 1 || fmt.Sprint(highlights[0]) != fmt.Sprint(want) {
+		t.Fatalf("expected %v, got %v", want, highlights)
+	}
+}
+
+func TestMoviesConnection(t *testing.T) {
+	schema, _ := newTestSchema(t)
+
+	for _, title := range []string{"Alien", "Heat", "Ronin"} {
+		execute(t, schema, `mutation($title: String!) { createMovie(input: {title: $title, year: 1995, rating: 8, duration: 120}) { id } }`,
+			map[string]interface{}{"title": title})
+	}
+
+	const query = `query($after: String) {
+		moviesConnection(first: 2, after: $after) {
+			edges { cursor node { title } }
+			pageInfo { hasNextPage hasPreviousPage startCursor endCursor }
+			totalCount
+		}
+	}`
+	var titles []string
+	var after interface{}
+	for {
+		conn := execute(t, schema, query, map[string]interface{}{"after": after})["moviesConnection"].(map[string]interface{})
+		for _, edge := range conn["edges"].([]interface{}) {
+			titles = append(titles, edge.(map[string]interface{})["node"].(map[string]interface{})["title"].(string))
+		}
+		info := conn["pageInfo"].(map[string]interface{})
+		if conn["totalCount"] != 3 || info["hasPreviousPage"] != (after != nil) {
+			t.Fatalf("unexpected connection page: %v", conn)
+		}
+		if info["hasNextPage"] != true {
+			break
+		}
+		after = info["endCursor"]
+	}
+	sort.Strings(titles)
+	if strings.Join(titles, ",") != "Alien,Heat,Ronin" {
+		t.Fatalf("expected every movie exactly once, got %v", titles)
+	}
+
+	result := graphql.Do(graphql.Params{
+		Schema:        schema,
+		RequestString: `{ moviesConnection(first: 1, last: 1) { totalCount } }`,
+		Context:       testContext(),
+	})
+	if len(result.Errors) == 0 {
+		t.Fatalf("expected first and last together to be rejected")
+	}
+	result = graphql.Do(graphql.Params{
+		Schema:        schema,
+		RequestString: `{ moviesConnection(after: "bogus") { totalCount } }`,
+		Context:       testContext(),
+	})
+	if len(result.Errors) == 0 || result.Errors[0].Message != "invalid cursor" {
+		t.Fatalf("expected an invalid cursor error, got %v", result.Errors)
+	}
+
+	// A page size of 0 is an empty page in the direction asked for.
+	for query, want := range map[string]string{
+		`{ moviesConnection(first: 0) { edges { cursor } pageInfo { hasNextPage hasPreviousPage } } }`: "[] true false",
+		`{ moviesConnection(last: 0) { edges { cursor } pageInfo { hasNextPage hasPreviousPage } } }`:  "[] false true",
+	} {
+		conn := execute(t, schema, query, nil)["moviesConnection"].(map[string]interface{})
+		info := conn["pageInfo"].(map[string]interface{})
+		if got := fmt.Sprint(conn["edges"], " ", info["hasNextPage"], " ", info["hasPreviousPage"]); got != want {
+			t.Errorf("%s: got %s, want %s", query, got, want)
+		}
+	}
+}
+
+func TestPageSizesAreClamped(t *testing.T) {
+	schema, _ := newTestSchema(t)
+
+	for _, tt := range []struct {
+		query string
+		want  int
+	}{
+		{`{ movies(limit: 10000) { pagination { limit } } }`, MaxPageSize},
+		{`{ movies(limit: 0) { pagination { limit } } }`, 1},
+		{`{ searchMovies(query: "heat", limit: 500) { pagination { limit } } }`, MaxPageSize},
+		{`{ searchMovies(query: "heat") { pagination { limit } } }`, DefaultPageSize},
+	} {
+		data := execute(t, schema, tt.query, nil)
+		for _, result := range data {
+			limit := result.(map[string]interface{})["pagination"].(map[string]interface{})["limit"]
+			if limit != tt.want {
+				t.Errorf("%s: limit = %v, want %d", tt.query, limit, tt.want)
+			}
+		}
+	}
+}
+
+func TestPageMustBePositive(t *testing.T) {
+	schema, _ := newTestSchema(t)
+
+	for _, query := range []string{
+		`{ movies(page: 0) { pagination { page } } }`,
+		`{ searchMovies(query: "heat", page: -5) { pagination { page } } }`,
+		`{ directors(page: -1) { pagination { page } } }`,
+	} {
+		result := graphql.Do(graphql.Params{Schema: schema, RequestString: query, Context: testContext()})
+		if len(result.Errors) != 1 || result.Errors[0].Extensions["code"] != "VALIDATION_FAILED" ||
+			fmt.Sprint(result.Errors[0].Extensions["fields"]) != "[{page page must be at least 1}]" {
+			t.Errorf("%s: expected page to be rejected, got %+v", query, result.Errors)
+		}
+	}
+}
+
+func TestMoviesSortArgument(t *testing.T) {
+	schema, _ := newTestSchema(t)
+
+	for _, m := range []map[string]interface{}{
+		{"title": "Heat", "year": 1995},
+		{"title": "Alien", "year": 1979},
+		{"title": "Ronin", "year": 1998},
+	} {
+		execute(t, schema, `mutation($title: String!, $year: Year!) {
+			createMovie(input: {title: $title, year: $year, rating: 8, duration: 120}) { id }
+		}`, m)
+	}
+
+	result := execute(t, schema, `{
+		movies(sort: [{field: YEAR, direction: DESC}]) { movies { title } }
+		moviesConnection(first: 2, sort: [{field: TITLE}]) { edges { node { title } } }
+	}`, nil)
+	movies := result["movies"].(map[string]interface{})["movies"].([]interface{})
+	var byYear []string
+	for _, m := range movies {
+		byYear = append(byYear, m.(map[string]interface{})["title"].(string))
+	}
+	if strings.Join(byYear, ",") != "Ronin,Heat,Alien" {
+		t.Fatalf("expected movies newest release first, got %v", byYear)
+	}
+	edges := result["moviesConnection"].(map[string]interface{})["edges"].([]interface{})
+	if len(edges) != 2 || fmt.Sprint(edges[0]) != "map[node:map[title:Alien]]" {
+		t.Fatalf("expected the connection sorted by title, got %v", edges)
+	}
+}
+
+func TestMovieReviewStats(t *testing.T) {
+	schema, _ := newTestSchema(t)
+
+	movie := execute(t, schema, `mutation {
+		createMovie(input: {title: "Alien", year: 1979, rating: 8.5, duration: 117}) { id weighted_score review_stats { count average histogram } }
+	}`, nil)["createMovie"].(map[string]interface{})
+	if movie["weighted_score"] != nil || fmt.Sprint(movie["review_stats"]) != "map[average:<nil> count:0 histogram:[0 0 0 0 0]]" {
+		t.Fatalf("expected empty review stats, got %v", movie)
+	}
+
+	for user, rating := range map[string]int{"alice": 5, "bob": 3} {
+		executeAs(t, schema, user, `mutation($id: ID!, $rating: Int!) {
+			createReview(input: {movie_id: $id, rating: $rating}) { id }
+		}`, map[string]interface{}{"id": movie["id"], "rating": rating})
+	}
+
+	result := execute(t, schema, `{
+		movies(filter: {min_review_count: 2}, sort: [{field: WEIGHTED_SCORE, direction: DESC}]) {
+			movies { weighted_score review_stats { count average histogram } }
+		}
+	}`, nil)
+	movies := result["movies"].(map[string]interface{})["movies"].([]interface{})
+	if len(movies) != 1 {
+		t.Fatalf("expected one reviewed movie, got %v", movies)
+	}
+	got := movies[0].(map[string]interface{})
+	if got["weighted_score"] != 4.0 || fmt.Sprint(got["review_stats"]) != "map[average:4 count:2 histogram:[0 0 1 0 1]]" {
+		t.Fatalf("unexpected review stats: %v", got)
+	}
+}
+
+func TestUsersAndUpsertReview(t *testing.T) {
+	schema, _ := newTestSchema(t)
+
+	anonymous := graphql.Do(graphql.Params{Schema: schema, RequestString: `{ me { id } }`, Context: context.Background()})
+	if len(anonymous.Errors) != 0 || anonymous.Data.(map[string]interface{})["me"] != nil {
+		t.Fatalf("expected me to be null for anonymous callers, got %v (errors %v)", anonymous.Data, anonymous.Errors)
+	}
+	me := execute(t, schema, `{ me { id name reviews { id } } }`, nil)["me"].(map[string]interface{})
+	if me["name"] != "tester" || fmt.Sprint(me["reviews"]) != "[]" {
+		t.Fatalf("expected a new user named after the subject, got %v", me)
+	}
+
+	vars := map[string]interface{}{"id": execute(t, schema, `mutation {
+		createMovie(input: {title: "Heat", year: 1995, rating: 8.3, duration: 170}) { id }
+	}`, nil)["createMovie"].(map[string]interface{})["id"]}
+	created := execute(t, schema, `mutation($id: ID!) {
+		createReview(input: {movie_id: $id, rating: 5}) { id user_id }
+	}`, vars)["createReview"].(map[string]interface{})
+	if created["user_id"] != me["id"] {
+		t.Fatalf("expected the review to be the caller's, got %v", created)
+	}
+
+	result := graphql.Do(graphql.Params{
+		Schema:         schema,
+		RequestString:  `mutation($id: ID!) { createReview(input: {movie_id: $id, rating: 4}) { id } }`,
+		VariableValues: vars,
+		Context:        testContext(),
+	})
+	if len(result.Errors) != 1 || result.Errors[0].Extensions["code"] != "CONFLICT" {
+		t.Fatalf("expected a second review of the movie to conflict, got %v", result.Errors)
+	}
+
+	upserted := execute(t, schema, `mutation($id: ID!) {
+		upsertReview(input: {movie_id: $id, rating: 2, comment: "Too long."}) { id rating comment }
+	}`, vars)["upsertReview"].(map[string]interface{})
+	if upserted["id"] != created["id"] || upserted["rating"] != 2 || upserted["comment"] != "Too long." {
+		t.Fatalf("expected upsertReview to replace the review, got %v", upserted)
+	}
+	executeAs(t, schema, "alice", `mutation($id: ID!) { upsertReview(input: {movie_id: $id, rating: 4}) { id } }`, vars)
+
+	execute(t, schema, `mutation {
+		updateProfile(input: {name: "Tess", bio: "Watches everything.", avatar_url: "https://example.com/tess.png"}) { id }
+	}`, nil)
+	movie := execute(t, schema, `query($id: ID!) {
+		movie(id: $id) { review_stats { count average } reviews { user_name user { name bio avatar_url reviews { rating } } } }
+	}`, vars)["movie"].(map[string]interface{})
+	if got := fmt.Sprint(movie["review_stats"]); got != "map[average:3 count:2]" {
+		t.Fatalf("expected the upserted rating in the stats, got %s", got)
+	}
+	var reviews []string
+	for _, review := range movie["reviews"].([]interface{}) {
+		reviews = append(reviews, fmt.Sprint(review))
+	}
+	sort.Strings(reviews)
+	want := "map[user:map[avatar_url:<nil> bio: name:alice reviews:[map[rating:4]]] user_name:alice] " +
+		"map[user:map[avatar_url:https://example.com/tess.png bio:Watches everything. name:Tess reviews:[map[rating:2]]] user_name:Tess]"
+	if got := strings.Join(reviews, " "); got != want {
+		t.Fatalf("unexpected reviews:\n got %s\nwant %s", got, want)
+	}
+}
+
+func TestPatchMovie(t *testing.T) {
+	schema, _ := newTestSchema(t)
+
+	movie := execute(t, schema, `mutation {
+		createMovie(input: {title: "Alien", description: "In space.", year: 1979, rating: 8.5, duration: 117, genre: "Horror"}) { id }
+	}`, nil)["createMovie"].(map[string]interface{})
+
+	const patchMovie = `mutation($id: ID!, $patch: MoviePatch!) {
+		patchMovie(id: $id, patch: $patch) { changed_fields movie { title description year genre genres { name } } }
+	}`
+	patched := execute(t, schema, patchMovie, map[string]interface{}{
+		"id":    movie["id"],
+		"patch": map[string]interface{}{"year": 1979, "description": nil, "genre": "Horror, Sci-Fi"},
+	})["patchMovie"].(map[string]interface{})
+	want := "map[changed_fields:[description genre] movie:map[description: genre:Horror, Sci-Fi " +
+		"genres:[map[name:Horror] map[name:Sci-Fi]] title:Alien year:1979]]"
+	if fmt.Sprint(patched) != want {
+		t.Fatalf("expected %s, got %v", want, patched)
+	}
+
+	// A null in a field variable clears the field too.
+	patched = execute(t, schema, `mutation($id: ID!, $genre: String) {
+		patchMovie(id: $id, patch: {genre: $genre}) { changed_fields movie { genre } }
+	}`, map[string]interface{}{"id": movie["id"], "genre": nil})["patchMovie"].(map[string]interface{})
+	if fmt.Sprint(patched) != "map[changed_fields:[genre] movie:map[genre:]]" {
+		t.Fatalf("expected genre to be cleared, got %v", patched)
+	}
+
+	for _, tc := range []struct {
+		vars map[string]interface{}
+		want string
+	}{
+		{map[string]interface{}{"id": movie["id"], "patch": map[string]interface{}{"title": nil}}, "title cannot be null"},
+		{map[string]interface{}{"id": "missing", "patch": map[string]interface{}{"title": "Heat"}}, "movie not found"},
+	} {
+		result := graphql.Do(graphql.Params{
+			Schema:         schema,
+			RequestString:  patchMovie,
+			VariableValues: tc.vars,
+			Context:        WithVariables(testContext(), tc.vars),
+		})
+		if len(result.Errors) == 0 || result.Errors[0].Message != tc.want {
+			t.Fatalf("expected %q, got %v", tc.want, result.Errors)
+		}
+	}
+}
+
+func TestMovieWritesCheckExpectedVersion(t *testing.T) {
+	schema, _ := newTestSchema(t)
+
+	movie := execute(t, schema, `mutation {
+		createMovie(input: {title: "Alien", year: 1979, rating: 8.5, duration: 117}) { id version }
+	}`, nil)["createMovie"].(map[string]interface{})
+	if movie["version"] != 1 {
+		t.Fatalf("expected version 1, got %v", movie)
+	}
+
+	patched := execute(t, schema, `mutation($id: ID!) {
+		patchMovie(id: $id, patch: {rating: 8.4}, expected_version: 1) { movie { version } }
+	}`, map[string]interface{}{"id": movie["id"]})
+	if fmt.Sprint(patched) != "map[patchMovie:map[movie:map[version:2]]]" {
+		t.Fatalf("expected the patch to reach version 2, got %v", patched)
+	}
+
+	for _, mutation := range []string{
+		`mutation($id: ID!) { updateMovie(id: $id, input: {title: "Heat", year: 1995, rating: 8, duration: 170}, expected_version: 1) { id } }`,
+		`mutation($id: ID!) { patchMovie(id: $id, patch: {title: "Heat"}, expected_version: 1) { changed_fields } }`,
+		`mutation($id: ID!) { deleteMovie(id: $id, expected_version: 1) }`,
+	} {
+		result := graphql.Do(graphql.Params{
+			Schema:         schema,
+			RequestString:  mutation,
+			VariableValues: map[string]interface{}{"id": movie["id"]},
+			Context:        testContext(),
+		})
+		if len(result.Errors) != 1 || result.Errors[0].Extensions["code"] != "CONFLICT" {
+			t.Fatalf("expected a CONFLICT error, got %v", result.Errors)
+		}
+		current, ok := result.Errors[0].Extensions["current"].(*models.Movie)
+		if !ok || current.Version != 2 || current.Title != "Alien" || current.Rating != 8.4 {
+			t.Fatalf("expected the current movie in the error, got %v", result.Errors[0].Extensions)
+		}
+	}
+}
+
+func TestMovieHistoryAndRevert(t *testing.T) {
+	schema, _ := newTestSchema(t)
+
+	do := func(actor, query string, vars map[string]interface{}) map[string]interface{} {
+		t.Helper()
+		result := graphql.Do(graphql.Params{
+			Schema:         schema,
+			RequestString:  query,
+			VariableValues: vars,
+			Context:        auth.WithPrincipal(WithVariables(context.Background(), vars), &auth.Principal{Subject: actor, Method: auth.MethodJWT, Role: auth.Editor}),
+		})
+		if len(result.Errors) > 0 {
+			t.Fatalf("unexpected errors: %v", result.Errors)
+		}
+		return result.Data.(map[string]interface{})
+	}
+
+	id := do("alice", `mutation {
+		createMovie(input: {title: "Alien", year: 1979, rating: 8.5, duration: 117}) { id }
+	}`, nil)["createMovie"].(map[string]interface{})["id"]
+	time.Sleep(5 * time.Millisecond)
+	do("bob", `mutation($id: ID!) { patchMovie(id: $id, patch: {title: "Alien 2"}) { changed_fields } }`,
+		map[string]interface{}{"id": id})
+
+	movie := do("", `query($id: ID!) {
+		movie(id: $id) { history { id mutation actor created_at before { title } after { title version } } }
+	}`, map[string]interface{}{"id": id})["movie"].(map[string]interface{})
+	history := movie["history"].([]interface{})
+	if len(history) != 2 {
+		t.Fatalf("expected 2 revisions, got %v", history)
+	}
+	patch, create := history[0].(map[string]interface{}), history[1].(map[string]interface{})
+	if patch["mutation"] != "patchMovie" || patch["actor"] != "bob" ||
+		fmt.Sprint(patch["before"], patch["after"]) != "map[title:Alien] map[title:Alien 2 version:2]" {
+		t.Fatalf("unexpected patch revision: %v", patch)
+	}
+	if create["mutation"] != "createMovie" || create["actor"] != "alice" || create["before"] != nil {
+		t.Fatalf("unexpected create revision: %v", create)
+	}
+
+	// Look the snapshot up by the revision's own time.
+	at := do("", `query($id: ID!, $at: DateTime!) { movieAt(id: $id, timestamp: $at) { title version } }`,
+		map[string]interface{}{"id": id, "at": create["created_at"]})
+	if fmt.Sprint(at) != "map[movieAt:map[title:Alien version:1]]" {
+		t.Fatalf("expected the original movie, got %v", at)
+	}
+
+	do("carol", `mutation($id: ID!) { deleteMovie(id: $id) }`, map[string]interface{}{"id": id})
+	reverted := do("carol", `mutation($id: ID!, $rev: ID!) {
+		revertMovie(id: $id, revision_id: $rev) { id title history { mutation actor } }
+	}`, map[string]interface{}{"id": id, "rev": create["id"]})["revertMovie"].(map[string]interface{})
+	if reverted["id"] != id || reverted["title"] != "Alien" {
+		t.Fatalf("expected the deleted movie to be restored as created, got %v", reverted)
+	}
+	if got := fmt.Sprint(reverted["history"]); !strings.HasPrefix(got, "[map[actor:carol mutation:revertMovie] map[actor:carol mutation:deleteMovie]") {
+		t.Fatalf("expected the delete and revert in history, got %s", got)
+	}
+}
+
+func TestMutationsRequireAuthentication(t *testing.T) {
+	schema, db := newTestSchema(t)
+
+	result := graphql.Do(graphql.Params{
+		Schema:        schema,
+		RequestString: `mutation { createMovie(input: {title: "Heat", year: 1995, rating: 8.3, duration: 170}) { id } }`,
+		Context:       context.Background(),
+	})
+	if len(result.Errors) != 1 || result.Errors[0].Extensions["code"] != "UNAUTHENTICATED" {
+		t.Fatalf("expected an anonymous mutation to be UNAUTHENTICATED, got %+v", result.Errors)
+	}
+	if n := countRows(t, db, "movies"); n != 0 {
+		t.Fatalf("expected nothing to be written, got %d movies", n)
+	}
+
+	result = graphql.Do(graphql.Params{
+		Schema:        schema,
+		RequestString: `{ movies { pagination { total } } }`,
+		Context:       context.Background(),
+	})
+	if len(result.Errors) != 0 {
+		t.Fatalf("expected anonymous queries to be allowed, got %v", result.Errors)
+	}
+}
+
+func TestRolePermissions(t *testing.T) {
+	schema, _ := newTestSchema(t)
+	var audit bytes.Buffer
+	auditLog.SetOutput(&audit)
+	t.Cleanup(func() { auditLog.SetOutput(os.Stderr) })
+
+	for typeName, fields := range map[string]graphql.FieldDefinitionMap{
+		"Query":    schema.QueryType().Fields(),
+		"Mutation": schema.MutationType().Fields(),
+	} {
+		for field := range fields {
+			if _, ok := permissions[typeName][field]; !ok {
+				t.Errorf("%s.%s has no entry in permissions", typeName, field)
+			}
+		}
+	}
+
+	as := func(subject string, role auth.Role, query string, vars map[string]interface{}) *graphql.Result {
+		ctx := context.Background()
+		if role != auth.RoleNone {
+			ctx = auth.WithPrincipal(ctx, &auth.Principal{Subject: subject, Method: auth.MethodJWT, Role: role})
+		}
+		return graphql.Do(graphql.Params{
+			Schema:         schema,
+			RequestString:  query,
+			VariableValues: vars,
+			Context:        WithVariables(ctx, vars),
+		})
+	}
+	code := func(result *graphql.Result) interface{} {
+		if len(result.Errors) != 1 {
+			return fmt.Sprint(result.Errors)
+		}
+		return result.Errors[0].Extensions["code"]
+	}
+
+	movieID := execute(t, schema, `mutation {
+		createMovie(input: {title: "Heat", year: 1995, rating: 8.3, duration: 170}) { id }
+	}`, nil)["createMovie"].(map[string]interface{})["id"]
+
+	createMovie := `mutation { createMovie(input: {title: "Alien", year: 1979, rating: 8.5, duration: 117}) { id } }`
+	if got := code(as("vera", auth.Viewer, createMovie, nil)); got != "FORBIDDEN" {
+		t.Fatalf("expected a viewer's createMovie to be FORBIDDEN, got %v", got)
+	}
+	if got := code(as("", auth.RoleNone, `{ trash { pagination { total } } }`, nil)); got != "UNAUTHENTICATED" {
+		t.Fatalf("expected an anonymous trash query to be UNAUTHENTICATED, got %v", got)
+	}
+
+	review := as("rita", auth.Reviewer, `mutation($id: ID!) {
+		createReview(input: {movie_id: $id, rating: 4}) { id }
+	}`, map[string]interface{}{"id": movieID})
+	if len(review.Errors) != 0 {
+		t.Fatalf("expected a reviewer to create a review, got %v", review.Errors)
+	}
+	vars := map[string]interface{}{"id": review.Data.(map[string]interface{})["createReview"].(map[string]interface{})["id"]}
+
+	deleteReview := `mutation($id: ID!) { deleteReview(id: $id) }`
+	if got := code(as("rob", auth.Reviewer, deleteReview, vars)); got != "FORBIDDEN" {
+		t.Fatalf("expected deleting another reviewer's review to be FORBIDDEN, got %v", got)
+	}
+	if !strings.Contains(audit.String(), `denied Mutation.deleteReview to "rob" (jwt, role reviewer)`) {
+		t.Fatalf("expected the denial to be audited, got %q", audit.String())
+	}
+	if result := as("rita", auth.Reviewer, deleteReview, vars); len(result.Errors) != 0 {
+		t.Fatalf("expected a reviewer to delete their own review, got %v", result.Errors)
+	}
+
+	purge := `mutation($before: DateTime!) { purgeTrash(before: $before) { movies actors reviews } }`
+	purgeVars := map[string]interface{}{"before": time.Now().Add(time.Minute).UTC().Format(time.RFC3339)}
+	if got := code(as("ed", auth.Editor, purge, purgeVars)); got != "FORBIDDEN" {
+		t.Fatalf("expected an editor's purgeTrash to be FORBIDDEN, got %v", got)
+	}
+	purged := execute(t, schema, purge, purgeVars)["purgeTrash"]
+	if got := fmt.Sprint(purged); got != "map[actors:0 movies:0 reviews:1]" {
+		t.Fatalf("expected the deleted review to be purged, got %s", got)
+	}
+}
+
+func TestTrashAndRestore(t *testing.T) {
+	schema, _ := newTestSchema(t)
+
+	id := execute(t, schema, `mutation {
+		createMovie(input: {title: "Alien", year: 1979, rating: 8.5, duration: 117}) { id }
+	}`, nil)["createMovie"].(map[string]interface{})["id"]
+	vars := map[string]interface{}{"id": id}
+	execute(t, schema, `mutation($id: ID!) { createReview(input: {movie_id: $id, rating: 5}) { id } }`, vars)
+	execute(t, schema, `mutation($id: ID!) { deleteMovie(id: $id) }`, vars)
+
+	trash := execute(t, schema, `{
+		trash(kinds: [MOVIE, REVIEW]) { items { kind id movie { title } review { id } } pagination { total } }
+	}`, nil)["trash"].(map[string]interface{})
+	if got := fmt.Sprint(trash); got != fmt.Sprintf("map[items:[map[id:%s kind:MOVIE movie:map[title:Alien] review:<nil>]] pagination:map[total:1]]", id) {
+		t.Fatalf("expected only the movie in the trash, got %s", got)
+	}
+
+	result := graphql.Do(graphql.Params{
+		Schema:         schema,
+		RequestString:  `mutation($id: ID!) { createReview(input: {movie_id: $id, rating: 1}) { id } }`,
+		VariableValues: vars,
+		Context:        testContext(),
+	})
+	if len(result.Errors) != 1 || result.Errors[0].Message != "movie not found" {
+		t.Fatalf("expected reviewing a deleted movie to fail, got %v", result.Errors)
+	}
+
+	restored := execute(t, schema, `mutation($id: ID!) {
+		restoreMovie(id: $id) { title reviews { user_name } history { mutation } }
+	}`, vars)["restoreMovie"]
+	if got := fmt.Sprint(restored); !strings.HasPrefix(got, "map[history:[map[mutation:restoreMovie] map[mutation:deleteMovie]") ||
+		!strings.HasSuffix(got, "reviews:[map[user_name:tester]] title:Alien]") {
+		t.Fatalf("expected the movie back with its review, got %s", got)
+	}
+
+	result = graphql.Do(graphql.Params{
+		Schema:         schema,
+		RequestString:  `mutation($id: ID!) { restoreMovie(id: $id) { id } }`,
+		VariableValues: vars,
+		Context:        testContext(),
+	})
+	if len(result.Errors) != 1 || result.Errors[0].Message != "movie not found in trash" {
+		t.Fatalf("expected restoring a live movie to fail, got %v", result.Errors)
+	}
+}
+
+func TestErrorCodes(t *testing.T) {
+	schema, db := newTestSchema(t)
+
+	do := func(query string) *graphql.Result {
+		t.Helper()
+		return graphql.Do(graphql.Params{Schema: schema, RequestString: query, Context: testContext()})
+	}
+
+	result := do(`{ movie(id: "missing") { id } }`)
+	if len(result.Errors) != 1 || result.Errors[0].Extensions["code"] != "NOT_FOUND" {
+		t.Fatalf("expected NOT_FOUND, got %+v", result.Errors)
+	}
+
+	result = do(`mutation { createMovieWithDetails(input: {
+		movie: {title: "Alien", year: 1979, rating: 8.5, duration: 117},
+		reviews: [{user_name: "alice", rating: 5}, {user_name: "bob", rating: 9}]
+	}) { id } }`)
+	if len(result.Errors) != 1 || result.Errors[0].Extensions["code"] != "VALIDATION_FAILED" {
+		t.Fatalf("expected VALIDATION_FAILED, got %+v", result.Errors)
+	}
+	fields := fmt.Sprint(result.Errors[0].Extensions["fields"])
+	if fields != "[{input.reviews[1].rating rating must be between 1 and 5}]" {
+		t.Fatalf("expected the invalid review's path, got %s", fields)
+	}
+
+	// graphql-go's own errors are coded by FormatError.
+	result = do(`{ movie(id: "1") { nope } }`)
+	if len(result.Errors) != 1 {
+		t.Fatalf("expected one error, got %+v", result.Errors)
+	}
+	if formatted := FormatError(result.Errors[0].OriginalError()); formatted.Extensions["code"] != "VALIDATION_FAILED" {
+		t.Fatalf("expected an unknown field to be VALIDATION_FAILED, got %+v", formatted)
+	}
+
+	// Anything else is masked, keeping driver text out of the response.
+	db.Close()
+	result = do(`{ movies { pagination { total } } }`)
+	if len(result.Errors) != 1 || result.Errors[0].Extensions["code"] != "INTERNAL" {
+		t.Fatalf("expected INTERNAL, got %+v", result.Errors)
+	}
+	id, _ := result.Errors[0].Extensions["correlation_id"].(string)
+	if msg := result.Errors[0].Message; id == "" || !strings.Contains(msg, id) || strings.Contains(msg, "sql") {
+		t.Fatalf("expected a masked message with the correlation id, got %q (id %q)", msg, id)
+	}
+}
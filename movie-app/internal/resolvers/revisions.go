@@ -0,0 +1,118 @@
+package resolvers
+
+import (
+	"context"
+	"movie-app/internal/apperr"
+	"movie-app/internal/auth"
+	"movie-app/internal/models"
+	"movie-app/internal/store"
+	"time"
+
+	"github.com/graphql-go/graphql"
+)
+
+// anonymousActor is recorded for changes made without a principal.
+const anonymousActor = "anonymous"
+
+// actorFor names who is making the request's changes in movie history: the
+// authenticated caller's subject.
+func actorFor(ctx context.Context) string {
+	if principal, ok := auth.PrincipalFrom(ctx); ok {
+		return principal.Subject
+	}
+	return anonymousActor
+}
+
+// recordRevision appends a change made by the current mutation to a
+// movie's history. Call it inside the mutation's transaction.
+func recordRevision(p graphql.ResolveParams, tx store.Store, movieID string, before, after *models.Movie) error {
+	_, err := tx.RecordMovieRevision(p.Context, models.MovieRevision{
+		MovieID:  movieID,
+		Mutation: p.Info.FieldName,
+		Actor:    actorFor(p.Context),
+		Before:   before,
+		After:    after,
+	})
+	return err
+}
+
+func (r *Resolver) resolveMovieHistory(p graphql.ResolveParams) (interface{}, error) {
+	movie, ok := movieFromSource(p.Source)
+	if !ok {
+		return []models.MovieRevision{}, nil
+	}
+	return r.loadersFor(p.Context).history.load(p.Context, movie.ID), nil
+}
+
+func (r *Resolver) GetMovieAt(p graphql.ResolveParams) (interface{}, error) {
+	id, _ := p.Args["id"].(string)
+	at, _ := p.Args["timestamp"].(time.Time)
+
+	movie, err := r.store.MovieAt(p.Context, id, at)
+	if err == store.ErrNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return movie, nil
+}
+
+// RevertMovie restores a movie's fields to how they were right after a
+// revision. A deleted movie is first restored from the trash or, once
+// purged, recreated with those fields under its old ID, without its cast
+// and reviews.
+func (r *Resolver) RevertMovie(p graphql.ResolveParams) (interface{}, error) {
+	id, _ := p.Args["id"].(string)
+	revisionID, _ := p.Args["revision_id"].(string)
+	expected, err := expectedVersionArg(p)
+	if err != nil {
+		return nil, err
+	}
+
+	var reverted *models.Movie
+	err = r.store.InTx(p.Context, func(tx store.Store) error {
+		revision, err := tx.GetMovieRevision(p.Context, revisionID)
+		if err == store.ErrNotFound || (err == nil && revision.MovieID != id) {
+			return apperr.NotFoundf("revision not found")
+		}
+		if err != nil {
+			return err
+		}
+		if revision.After == nil {
+			return apperr.Invalid("revision_id", "revision %s deleted the movie; revert to an earlier revision", revisionID)
+		}
+
+		current, err := tx.GetMovie(p.Context, id)
+		base := current
+		switch {
+		case err == store.ErrNotFound && expected != 0:
+			return apperr.NotFoundf("movie not found")
+		case err == store.ErrNotFound:
+			// Deleted: bring it back from the trash, or recreate it once purged.
+			base, err = tx.RestoreMovie(p.Context, id)
+			if err == store.ErrNotFound {
+				base, err = tx.CreateMovie(p.Context, *revision.After)
+			}
+		}
+		if err != nil {
+			return err
+		}
+
+		movie := *base
+		for _, field := range moviePatchFields {
+			field.set(&movie, field.get(revision.After))
+		}
+		if reverted, err = tx.UpdateMovie(p.Context, movie, expected); err != nil {
+			return err
+		}
+		return recordRevision(p, tx, id, current, reverted)
+	})
+	if err == store.ErrVersionConflict {
+		return nil, r.movieConflict(p, id, expected)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return reverted, nil
+}
@@ -0,0 +1,109 @@
+package resolvers
+
+import (
+	"time"
+
+	"movie-app/internal/apperr"
+	"movie-app/internal/models"
+	"movie-app/internal/store"
+
+	"github.com/graphql-go/graphql"
+)
+
+func (r *Resolver) GetTrash(p graphql.ResolveParams) (interface{}, error) {
+	page, limit, err := pageArgs(p)
+	if err != nil {
+		return nil, err
+	}
+
+	items, total, err := r.store.Trash(p.Context, stringsArg(p.Args, "kinds"), limit, (page-1)*limit)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.TrashResult{
+		Items:      items,
+		Pagination: newPagination(page, limit, total),
+	}, nil
+}
+
+// RestoreMovie brings a movie back from the trash with its cast and the
+// reviews deleted along with it, recording the restore in its history.
+func (r *Resolver) RestoreMovie(p graphql.ResolveParams) (interface{}, error) {
+	id, ok := p.Args["id"].(string)
+	if !ok {
+		return nil, apperr.Invalid("id", "id is required")
+	}
+
+	var restored *models.Movie
+	err := r.store.InTx(p.Context, func(tx store.Store) error {
+		var err error
+		restored, err = tx.RestoreMovie(p.Context, id)
+		if err == store.ErrNotFound {
+			return apperr.NotFoundf("movie not found in trash")
+		}
+		if err != nil {
+			return err
+		}
+		return recordRevision(p, tx, id, nil, restored)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return restored, nil
+}
+
+func (r *Resolver) DeleteActor(p graphql.ResolveParams) (interface{}, error) {
+	id, ok := p.Args["id"].(string)
+	if !ok {
+		return nil, apperr.Invalid("id", "id is required")
+	}
+
+	return r.store.DeleteActor(p.Context, id)
+}
+
+func (r *Resolver) RestoreActor(p graphql.ResolveParams) (interface{}, error) {
+	id, ok := p.Args["id"].(string)
+	if !ok {
+		return nil, apperr.Invalid("id", "id is required")
+	}
+
+	actor, err := r.store.RestoreActor(p.Context, id)
+	if err == store.ErrNotFound {
+		return nil, apperr.NotFoundf("actor not found in trash")
+	}
+	return actor, err
+}
+
+func (r *Resolver) RestoreReview(p graphql.ResolveParams) (interface{}, error) {
+	id, ok := p.Args["id"].(string)
+	if !ok {
+		return nil, apperr.Invalid("id", "id is required")
+	}
+
+	review, err := r.store.RestoreReview(p.Context, id)
+	if err == store.ErrNotFound {
+		return nil, apperr.NotFoundf("review not found in trash, or its movie is deleted; restore the movie first")
+	}
+	if err == store.ErrDuplicate {
+		return nil, apperr.Conflictf(nil, "the author of review %s has reviewed its movie again since it was deleted", id)
+	}
+	return review, err
+}
+
+// PurgeTrash permanently removes what was deleted before the given time, as
+// the server's scheduled purge does.
+func (r *Resolver) PurgeTrash(p graphql.ResolveParams) (interface{}, error) {
+	before, _ := p.Args["before"].(time.Time)
+
+	var counts store.PurgeCounts
+	err := r.store.InTx(p.Context, func(tx store.Store) error {
+		var err error
+		counts, err = tx.PurgeDeleted(p.Context, before)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return counts, nil
+}
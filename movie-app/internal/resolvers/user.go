@@ -0,0 +1,109 @@
+package resolvers
+
+import (
+	"movie-app/internal/apperr"
+	"movie-app/internal/auth"
+	"movie-app/internal/models"
+	"movie-app/internal/store"
+
+	"github.com/graphql-go/graphql"
+)
+
+// profileRules covers ProfileInput.
+var profileRules = []fieldRule{
+	{"name", []check{notBlank, maxLength(100)}},
+	{"bio", []check{maxLength(2000)}},
+}
+
+// currentUser returns the caller's user, creating it on first use. Anonymous
+// callers have none.
+func (r *Resolver) currentUser(p graphql.ResolveParams) (*models.User, error) {
+	principal, ok := auth.PrincipalFrom(p.Context)
+	if !ok {
+		return nil, apperr.New(apperr.Unauthenticated, "%s requires authentication", p.Info.FieldName)
+	}
+	return r.store.EnsureUser(p.Context, principal.Subject)
+}
+
+func (r *Resolver) GetMe(p graphql.ResolveParams) (interface{}, error) {
+	if _, ok := auth.PrincipalFrom(p.Context); !ok {
+		return nil, nil
+	}
+	return r.currentUser(p)
+}
+
+func (r *Resolver) UpdateProfile(p graphql.ResolveParams) (interface{}, error) {
+	input, ok := p.Args["input"].(map[string]interface{})
+	if !ok {
+		return nil, apperr.Invalid("input", "input is required")
+	}
+	if err := validationError(validate("input", input, profileRules)); err != nil {
+		return nil, err
+	}
+
+	user, err := r.currentUser(p)
+	if err != nil {
+		return nil, err
+	}
+	user.Name = input["name"].(string)
+	user.Bio = stringArg(input, "bio")
+	user.AvatarURL = stringArg(input, "avatar_url")
+	return r.store.UpdateUser(p.Context, *user)
+}
+
+func (r *Resolver) resolveReviewUser(p graphql.ResolveParams) (interface{}, error) {
+	review, ok := reviewFromSource(p.Source)
+	if !ok {
+		return nil, nil
+	}
+	return r.loadersFor(p.Context).users.load(p.Context, review.UserID), nil
+}
+
+func (r *Resolver) resolveUserReviews(p graphql.ResolveParams) (interface{}, error) {
+	user, ok := userFromSource(p.Source)
+	if !ok {
+		return []models.Review{}, nil
+	}
+	return r.loadersFor(p.Context).userReviews.load(p.Context, user.ID), nil
+}
+
+// reviewOwner is the subject that wrote the review named by the id argument.
+// Missing reviews and placeholder users have no owner.
+func (r *Resolver) reviewOwner(p graphql.ResolveParams) (string, error) {
+	id, _ := p.Args["id"].(string)
+	review, err := r.store.GetReview(p.Context, id)
+	if err == store.ErrNotFound {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	user, err := r.store.GetUser(p.Context, review.UserID)
+	if err == store.ErrNotFound {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	return user.Subject, nil
+}
+
+func reviewFromSource(source interface{}) (*models.Review, bool) {
+	switch review := source.(type) {
+	case *models.Review:
+		return review, true
+	case models.Review:
+		return &review, true
+	}
+	return nil, false
+}
+
+func userFromSource(source interface{}) (*models.User, bool) {
+	switch user := source.(type) {
+	case *models.User:
+		return user, true
+	case models.User:
+		return &user, true
+	}
+	return nil, false
+}
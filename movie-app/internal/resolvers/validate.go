@@ -0,0 +1,144 @@
+package resolvers
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"movie-app/internal/apperr"
+)
+
+// check validates one input value, returning what is wrong with it or ""
+// when it is valid.
+type check func(value interface{}) string
+
+// fieldRule lists the checks of one input field. Omitted and null fields
+// are not checked; GraphQL nullability already rejects missing required
+// fields, and applyMoviePatch rejects nulls that cannot be cleared. The
+// Year, Date and URL scalars check their format before rules run.
+type fieldRule struct {
+	name   string
+	checks []check
+}
+
+// movieRules covers MovieInput and MoviePatch.
+var movieRules = []fieldRule{
+	{"title", []check{notBlank, maxLength(200)}},
+	{"description", []check{maxLength(5000)}},
+	{"rating", []check{floatBetween(0, 10)}},
+	{"duration", []check{positive}},
+}
+
+// actorRules covers ActorInput and the arguments of createActor.
+var actorRules = []fieldRule{
+	{"name", []check{notBlank, maxLength(200)}},
+	{"birth_date", []check{notFuture}},
+	{"biography", []check{maxLength(5000)}},
+}
+
+// reviewRules covers ReviewInput and ReviewCreateInput; only the latter has
+// a user_name.
+var reviewRules = []fieldRule{
+	{"user_name", []check{notBlank, maxLength(100)}},
+	{"rating", []check{intBetween(1, 5)}},
+	{"comment", []check{maxLength(5000)}},
+}
+
+// validate checks the fields of input against rules and returns every
+// violation, with field paths under path ("input", "input.actors[2]").
+func validate(path string, input map[string]interface{}, rules []fieldRule) []apperr.FieldError {
+	var violations []apperr.FieldError
+	for _, rule := range rules {
+		value, ok := input[rule.name]
+		if !ok || value == nil {
+			continue
+		}
+		for _, c := range rule.checks {
+			if problem := c(value); problem != "" {
+				violations = append(violations, apperr.FieldError{
+					Field:   joinPath(path, rule.name),
+					Message: rule.name + " " + problem,
+				})
+				break
+			}
+		}
+	}
+	return violations
+}
+
+// validateList validates each element of the [Input!] list input, with
+// paths such as "input.reviews[1]".
+func validateList(path string, inputs []interface{}, rules []fieldRule) []apperr.FieldError {
+	var violations []apperr.FieldError
+	for i, input := range inputs {
+		fields, _ := input.(map[string]interface{})
+		violations = append(violations, validate(fmt.Sprintf("%s[%d]", path, i), fields, rules)...)
+	}
+	return violations
+}
+
+// validationError reports violations as one VALIDATION_FAILED error, or
+// returns nil when there are none.
+func validationError(violations []apperr.FieldError) error {
+	if len(violations) == 0 {
+		return nil
+	}
+	return apperr.Validation(violations)
+}
+
+func joinPath(path, field string) string {
+	if path == "" {
+		return field
+	}
+	return path + "." + field
+}
+
+func notBlank(value interface{}) string {
+	if s, _ := value.(string); strings.TrimSpace(s) == "" {
+		return "must not be blank"
+	}
+	return ""
+}
+
+func maxLength(n int) check {
+	return func(value interface{}) string {
+		if s, _ := value.(string); len([]rune(s)) > n {
+			return fmt.Sprintf("must be at most %d characters", n)
+		}
+		return ""
+	}
+}
+
+func intBetween(min, max int) check {
+	return func(value interface{}) string {
+		if n, _ := value.(int); n < min || n > max {
+			return fmt.Sprintf("must be between %d and %d", min, max)
+		}
+		return ""
+	}
+}
+
+func floatBetween(min, max float64) check {
+	return func(value interface{}) string {
+		if f, _ := value.(float64); f < min || f > max {
+			return fmt.Sprintf("must be between %g and %g", min, max)
+		}
+		return ""
+	}
+}
+
+func positive(value interface{}) string {
+	if n, _ := value.(int); n <= 0 {
+		return "must be greater than 0"
+	}
+	return ""
+}
+
+// notFuture checks a Date, which the scalar has already parsed.
+func notFuture(value interface{}) string {
+	s, _ := value.(string)
+	if date, err := time.Parse(time.DateOnly, s); err == nil && date.After(time.Now()) {
+		return "must not be in the future"
+	}
+	return ""
+}
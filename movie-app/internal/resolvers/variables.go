@@ -0,0 +1,55 @@
+package resolvers
+
+import (
+	"context"
+
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/language/ast"
+)
+
+type variablesKey struct{}
+
+// WithVariables attaches a request's variables as the client sent them.
+// graphql-go drops input fields set to null while coercing variables (and
+// its parser has no null literal), so resolvers that treat an explicit null
+// differently from a missing field read the nulls from here.
+func WithVariables(ctx context.Context, variables map[string]interface{}) context.Context {
+	return context.WithValue(ctx, variablesKey{}, variables)
+}
+
+// nullFields returns the fields of the input object argument arg that the
+// client explicitly set to null, either in a variable holding the whole
+// object or in a variable used for one of its fields.
+func nullFields(p graphql.ResolveParams, arg string) map[string]bool {
+	nulls := map[string]bool{}
+	variables, _ := p.Context.Value(variablesKey{}).(map[string]interface{})
+	if len(variables) == 0 || len(p.Info.FieldASTs) == 0 {
+		return nulls
+	}
+
+	for _, a := range p.Info.FieldASTs[0].Arguments {
+		if a.Name == nil || a.Name.Value != arg {
+			continue
+		}
+		switch value := a.Value.(type) {
+		case *ast.Variable:
+			object, _ := variables[value.Name.Value].(map[string]interface{})
+			for name, v := range object {
+				if v == nil {
+					nulls[name] = true
+				}
+			}
+		case *ast.ObjectValue:
+			for _, field := range value.Fields {
+				variable, ok := field.Value.(*ast.Variable)
+				if !ok {
+					continue
+				}
+				if v, sent := variables[variable.Name.Value]; sent && v == nil {
+					nulls[field.Name.Value] = true
+				}
+			}
+		}
+	}
+	return nulls
+}
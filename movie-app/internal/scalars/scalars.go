@@ -0,0 +1,162 @@
+// Package scalars implements the custom GraphQL scalars declared in
+// schema.graphql. Each rejects malformed input when it is parsed, so
+// resolvers receive values that are already checked: a time.Time for
+// DateTime, a "YYYY-MM-DD" string for Date, an absolute http(s) string for
+// URL and a bounded int for Year.
+package scalars
+
+import (
+	"fmt"
+	"math"
+	"net/url"
+	"time"
+
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/language/ast"
+)
+
+// DateTimeLayout is the RFC 3339 layout DateTime values are written in,
+// always in UTC with millisecond precision.
+const DateTimeLayout = "2006-01-02T15:04:05.000Z07:00"
+
+// MinYear is the year of the oldest surviving film. Years up to ten years
+// ahead are accepted for announced releases.
+const MinYear = 1888
+
+// MaxYear returns the latest Year accepted as input.
+func MaxYear() int {
+	return time.Now().Year() + 10
+}
+
+// All maps each scalar to its name in the SDL, for schema.Config.Scalars.
+var All = map[string]*graphql.Scalar{
+	"DateTime": DateTime,
+	"Date":     Date,
+	"URL":      URL,
+	"Year":     Year,
+}
+
+var DateTime = graphql.NewScalar(graphql.ScalarConfig{
+	Name:        "DateTime",
+	Description: "An RFC 3339 date-time, such as 2024-05-01T12:00:00.000Z. Output is always UTC.",
+	Serialize: func(value interface{}) interface{} {
+		switch t := value.(type) {
+		case time.Time:
+			return t.UTC().Format(DateTimeLayout)
+		case *time.Time:
+			if t == nil {
+				return nil
+			}
+			return t.UTC().Format(DateTimeLayout)
+		}
+		return nil
+	},
+	ParseValue:   func(value interface{}) interface{} { return parseDateTime(value) },
+	ParseLiteral: func(value ast.Value) interface{} { return parseDateTime(stringLiteral(value)) },
+})
+
+var Date = graphql.NewScalar(graphql.ScalarConfig{
+	Name:        "Date",
+	Description: "A calendar date in ISO 8601 YYYY-MM-DD format.",
+	Serialize: func(value interface{}) interface{} {
+		switch d := value.(type) {
+		case time.Time:
+			return d.Format(time.DateOnly)
+		case string:
+			return parseDate(d)
+		}
+		return nil
+	},
+	ParseValue:   func(value interface{}) interface{} { return parseDate(value) },
+	ParseLiteral: func(value ast.Value) interface{} { return parseDate(stringLiteral(value)) },
+})
+
+var URL = graphql.NewScalar(graphql.ScalarConfig{
+	Name:        "URL",
+	Description: "An absolute http or https URL.",
+	Serialize: func(value interface{}) interface{} {
+		return parseURL(value)
+	},
+	ParseValue:   func(value interface{}) interface{} { return parseURL(value) },
+	ParseLiteral: func(value ast.Value) interface{} { return parseURL(stringLiteral(value)) },
+})
+
+var Year = graphql.NewScalar(graphql.ScalarConfig{
+	Name:        "Year",
+	Description: fmt.Sprintf("A release year from %d to ten years ahead.", MinYear),
+	// Stored years are written as they are; the bounds apply to input.
+	Serialize:  graphql.Int.Serialize,
+	ParseValue: func(value interface{}) interface{} { return parseYear(value) },
+	ParseLiteral: func(value ast.Value) interface{} {
+		if v, ok := value.(*ast.IntValue); ok {
+			return parseYear(graphql.Int.ParseLiteral(v))
+		}
+		return nil
+	},
+})
+
+// Parsers return nil for an invalid value, which graphql-go reports as a
+// validation error naming the argument and the expected scalar.
+
+func parseDateTime(value interface{}) interface{} {
+	s, ok := value.(string)
+	if !ok {
+		return nil
+	}
+	t, err := time.Parse(time.RFC3339Nano, s)
+	if err != nil {
+		return nil
+	}
+	return t.UTC()
+}
+
+func parseDate(value interface{}) interface{} {
+	s, ok := value.(string)
+	if !ok {
+		return nil
+	}
+	d, err := time.Parse(time.DateOnly, s)
+	if err != nil {
+		return nil
+	}
+	return d.Format(time.DateOnly)
+}
+
+func parseURL(value interface{}) interface{} {
+	s, ok := value.(string)
+	if !ok {
+		return nil
+	}
+	u, err := url.Parse(s)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil
+	}
+	return s
+}
+
+// parseYear accepts an int, or the float64 a JSON-decoded variable holds.
+func parseYear(value interface{}) interface{} {
+	var year int
+	switch v := value.(type) {
+	case int:
+		year = v
+	case float64:
+		if v != math.Trunc(v) {
+			return nil
+		}
+		year = int(v)
+	default:
+		return nil
+	}
+	if year < MinYear || year > MaxYear() {
+		return nil
+	}
+	return year
+}
+
+func stringLiteral(value ast.Value) interface{} {
+	if v, ok := value.(*ast.StringValue); ok {
+		return v.Value
+	}
+	return nil
+}
@@ -0,0 +1,422 @@
+package schema
+
+import (
+	_ "embed"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/language/ast"
+	"github.com/graphql-go/graphql/language/parser"
+)
+
+// SDL is the schema definition served by the API.
+//
+//go:embed schema.graphql
+var SDL string
+
+// Resolvers maps a GraphQL type name to its field resolvers, e.g.
+// Resolvers{"Query": {"movie": GetMovie}}.
+type Resolvers map[string]map[string]graphql.FieldResolveFn
+
+// Models maps an object type name to a value of the Go type its resolvers
+// return. Fields without an explicit resolver are read from the struct field
+// whose json tag (or name) matches the GraphQL field.
+type Models map[string]interface{}
+
+type Config struct {
+	Resolvers Resolvers
+	Models    Models
+	Scalars   map[string]*graphql.Scalar
+}
+
+// BindingError lists every mismatch between the SDL and the Go bindings.
+type BindingError struct {
+	Problems []string
+}
+
+func (e *BindingError) Error() string {
+	return fmt.Sprintf("schema binding failed with %d problem(s):\n  %s",
+		len(e.Problems), strings.Join(e.Problems, "\n  "))
+}
+
+var builtinScalars = map[string]*graphql.Scalar{
+	"ID":      graphql.ID,
+	"String":  graphql.String,
+	"Int":     graphql.Int,
+	"Float":   graphql.Float,
+	"Boolean": graphql.Boolean,
+}
+
+type builder struct {
+	cfg      Config
+	defs     map[string]ast.Node
+	types    map[string]graphql.Type
+	problems []string
+}
+
+// Build parses sdl and binds cfg to it, returning an executable schema.
+// Declared fields that cannot be resolved and resolvers that target
+// undeclared fields are all reported together in a *BindingError.
+func Build(sdl string, cfg Config) (graphql.Schema, error) {
+	doc, err := parser.Parse(parser.ParseParams{Source: sdl})
+	if err != nil {
+		return graphql.Schema{}, fmt.Errorf("failed to parse schema: %v", err)
+	}
+
+	b := &builder{
+		cfg:   cfg,
+		defs:  map[string]ast.Node{},
+		types: map[string]graphql.Type{},
+	}
+
+	for _, def := range doc.Definitions {
+		name := definitionName(def)
+		if name == "" {
+			b.problemf("unsupported definition %T", def)
+			continue
+		}
+		if _, dup := b.defs[name]; dup {
+			b.problemf("type %s is declared more than once", name)
+			continue
+		}
+		b.defs[name] = def
+	}
+
+	for _, name := range sortedKeys(b.defs) {
+		b.namedType(name)
+	}
+	b.checkBindings()
+
+	if len(b.problems) > 0 {
+		sort.Strings(b.problems)
+		return graphql.Schema{}, &BindingError{Problems: b.problems}
+	}
+
+	query, _ := b.types["Query"].(*graphql.Object)
+	if query == nil {
+		return graphql.Schema{}, fmt.Errorf("schema does not declare a Query type")
+	}
+	mutation, _ := b.types["Mutation"].(*graphql.Object)
+
+	var types []graphql.Type
+	for _, name := range sortedKeys(b.defs) {
+		types = append(types, b.types[name])
+	}
+
+	return graphql.NewSchema(graphql.SchemaConfig{
+		Query:    query,
+		Mutation: mutation,
+		Types:    types,
+	})
+}
+
+func (b *builder) problemf(format string, args ...interface{}) {
+	b.problems = append(b.problems, fmt.Sprintf(format, args...))
+}
+
+func (b *builder) namedType(name string) graphql.Type {
+	if t, ok := b.types[name]; ok {
+		return t
+	}
+	if s, ok := builtinScalars[name]; ok {
+		return s
+	}
+
+	def, ok := b.defs[name]
+	if !ok {
+		b.problemf("unknown type %s", name)
+		return nil
+	}
+
+	switch def := def.(type) {
+	case *ast.ObjectDefinition:
+		b.types[name] = b.object(def)
+	case *ast.InputObjectDefinition:
+		b.types[name] = b.inputObject(def)
+	case *ast.EnumDefinition:
+		b.types[name] = b.enum(def)
+	case *ast.ScalarDefinition:
+		scalar, ok := b.cfg.Scalars[name]
+		if !ok {
+			b.problemf("scalar %s has no Go implementation", name)
+			scalar = graphql.String
+		}
+		b.types[name] = scalar
+	default:
+		b.problemf("type %s: unsupported definition %T", name, def)
+		return nil
+	}
+	return b.types[name]
+}
+
+func (b *builder) typeRef(t ast.Type) graphql.Type {
+	switch t := t.(type) {
+	case *ast.NonNull:
+		inner := b.typeRef(t.Type)
+		if inner == nil {
+			return nil
+		}
+		return graphql.NewNonNull(inner)
+	case *ast.List:
+		inner := b.typeRef(t.Type)
+		if inner == nil {
+			return nil
+		}
+		return graphql.NewList(inner)
+	case *ast.Named:
+		return b.namedType(t.Name.Value)
+	}
+	return nil
+}
+
+// checkRef reports references to undeclared types up front, since field
+// types themselves are only built once the schema is assembled.
+func (b *builder) checkRef(t ast.Type, where string) {
+	switch t := t.(type) {
+	case *ast.NonNull:
+		b.checkRef(t.Type, where)
+	case *ast.List:
+		b.checkRef(t.Type, where)
+	case *ast.Named:
+		if _, ok := builtinScalars[t.Name.Value]; ok {
+			return
+		}
+		if _, ok := b.defs[t.Name.Value]; !ok {
+			b.problemf("%s references unknown type %s", where, t.Name.Value)
+		}
+	}
+}
+
+func (b *builder) object(def *ast.ObjectDefinition) *graphql.Object {
+	typeName := def.Name.Value
+	resolvers := b.cfg.Resolvers[typeName]
+	model := b.cfg.Models[typeName]
+
+	fields := graphql.Fields{}
+	for _, f := range def.Fields {
+		b.checkRef(f.Type, typeName+"."+f.Name.Value)
+		field := &graphql.Field{
+			Name:              f.Name.Value,
+			Description:       description(f.Description),
+			DeprecationReason: deprecationReason(f.Directives),
+			Args:              graphql.FieldConfigArgument{},
+		}
+		for _, arg := range f.Arguments {
+			b.checkRef(arg.Type, typeName+"."+f.Name.Value+"("+arg.Name.Value+")")
+			field.Args[arg.Name.Value] = &graphql.ArgumentConfig{
+				Description:  description(arg.Description),
+				DefaultValue: valueFromAST(arg.DefaultValue),
+			}
+		}
+
+		if resolve, ok := resolvers[f.Name.Value]; ok {
+			field.Resolve = resolve
+		} else if !modelHasField(model, f.Name.Value) {
+			if model == nil {
+				b.problemf("%s.%s has no resolver", typeName, f.Name.Value)
+			} else {
+				b.problemf("%s.%s has no resolver and %T has no matching field", typeName, f.Name.Value, model)
+			}
+		}
+		fields[f.Name.Value] = field
+	}
+
+	// Field and argument types are resolved lazily so types may reference
+	// each other regardless of declaration order.
+	return graphql.NewObject(graphql.ObjectConfig{
+		Name:        typeName,
+		Description: description(def.Description),
+		Fields: graphql.FieldsThunk(func() graphql.Fields {
+			for _, f := range def.Fields {
+				field := fields[f.Name.Value]
+				field.Type = b.typeRef(f.Type)
+				for _, arg := range f.Arguments {
+					field.Args[arg.Name.Value].Type = b.typeRef(arg.Type)
+				}
+			}
+			return fields
+		}),
+	})
+}
+
+func (b *builder) inputObject(def *ast.InputObjectDefinition) *graphql.InputObject {
+	for _, f := range def.Fields {
+		b.checkRef(f.Type, def.Name.Value+"."+f.Name.Value)
+	}
+	return graphql.NewInputObject(graphql.InputObjectConfig{
+		Name:        def.Name.Value,
+		Description: description(def.Description),
+		Fields: graphql.InputObjectConfigFieldMapThunk(func() graphql.InputObjectConfigFieldMap {
+			fields := graphql.InputObjectConfigFieldMap{}
+			for _, f := range def.Fields {
+				fields[f.Name.Value] = &graphql.InputObjectFieldConfig{
+					Type:         b.typeRef(f.Type),
+					Description:  description(f.Description),
+					DefaultValue: valueFromAST(f.DefaultValue),
+				}
+			}
+			return fields
+		}),
+	})
+}
+
+func (b *builder) enum(def *ast.EnumDefinition) *graphql.Enum {
+	values := graphql.EnumValueConfigMap{}
+	for _, v := range def.Values {
+		values[v.Name.Value] = &graphql.EnumValueConfig{
+			Value:             v.Name.Value,
+			Description:       description(v.Description),
+			DeprecationReason: deprecationReason(v.Directives),
+		}
+	}
+	return graphql.NewEnum(graphql.EnumConfig{
+		Name:        def.Name.Value,
+		Description: description(def.Description),
+		Values:      values,
+	})
+}
+
+// checkBindings reports resolvers and models that do not correspond to
+// anything declared in the SDL.
+func (b *builder) checkBindings() {
+	for typeName, fields := range b.cfg.Resolvers {
+		def, ok := b.defs[typeName].(*ast.ObjectDefinition)
+		if !ok {
+			for fieldName := range fields {
+				b.problemf("resolver %s.%s targets undeclared type %s", typeName, fieldName, typeName)
+			}
+			continue
+		}
+		declared := map[string]bool{}
+		for _, f := range def.Fields {
+			declared[f.Name.Value] = true
+		}
+		for fieldName := range fields {
+			if !declared[fieldName] {
+				b.problemf("resolver %s.%s targets an undeclared field", typeName, fieldName)
+			}
+		}
+	}
+	for typeName := range b.cfg.Models {
+		if _, ok := b.defs[typeName].(*ast.ObjectDefinition); !ok {
+			b.problemf("model bound to undeclared type %s", typeName)
+		}
+	}
+	for name := range b.cfg.Scalars {
+		if _, ok := b.defs[name].(*ast.ScalarDefinition); !ok {
+			b.problemf("scalar %s is not declared", name)
+		}
+	}
+}
+
+func modelHasField(model interface{}, name string) bool {
+	if model == nil {
+		return false
+	}
+	t := reflect.TypeOf(model)
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return false
+	}
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if strings.EqualFold(f.Name, name) {
+			return true
+		}
+		if tag := strings.Split(f.Tag.Get("json"), ",")[0]; tag == name {
+			return true
+		}
+	}
+	return false
+}
+
+func definitionName(def ast.Node) string {
+	switch def := def.(type) {
+	case *ast.ObjectDefinition:
+		return def.Name.Value
+	case *ast.InputObjectDefinition:
+		return def.Name.Value
+	case *ast.EnumDefinition:
+		return def.Name.Value
+	case *ast.ScalarDefinition:
+		return def.Name.Value
+	case *ast.InterfaceDefinition:
+		return def.Name.Value
+	case *ast.UnionDefinition:
+		return def.Name.Value
+	}
+	return ""
+}
+
+func description(s *ast.StringValue) string {
+	if s == nil {
+		return ""
+	}
+	return s.Value
+}
+
+func deprecationReason(directives []*ast.Directive) string {
+	for _, d := range directives {
+		if d.Name.Value != "deprecated" {
+			continue
+		}
+		for _, arg := range d.Arguments {
+			if arg.Name.Value == "reason" {
+				if reason, ok := valueFromAST(arg.Value).(string); ok {
+					return reason
+				}
+			}
+		}
+		return graphql.DefaultDeprecationReason
+	}
+	return ""
+}
+
+// valueFromAST converts a literal default value into the Go value graphql-go
+// expects for arguments and input fields.
+func valueFromAST(v ast.Value) interface{} {
+	switch v := v.(type) {
+	case nil:
+		return nil
+	case *ast.IntValue:
+		n, _ := strconv.Atoi(v.Value)
+		return n
+	case *ast.FloatValue:
+		f, _ := strconv.ParseFloat(v.Value, 64)
+		return f
+	case *ast.StringValue:
+		return v.Value
+	case *ast.BooleanValue:
+		return v.Value
+	case *ast.EnumValue:
+		return v.Value
+	case *ast.ListValue:
+		list := make([]interface{}, 0, len(v.Values))
+		for _, item := range v.Values {
+			list = append(list, valueFromAST(item))
+		}
+		return list
+	case *ast.ObjectValue:
+		obj := map[string]interface{}{}
+		for _, f := range v.Fields {
+			obj[f.Name.Value] = valueFromAST(f.Value)
+		}
+		return obj
+	}
+	return nil
+}
+
+func sortedKeys(m map[string]ast.Node) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
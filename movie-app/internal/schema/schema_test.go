@@ -0,0 +1,105 @@
+package schema
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/graphql-go/graphql"
+)
+
+const testSDL = `
+type Book {
+  id: ID!
+  title: String!
+  author: Author
+}
+
+type Author {
+  name: String!
+  books: [Book!]!
+}
+
+type Query {
+  book(id: ID!): Book
+}
+`
+
+type book struct {
+	ID    string `json:"id"`
+	Title string `json:"title"`
+}
+
+func TestBuildBindsResolversAndModels(t *testing.T) {
+	s, err := Build(testSDL, Config{
+		Resolvers: Resolvers{
+			"Query": {
+				"book": func(p graphql.ResolveParams) (interface{}, error) {
+					return &book{ID: p.Args["id"].(string), Title: "Dune"}, nil
+				},
+			},
+			"Book": {
+				"author": func(p graphql.ResolveParams) (interface{}, error) {
+					return map[string]interface{}{"name": "Frank Herbert"}, nil
+				},
+			},
+			"Author": {
+				"name":  func(p graphql.ResolveParams) (interface{}, error) { return "Frank Herbert", nil },
+				"books": func(p graphql.ResolveParams) (interface{}, error) { return []book{}, nil },
+			},
+		},
+		Models: Models{"Book": book{}},
+	})
+	if err != nil {
+		t.Fatalf("failed to build schema: %v", err)
+	}
+
+	result := graphql.Do(graphql.Params{
+		Schema:        s,
+		RequestString: `{ book(id: "b1") { id title author { name } } }`,
+	})
+	if len(result.Errors) > 0 {
+		t.Fatalf("unexpected errors: %v", result.Errors)
+	}
+
+	got := result.Data.(map[string]interface{})["book"].(map[string]interface{})
+	if got["id"] != "b1" || got["title"] != "Dune" {
+		t.Fatalf("unexpected book: %v", got)
+	}
+}
+
+func TestBuildReportsEveryBindingProblem(t *testing.T) {
+	_, err := Build(testSDL, Config{
+		Resolvers: Resolvers{
+			"Query": {
+				"book":  func(p graphql.ResolveParams) (interface{}, error) { return nil, nil },
+				"books": func(p graphql.ResolveParams) (interface{}, error) { return nil, nil },
+			},
+			"Publisher": {
+				"name": func(p graphql.ResolveParams) (interface{}, error) { return nil, nil },
+			},
+		},
+		Models: Models{"Book": book{}},
+	})
+
+	var bindErr *BindingError
+	if !errors.As(err, &bindErr) {
+		t.Fatalf("expected *BindingError, got %v", err)
+	}
+
+	want := []string{
+		"Book.author has no resolver and schema.book has no matching field",
+		"Author.name has no resolver",
+		"Author.books has no resolver",
+		"resolver Query.books targets an undeclared field",
+		"resolver Publisher.name targets undeclared type Publisher",
+	}
+	for _, w := range want {
+		if !strings.Contains(err.Error(), w) {
+			t.Errorf("expected report to contain %q, got:\n%v", w, err)
+		}
+	}
+	if len(bindErr.Problems) != len(want) {
+		t.Errorf("expected %d problems, got %d:\n%v", len(want), len(bindErr.Problems), err)
+	}
+}
@@ -0,0 +1,744 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"movie-app/internal/models"
+
+	"github.com/google/uuid"
+	"github.com/mattn/go-sqlite3"
+)
+
+// SQLiteStore implements Store on top of the tables created by the
+// database package.
+type SQLiteStore struct {
+	db DBTX
+}
+
+func NewSQLiteStore(db DBTX) *SQLiteStore {
+	return &SQLiteStore{db: db}
+}
+
+type txBeginner interface {
+	BeginTx(ctx context.Context, opts *sql.TxOptions) (*sql.Tx, error)
+}
+
+func (s *SQLiteStore) InTx(ctx context.Context, fn func(Store) error) error {
+	beginner, ok := s.db.(txBeginner)
+	if !ok {
+		// Already inside a transaction (or a DBTX that cannot start one):
+		// run in the caller's scope.
+		return fn(s)
+	}
+
+	tx, err := beginner.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	if err := fn(NewSQLiteStore(tx)); err != nil {
+		tx.Rollback()
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %v", err)
+	}
+	return nil
+}
+
+const movieColumns = `id, title, description, year, rating, duration, genre, director, poster_url, created_at, updated_at, version`
+
+// prefixedMovieColumns qualifies movieColumns with a table alias for joins.
+func prefixedMovieColumns(alias string) string {
+	columns := strings.Split(movieColumns, ", ")
+	for i, c := range columns {
+		columns[i] = alias + "." + c
+	}
+	return strings.Join(columns, ", ")
+}
+
+type scanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// prefixScanner scans leading join columns into prefix before handing the
+// remaining columns to a scanner such as scanMovie.
+type prefixScanner struct {
+	row    scanner
+	prefix []interface{}
+}
+
+func (p prefixScanner) Scan(dest ...interface{}) error {
+	return p.row.Scan(append(p.prefix, dest...)...)
+}
+
+func scanMovie(row scanner) (*models.Movie, error) {
+	var movie models.Movie
+	var description, genre, director, posterURL sql.NullString
+	err := row.Scan(
+		&movie.ID, &movie.Title, &description, &movie.Year, &movie.Rating,
+		&movie.Duration, &genre, &director, &posterURL,
+		&movie.CreatedAt, &movie.UpdatedAt, &movie.Version,
+	)
+	if err != nil {
+		return nil, err
+	}
+	movie.Description = description.String
+	movie.Genre = genre.String
+	movie.Director = director.String
+	movie.PosterURL = posterURL.String
+	return &movie, nil
+}
+
+func scanMovies(rows *sql.Rows) ([]models.Movie, error) {
+	defer rows.Close()
+
+	movies := []models.Movie{}
+	for rows.Next() {
+		movie, err := scanMovie(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan movie: %v", err)
+		}
+		movies = append(movies, *movie)
+	}
+	return movies, rows.Err()
+}
+
+func scanActor(row scanner) (*models.Actor, error) {
+	var actor models.Actor
+	var birthDate, nationality, biography, profileURL sql.NullString
+	err := row.Scan(&actor.ID, &actor.Name, &birthDate, &nationality, &biography, &profileURL)
+	if err != nil {
+		return nil, err
+	}
+	actor.BirthDate = birthDate.String
+	actor.Nationality = nationality.String
+	actor.Biography = biography.String
+	actor.ProfileURL = profileURL.String
+	return &actor, nil
+}
+
+// reviewColumns reads the author's name from users; queries using it must
+// select FROM reviews without an alias.
+const reviewColumns = `id, movie_id, user_id, (SELECT name FROM users WHERE users.id = reviews.user_id),
+	rating, comment, created_at`
+
+func scanReview(row scanner) (*models.Review, error) {
+	var review models.Review
+	var userID, userName, comment sql.NullString
+	err := row.Scan(&review.ID, &review.MovieID, &userID, &userName, &review.Rating, &comment, &review.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	review.UserID = userID.String
+	review.UserName = userName.String
+	review.Comment = comment.String
+	return &review, nil
+}
+
+// nullable stores empty optional strings as NULL, matching what the API
+// wrote before the store existed when an optional input was omitted.
+func nullable(s string) interface{} {
+	if s == "" {
+		return nil
+	}
+	return s
+}
+
+// splitNames splits a comma-joined list such as
+// "Lana Wachowski, Lilly Wachowski" or "Sci-Fi, Action" into its names,
+// dropping blanks and case-insensitive repeats.
+func splitNames(list string) []string {
+	var names []string
+	seen := map[string]bool{}
+	for _, name := range strings.Split(list, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" || seen[strings.ToLower(name)] {
+			continue
+		}
+		seen[strings.ToLower(name)] = true
+		names = append(names, name)
+	}
+	return names
+}
+
+// placeholders returns "?, ?, ..." with n markers for an IN clause.
+func placeholders(n int) string {
+	return strings.TrimSuffix(strings.Repeat("?, ", n), ", ")
+}
+
+func stringArgs(values []string) []interface{} {
+	args := make([]interface{}, len(values))
+	for i, v := range values {
+		args[i] = v
+	}
+	return args
+}
+
+func (s *SQLiteStore) GetMovie(ctx context.Context, id string) (*models.Movie, error) {
+	movie, err := scanMovie(s.db.QueryRowContext(ctx,
+		`SELECT `+movieColumns+` FROM movies WHERE id = ? AND deleted_at IS NULL`, id))
+	if err == sql.ErrNoRows {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to query movie: %v", err)
+	}
+	return movie, nil
+}
+
+// movieListOrder is the default listing order: newest first, with the ID
+// breaking ties between rows created in the same second.
+var movieListOrder = keyset{name: "created_at", keys: []sortKey{
+	{expr: "m.created_at", desc: true},
+	{expr: "m.id", desc: true},
+}}
+
+// movieSortFields whitelists the MovieSort fields, as expressions over
+// movies aliased as m. Nullable fields sort their NULLs last in either
+// direction.
+var movieSortFields = map[string]struct {
+	expr     string
+	nullable bool
+}{
+	"title":                {expr: "m.title COLLATE NOCASE"},
+	"year":                 {expr: "COALESCE(m.year, 0)"},
+	"rating":               {expr: "COALESCE(m.rating, 0)"},
+	"duration":             {expr: "COALESCE(m.duration, 0)"},
+	"created_at":           {expr: "m.created_at"},
+	"average_review_score": {expr: reviewAverageExpr, nullable: true},
+	"review_count":         {expr: reviewCountExpr},
+	"weighted_score":       {expr: weightedScoreExpr, nullable: true},
+}
+
+// movieOrder returns the keyset for sorts, or fallback when sorts is empty.
+// The ID breaks any remaining ties, and the keyset is named after sorts so
+// cursors only apply to the ordering that issued them.
+func movieOrder(sorts []models.MovieSort, fallback keyset) (keyset, error) {
+	if len(sorts) == 0 {
+		return fallback, nil
+	}
+
+	var names []string
+	var keys []sortKey
+	for _, sort := range sorts {
+		field, ok := movieSortFields[sort.Field]
+		if !ok {
+			return keyset{}, fmt.Errorf("unknown sort field %q", sort.Field)
+		}
+
+		dir := "asc"
+		if sort.Desc {
+			dir = "desc"
+		}
+		names = append(names, sort.Field+":"+dir)
+
+		if field.nullable {
+			keys = append(keys,
+				sortKey{expr: "(" + field.expr + " IS NULL)"},
+				sortKey{expr: "COALESCE(" + field.expr + ", 0)", desc: sort.Desc})
+		} else {
+			keys = append(keys, sortKey{expr: field.expr, desc: sort.Desc})
+		}
+	}
+	return keyset{name: strings.Join(names, ","), keys: append(keys, sortKey{expr: "m.id"})}, nil
+}
+
+// movieFilterWhere builds the WHERE clause shared by ListMovies and
+// MoviesConnection, over movies aliased as m. Trashed movies never match.
+func movieFilterWhere(filter models.MovieFilter) (string, []interface{}) {
+	where := " WHERE m.deleted_at IS NULL"
+	args := []interface{}{}
+
+	for _, f := range []struct {
+		names             []string
+		matchAll, exclude bool
+	}{
+		{names: []string{filter.Genre}},
+		{names: filter.GenresAny},
+		{names: filter.GenresAll, matchAll: true},
+		{names: filter.ExcludeGenres, exclude: true},
+	} {
+		if cond, condArgs, ok := genreFilter(f.names, f.matchAll, f.exclude); ok {
+			where += cond
+			args = append(args, condArgs...)
+		}
+	}
+	if filter.MinYear > 0 {
+		where += " AND year >= ?"
+		args = append(args, filter.MinYear)
+	}
+	if filter.MaxYear > 0 {
+		where += " AND year <= ?"
+		args = append(args, filter.MaxYear)
+	}
+	if filter.MinRating > 0 {
+		where += " AND rating >= ?"
+		args = append(args, filter.MinRating)
+	}
+	if filter.MinReviewCount > 0 {
+		where += " AND " + reviewCountExpr + " >= ?"
+		args = append(args, filter.MinReviewCount)
+	}
+	if filter.MinAverageReview > 0 {
+		where += " AND " + reviewAverageExpr + " >= ?"
+		args = append(args, filter.MinAverageReview)
+	}
+	if filter.MinWeightedScore > 0 {
+		where += " AND " + weightedScoreExpr + " >= ?"
+		args = append(args, filter.MinWeightedScore)
+	}
+	if match := ftsQuery(filter.Search); match != "" {
+		where += " AND id IN (SELECT i.movie_id FROM movies_fts INNER JOIN movies_fts_ids i ON i.fts_rowid = movies_fts.rowid WHERE movies_fts MATCH ?)"
+		args = append(args, match)
+	}
+	return where, args
+}
+
+func (s *SQLiteStore) ListMovies(ctx context.Context, filter models.MovieFilter, sort []models.MovieSort, limit, offset int) ([]models.Movie, int, error) {
+	order, err := movieOrder(sort, movieListOrder)
+	if err != nil {
+		return nil, 0, err
+	}
+	where, args := movieFilterWhere(filter)
+
+	var total int
+	err = s.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM movies m"+where, args...).Scan(&total)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to count movies: %v", err)
+	}
+
+	rows, err := s.db.QueryContext(ctx,
+		"SELECT "+movieColumns+" FROM movies m"+where+order.orderBy(false)+" LIMIT ? OFFSET ?",
+		append(args, limit, offset)...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to query movies: %v", err)
+	}
+	movies, err := scanMovies(rows)
+	if err != nil {
+		return nil, 0, err
+	}
+	return movies, total, nil
+}
+
+func (s *SQLiteStore) MoviesConnection(ctx context.Context, filter models.MovieFilter, sort []models.MovieSort, page models.PageArgs) (*models.Connection[models.Movie], error) {
+	order, err := movieOrder(sort, movieListOrder)
+	if err != nil {
+		return nil, err
+	}
+	where, args := movieFilterWhere(filter)
+
+	var total int
+	err = s.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM movies m"+where, args...).Scan(&total)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count movies: %v", err)
+	}
+
+	edges, info, err := fetchPage(ctx, s.db, order, page, movieColumns, "FROM movies m"+where, args, scanMovie)
+	if err != nil {
+		return nil, err
+	}
+	return &models.Connection[models.Movie]{Edges: edges, PageInfo: info, TotalCount: total}, nil
+}
+
+func (s *SQLiteStore) CreateMovie(ctx context.Context, movie models.Movie) (*models.Movie, error) {
+	if movie.ID == "" {
+		movie.ID = uuid.New().String()
+	}
+
+	_, err := s.db.ExecContext(ctx, `
+		INSERT INTO movies (id, title, description, year, rating, duration, genre, director, poster_url, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP)`,
+		movie.ID, movie.Title, nullable(movie.Description), movie.Year, movie.Rating,
+		movie.Duration, nullable(movie.Genre), nullable(movie.Director), nullable(movie.PosterURL),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create movie: %v", err)
+	}
+	if err := s.SetMovieDirectors(ctx, movie.ID, movie.Director); err != nil {
+		return nil, err
+	}
+	if err := s.SetMovieGenres(ctx, movie.ID, movie.Genre); err != nil {
+		return nil, err
+	}
+	return s.GetMovie(ctx, movie.ID)
+}
+
+// checkMovieVersion returns ErrNotFound for an unknown or trashed movie and
+// ErrVersionConflict when expectedVersion is set and differs from the
+// movie's version.
+func (s *SQLiteStore) checkMovieVersion(ctx context.Context, id string, expectedVersion int) error {
+	var version int
+	err := s.db.QueryRowContext(ctx, "SELECT version FROM movies WHERE id = ? AND deleted_at IS NULL", id).Scan(&version)
+	if err == sql.ErrNoRows {
+		return ErrNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("failed to query movie version: %v", err)
+	}
+	if expectedVersion != 0 && version != expectedVersion {
+		return ErrVersionConflict
+	}
+	return nil
+}
+
+func (s *SQLiteStore) UpdateMovie(ctx context.Context, movie models.Movie, expectedVersion int) (*models.Movie, error) {
+	result, err := s.db.ExecContext(ctx, `
+		UPDATE movies
+		SET title = ?, description = ?, year = ?, rating = ?, duration = ?,
+		    genre = ?, director = ?, poster_url = ?, updated_at = CURRENT_TIMESTAMP,
+		    version = version + 1
+		WHERE id = ? AND deleted_at IS NULL AND (? = 0 OR version = ?)`,
+		movie.Title, nullable(movie.Description), movie.Year, movie.Rating, movie.Duration,
+		nullable(movie.Genre), nullable(movie.Director), nullable(movie.PosterURL),
+		movie.ID, expectedVersion, expectedVersion,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update movie: %v", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		if err := s.checkMovieVersion(ctx, movie.ID, expectedVersion); err != nil {
+			return nil, err
+		}
+		return nil, ErrVersionConflict
+	}
+	if err := s.SetMovieDirectors(ctx, movie.ID, movie.Director); err != nil {
+		return nil, err
+	}
+	if err := s.SetMovieGenres(ctx, movie.ID, movie.Genre); err != nil {
+		return nil, err
+	}
+	return s.GetMovie(ctx, movie.ID)
+}
+
+// DeleteMovie moves the movie to the trash along with its live reviews,
+// which share its deleted_at. Cast, director and genre links are kept for
+// RestoreMovie; PurgeDeleted removes them.
+func (s *SQLiteStore) DeleteMovie(ctx context.Context, id string, expectedVersion int) (bool, error) {
+	if err := s.checkMovieVersion(ctx, id, expectedVersion); err == ErrNotFound {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+
+	result, err := s.db.ExecContext(ctx,
+		"UPDATE movies SET deleted_at = "+nowMillis+" WHERE id = ? AND deleted_at IS NULL", id)
+	if err != nil {
+		return false, fmt.Errorf("failed to delete movie: %v", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return false, nil
+	}
+
+	if _, err := s.db.ExecContext(ctx, `
+		UPDATE reviews SET deleted_at = (SELECT deleted_at FROM movies WHERE id = ?)
+		WHERE movie_id = ? AND deleted_at IS NULL`, id, id); err != nil {
+		return false, fmt.Errorf("failed to delete reviews: %v", err)
+	}
+	return true, nil
+}
+
+func (s *SQLiteStore) RestoreMovie(ctx context.Context, id string) (*models.Movie, error) {
+	// Reviews first, while the movie's deleted_at still says which ones
+	// were trashed with it.
+	if _, err := s.db.ExecContext(ctx, `
+		UPDATE reviews SET deleted_at = NULL
+		WHERE movie_id = ? AND deleted_at = (SELECT deleted_at FROM movies WHERE id = ?)`, id, id); err != nil {
+		return nil, fmt.Errorf("failed to restore reviews: %v", err)
+	}
+
+	result, err := s.db.ExecContext(ctx,
+		"UPDATE movies SET deleted_at = NULL WHERE id = ? AND deleted_at IS NOT NULL", id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to restore movie: %v", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return nil, ErrNotFound
+	}
+	return s.GetMovie(ctx, id)
+}
+
+func (s *SQLiteStore) GetActor(ctx context.Context, id string) (*models.Actor, error) {
+	actor, err := scanActor(s.db.QueryRowContext(ctx, `
+		SELECT id, name, birth_date, nationality, biography, profile_url
+		FROM actors WHERE id = ? AND deleted_at IS NULL`, id))
+	if err == sql.ErrNoRows {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to query actor: %v", err)
+	}
+	return actor, nil
+}
+
+func (s *SQLiteStore) FindActorByName(ctx context.Context, name string) (*models.Actor, error) {
+	actor, err := scanActor(s.db.QueryRowContext(ctx, `
+		SELECT id, name, birth_date, nationality, biography, profile_url
+		FROM actors WHERE name = ? COLLATE NOCASE AND deleted_at IS NULL
+		ORDER BY rowid LIMIT 1`, name))
+	if err == sql.ErrNoRows {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to query actor: %v", err)
+	}
+	return actor, nil
+}
+
+func (s *SQLiteStore) CreateActor(ctx context.Context, actor models.Actor) (*models.Actor, error) {
+	if actor.ID == "" {
+		actor.ID = uuid.New().String()
+	}
+
+	_, err := s.db.ExecContext(ctx, `
+		INSERT INTO actors (id, name, birth_date, nationality, biography, profile_url)
+		VALUES (?, ?, ?, ?, ?, ?)`,
+		actor.ID, actor.Name, nullable(actor.BirthDate), nullable(actor.Nationality),
+		nullable(actor.Biography), nullable(actor.ProfileURL),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create actor: %v", err)
+	}
+	return s.GetActor(ctx, actor.ID)
+}
+
+func (s *SQLiteStore) DeleteActor(ctx context.Context, id string) (bool, error) {
+	result, err := s.db.ExecContext(ctx,
+		"UPDATE actors SET deleted_at = "+nowMillis+" WHERE id = ? AND deleted_at IS NULL", id)
+	if err != nil {
+		return false, fmt.Errorf("failed to delete actor: %v", err)
+	}
+	rowsAffected, _ := result.RowsAffected()
+	return rowsAffected > 0, nil
+}
+
+func (s *SQLiteStore) RestoreActor(ctx context.Context, id string) (*models.Actor, error) {
+	result, err := s.db.ExecContext(ctx,
+		"UPDATE actors SET deleted_at = NULL WHERE id = ? AND deleted_at IS NOT NULL", id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to restore actor: %v", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return nil, ErrNotFound
+	}
+	return s.GetActor(ctx, id)
+}
+
+func (s *SQLiteStore) GetReview(ctx context.Context, id string) (*models.Review, error) {
+	review, err := scanReview(s.db.QueryRowContext(ctx, `
+		SELECT `+reviewColumns+`
+		FROM reviews WHERE id = ? AND deleted_at IS NULL`, id))
+	if err == sql.ErrNoRows {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error retrieving review: %v", err)
+	}
+	return review, nil
+}
+
+func (s *SQLiteStore) ReviewsForMovie(ctx context.Context, movieID string) ([]models.Review, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT `+reviewColumns+`
+		FROM reviews WHERE movie_id = ? AND deleted_at IS NULL`+reviewListOrder.orderBy(false), movieID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query reviews: %v", err)
+	}
+	defer rows.Close()
+
+	reviews := []models.Review{}
+	for rows.Next() {
+		review, err := scanReview(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan review: %v", err)
+		}
+		reviews = append(reviews, *review)
+	}
+	return reviews, rows.Err()
+}
+
+var reviewListOrder = keyset{name: "created_at", keys: []sortKey{
+	{expr: "created_at", desc: true},
+	{expr: "id", desc: true},
+}}
+
+func (s *SQLiteStore) ReviewsConnection(ctx context.Context, movieID string, page models.PageArgs) (*models.Connection[models.Review], error) {
+	var total int
+	err := s.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM reviews WHERE movie_id = ? AND deleted_at IS NULL", movieID).Scan(&total)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count reviews: %v", err)
+	}
+
+	edges, info, err := fetchPage(ctx, s.db, reviewListOrder, page,
+		reviewColumns, "FROM reviews WHERE movie_id = ? AND deleted_at IS NULL",
+		[]interface{}{movieID}, scanReview)
+	if err != nil {
+		return nil, err
+	}
+	return &models.Connection[models.Review]{Edges: edges, PageInfo: info, TotalCount: total}, nil
+}
+
+func (s *SQLiteStore) ReviewsForMovies(ctx context.Context, movieIDs []string) (map[string][]models.Review, error) {
+	byMovie := make(map[string][]models.Review, len(movieIDs))
+	if len(movieIDs) == 0 {
+		return byMovie, nil
+	}
+
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT `+reviewColumns+`
+		FROM reviews WHERE movie_id IN (`+placeholders(len(movieIDs))+`) AND deleted_at IS NULL`+
+		reviewListOrder.orderBy(false), stringArgs(movieIDs)...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query reviews: %v", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		review, err := scanReview(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan review: %v", err)
+		}
+		byMovie[review.MovieID] = append(byMovie[review.MovieID], *review)
+	}
+	return byMovie, rows.Err()
+}
+
+func (s *SQLiteStore) CreateReview(ctx context.Context, review models.Review) (*models.Review, error) {
+	if review.ID == "" {
+		review.ID = uuid.New().String()
+	}
+
+	_, err := s.db.ExecContext(ctx, `
+		INSERT INTO reviews (id, movie_id, user_id, rating, comment)
+		VALUES (?, ?, ?, ?, ?)`,
+		review.ID, review.MovieID, review.UserID, review.Rating, nullable(review.Comment),
+	)
+	if isConstraintError(err, sqlite3.ErrConstraintUnique) {
+		return nil, ErrDuplicate
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to create review: %v", err)
+	}
+	return s.GetReview(ctx, review.ID)
+}
+
+// UpsertReview updates and then inserts rather than using INSERT ... ON
+// CONFLICT DO UPDATE, whose conflict handling would override the INSERT OR
+// IGNORE in the review stats triggers.
+func (s *SQLiteStore) UpsertReview(ctx context.Context, review models.Review) (*models.Review, error) {
+	updated, err := s.updateLiveReview(ctx, review)
+	if err != nil || updated != nil {
+		return updated, err
+	}
+
+	created, err := s.CreateReview(ctx, review)
+	if err != ErrDuplicate {
+		return created, err
+	}
+	// A concurrent request created the review first.
+	return s.updateLiveReview(ctx, review)
+}
+
+// updateLiveReview replaces the rating and comment of the user's live review
+// of the movie. It returns nil if there is none.
+func (s *SQLiteStore) updateLiveReview(ctx context.Context, review models.Review) (*models.Review, error) {
+	_, err := s.db.ExecContext(ctx, `
+		UPDATE reviews SET rating = ?, comment = ?
+		WHERE movie_id = ? AND user_id = ? AND deleted_at IS NULL`,
+		review.Rating, nullable(review.Comment), review.MovieID, review.UserID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update review: %v", err)
+	}
+
+	updated, err := scanReview(s.db.QueryRowContext(ctx, `
+		SELECT `+reviewColumns+`
+		FROM reviews WHERE movie_id = ? AND user_id = ? AND deleted_at IS NULL`, review.MovieID, review.UserID))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error retrieving review: %v", err)
+	}
+	return updated, nil
+}
+
+func (s *SQLiteStore) ReviewsForUsers(ctx context.Context, userIDs []string) (map[string][]models.Review, error) {
+	byUser := make(map[string][]models.Review, len(userIDs))
+	if len(userIDs) == 0 {
+		return byUser, nil
+	}
+
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT `+reviewColumns+`
+		FROM reviews WHERE user_id IN (`+placeholders(len(userIDs))+`) AND deleted_at IS NULL`+
+		reviewListOrder.orderBy(false), stringArgs(userIDs)...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query reviews: %v", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		review, err := scanReview(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan review: %v", err)
+		}
+		byUser[review.UserID] = append(byUser[review.UserID], *review)
+	}
+	return byUser, rows.Err()
+}
+
+func (s *SQLiteStore) DeleteReview(ctx context.Context, id string) (bool, error) {
+	result, err := s.db.ExecContext(ctx,
+		"UPDATE reviews SET deleted_at = "+nowMillis+" WHERE id = ? AND deleted_at IS NULL", id)
+	if err != nil {
+		return false, fmt.Errorf("failed to delete review: %v", err)
+	}
+	rowsAffected, _ := result.RowsAffected()
+	return rowsAffected > 0, nil
+}
+
+func (s *SQLiteStore) RestoreReview(ctx context.Context, id string) (*models.Review, error) {
+	result, err := s.db.ExecContext(ctx, `
+		UPDATE reviews SET deleted_at = NULL
+		WHERE id = ? AND deleted_at IS NOT NULL
+		  AND movie_id IN (SELECT id FROM movies WHERE deleted_at IS NULL)`, id)
+	if isConstraintError(err, sqlite3.ErrConstraintUnique) {
+		return nil, ErrDuplicate
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to restore review: %v", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return nil, ErrNotFound
+	}
+	return s.GetReview(ctx, id)
+}
+
+func (s *SQLiteStore) EnsureDirector(ctx context.Context, name string) (string, error) {
+	if name == "" {
+		return "", fmt.Errorf("director name is required")
+	}
+
+	var id string
+	err := s.db.QueryRowContext(ctx, "SELECT id FROM directors WHERE name = ?", name).Scan(&id)
+	if err == nil {
+		return id, nil
+	}
+	if err != sql.ErrNoRows {
+		return "", err
+	}
+
+	id = uuid.New().String()
+	_, err = s.db.ExecContext(ctx, "INSERT INTO directors (id, name) VALUES (?, ?)", id, name)
+	if err != nil {
+		err2 := s.db.QueryRowContext(ctx, "SELECT id FROM directors WHERE name = ?", name).Scan(&id)
+		if err2 == nil {
+			return id, nil
+		}
+		return "", err
+	}
+
+	return id, nil
+}
@@ -0,0 +1,91 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"movie-app/internal/models"
+
+	"github.com/google/uuid"
+	"github.com/mattn/go-sqlite3"
+)
+
+const apiKeyColumns = `id, name, prefix, key_hash, role, created_at, revoked_at`
+
+func scanAPIKey(row scanner) (*models.APIKey, error) {
+	var key models.APIKey
+	var revokedAt sql.NullTime
+	if err := row.Scan(&key.ID, &key.Name, &key.Prefix, &key.KeyHash, &key.Role, &key.CreatedAt, &revokedAt); err != nil {
+		return nil, err
+	}
+	if revokedAt.Valid {
+		key.RevokedAt = &revokedAt.Time
+	}
+	return &key, nil
+}
+
+func (s *SQLiteStore) CreateAPIKey(ctx context.Context, key models.APIKey) (*models.APIKey, error) {
+	if key.ID == "" {
+		key.ID = uuid.New().String()
+	}
+
+	_, err := s.db.ExecContext(ctx,
+		"INSERT INTO api_keys (id, name, prefix, key_hash, role) VALUES (?, ?, ?, ?, ?)",
+		key.ID, key.Name, key.Prefix, key.KeyHash, key.Role)
+	if isConstraintError(err, sqlite3.ErrConstraintUnique) {
+		return nil, ErrDuplicate
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to create API key: %v", err)
+	}
+
+	created, err := scanAPIKey(s.db.QueryRowContext(ctx, "SELECT "+apiKeyColumns+" FROM api_keys WHERE id = ?", key.ID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get API key: %v", err)
+	}
+	return created, nil
+}
+
+func (s *SQLiteStore) APIKeyByHash(ctx context.Context, hash string) (*models.APIKey, error) {
+	key, err := scanAPIKey(s.db.QueryRowContext(ctx,
+		"SELECT "+apiKeyColumns+" FROM api_keys WHERE key_hash = ? AND revoked_at IS NULL", hash))
+	if err == sql.ErrNoRows {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get API key: %v", err)
+	}
+	return key, nil
+}
+
+func (s *SQLiteStore) ListAPIKeys(ctx context.Context) ([]models.APIKey, error) {
+	rows, err := s.db.QueryContext(ctx, "SELECT "+apiKeyColumns+" FROM api_keys ORDER BY created_at, name")
+	if err != nil {
+		return nil, fmt.Errorf("failed to query API keys: %v", err)
+	}
+	defer rows.Close()
+
+	keys := []models.APIKey{}
+	for rows.Next() {
+		key, err := scanAPIKey(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan API key: %v", err)
+		}
+		keys = append(keys, *key)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to query API keys: %v", err)
+	}
+	return keys, nil
+}
+
+func (s *SQLiteStore) RevokeAPIKey(ctx context.Context, name string) (bool, error) {
+	result, err := s.db.ExecContext(ctx,
+		"UPDATE api_keys SET revoked_at = "+nowMillis+" WHERE name = ? AND revoked_at IS NULL", name)
+	if err != nil {
+		return false, fmt.Errorf("failed to revoke API key: %v", err)
+	}
+	n, _ := result.RowsAffected()
+	return n > 0, nil
+}
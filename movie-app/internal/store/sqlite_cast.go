@@ -0,0 +1,181 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"movie-app/internal/models"
+
+	"github.com/mattn/go-sqlite3"
+)
+
+func isConstraintError(err error, code sqlite3.ErrNoExtended) bool {
+	var sqliteErr sqlite3.Error
+	return errors.As(err, &sqliteErr) && sqliteErr.ExtendedCode == code
+}
+
+func (s *SQLiteStore) CastForMovies(ctx context.Context, movieIDs []string) (map[string][]models.CastMember, error) {
+	byMovie := make(map[string][]models.CastMember, len(movieIDs))
+	if len(movieIDs) == 0 {
+		return byMovie, nil
+	}
+
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT ma.movie_id, ma.character_name, ma.billing_order,
+		       a.id, a.name, a.birth_date, a.nationality, a.biography, a.profile_url
+		FROM movie_actors ma
+		INNER JOIN actors a ON a.id = ma.actor_id AND a.deleted_at IS NULL
+		WHERE ma.movie_id IN (`+placeholders(len(movieIDs))+`)
+		ORDER BY ma.movie_id, ma.billing_order, a.name`, stringArgs(movieIDs)...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query cast: %v", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var movieID string
+		var member models.CastMember
+		var characterName, birthDate, nationality, biography, profileURL sql.NullString
+		err := rows.Scan(&movieID, &characterName, &member.BillingOrder,
+			&member.Actor.ID, &member.Actor.Name, &birthDate, &nationality, &biography, &profileURL)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan cast member: %v", err)
+		}
+		member.CharacterName = characterName.String
+		member.Actor.BirthDate = birthDate.String
+		member.Actor.Nationality = nationality.String
+		member.Actor.Biography = biography.String
+		member.Actor.ProfileURL = profileURL.String
+		byMovie[movieID] = append(byMovie[movieID], member)
+	}
+	return byMovie, rows.Err()
+}
+
+func (s *SQLiteStore) FilmographyForActors(ctx context.Context, actorIDs []string) (map[string][]models.FilmographyEntry, error) {
+	byActor := make(map[string][]models.FilmographyEntry, len(actorIDs))
+	if len(actorIDs) == 0 {
+		return byActor, nil
+	}
+
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT ma.actor_id, ma.character_name, ma.billing_order, `+prefixedMovieColumns("m")+`
+		FROM movie_actors ma
+		INNER JOIN movies m ON m.id = ma.movie_id AND m.deleted_at IS NULL
+		WHERE ma.actor_id IN (`+placeholders(len(actorIDs))+`)
+		ORDER BY m.year DESC, m.title`, stringArgs(actorIDs)...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query filmography: %v", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var actorID string
+		var characterName sql.NullString
+		var entry models.FilmographyEntry
+		movie, err := scanMovie(prefixScanner{rows, []interface{}{&actorID, &characterName, &entry.BillingOrder}})
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan filmography: %v", err)
+		}
+		entry.Movie = *movie
+		entry.CharacterName = characterName.String
+		byActor[actorID] = append(byActor[actorID], entry)
+	}
+	return byActor, rows.Err()
+}
+
+func (s *SQLiteStore) AddCastMember(ctx context.Context, link models.MovieActor) error {
+	if link.BillingOrder <= 0 {
+		err := s.db.QueryRowContext(ctx,
+			"SELECT COALESCE(MAX(billing_order), 0) + 1 FROM movie_actors WHERE movie_id = ?",
+			link.MovieID).Scan(&link.BillingOrder)
+		if err != nil {
+			return fmt.Errorf("failed to compute billing order: %v", err)
+		}
+	} else {
+		_, err := s.db.ExecContext(ctx,
+			"UPDATE movie_actors SET billing_order = billing_order + 1 WHERE movie_id = ? AND billing_order >= ?",
+			link.MovieID, link.BillingOrder)
+		if err != nil {
+			return fmt.Errorf("failed to shift billing order: %v", err)
+		}
+	}
+
+	_, err := s.db.ExecContext(ctx, `
+		INSERT INTO movie_actors (movie_id, actor_id, character_name, billing_order)
+		VALUES (?, ?, ?, ?)`,
+		link.MovieID, link.ActorID, nullable(link.CharacterName), link.BillingOrder,
+	)
+	if isConstraintError(err, sqlite3.ErrConstraintPrimaryKey) {
+		return ErrDuplicate
+	}
+	if err != nil {
+		return fmt.Errorf("failed to link actor to movie: %v", err)
+	}
+	return nil
+}
+
+func (s *SQLiteStore) ReorderCast(ctx context.Context, movieID string, actorIDs []string) error {
+	// Trashed actors keep their billing but are not part of the visible cast.
+	var current int
+	err := s.db.QueryRowContext(ctx, `
+		SELECT COUNT(*) FROM movie_actors ma
+		INNER JOIN actors a ON a.id = ma.actor_id AND a.deleted_at IS NULL
+		WHERE ma.movie_id = ?`, movieID).Scan(&current)
+	if err != nil {
+		return fmt.Errorf("failed to count cast: %v", err)
+	}
+	if current != len(actorIDs) {
+		return &ValidationError{Field: "actor_ids",
+			Message: fmt.Sprintf("actor_ids must list all %d cast members exactly once", current)}
+	}
+
+	seen := map[string]bool{}
+	for i, actorID := range actorIDs {
+		if seen[actorID] {
+			return &ValidationError{Field: fmt.Sprintf("actor_ids[%d]", i),
+				Message: fmt.Sprintf("actor %s is listed more than once", actorID)}
+		}
+		seen[actorID] = true
+
+		result, err := s.db.ExecContext(ctx,
+			`UPDATE movie_actors SET billing_order = ?
+			WHERE movie_id = ? AND actor_id IN (SELECT id FROM actors WHERE id = ? AND deleted_at IS NULL)`,
+			i+1, movieID, actorID)
+		if err != nil {
+			return fmt.Errorf("failed to reorder cast: %v", err)
+		}
+		if n, _ := result.RowsAffected(); n == 0 {
+			return &ValidationError{Field: fmt.Sprintf("actor_ids[%d]", i),
+				Message: fmt.Sprintf("actor %s is not in the cast", actorID)}
+		}
+	}
+	return nil
+}
+
+func (s *SQLiteStore) RemoveCastMember(ctx context.Context, movieID, actorID string) (bool, error) {
+	var order int
+	err := s.db.QueryRowContext(ctx,
+		"SELECT billing_order FROM movie_actors WHERE movie_id = ? AND actor_id = ?",
+		movieID, actorID).Scan(&order)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to query cast member: %v", err)
+	}
+
+	if _, err := s.db.ExecContext(ctx,
+		"DELETE FROM movie_actors WHERE movie_id = ? AND actor_id = ?", movieID, actorID); err != nil {
+		return false, fmt.Errorf("failed to remove cast member: %v", err)
+	}
+
+	// Close the gap so billing stays 1..n.
+	if _, err := s.db.ExecContext(ctx,
+		"UPDATE movie_actors SET billing_order = billing_order - 1 WHERE movie_id = ? AND billing_order > ?",
+		movieID, order); err != nil {
+		return false, fmt.Errorf("failed to shift billing order: %v", err)
+	}
+	return true, nil
+}
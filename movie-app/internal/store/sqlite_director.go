@@ -0,0 +1,179 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"movie-app/internal/models"
+)
+
+func (s *SQLiteStore) GetDirector(ctx context.Context, id string) (*models.Director, error) {
+	var director models.Director
+	err := s.db.QueryRowContext(ctx, "SELECT id, name FROM directors WHERE id = ?", id).
+		Scan(&director.ID, &director.Name)
+	if err == sql.ErrNoRows {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to query director: %v", err)
+	}
+	return &director, nil
+}
+
+func (s *SQLiteStore) ListDirectors(ctx context.Context, search string, limit, offset int) ([]models.Director, int, error) {
+	where := ""
+	args := []interface{}{}
+	if search != "" {
+		where = " WHERE name LIKE ?"
+		args = append(args, "%"+search+"%")
+	}
+
+	var total int
+	err := s.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM directors"+where, args...).Scan(&total)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to count directors: %v", err)
+	}
+
+	rows, err := s.db.QueryContext(ctx,
+		"SELECT id, name FROM directors"+where+" ORDER BY name COLLATE NOCASE, id LIMIT ? OFFSET ?",
+		append(args, limit, offset)...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to query directors: %v", err)
+	}
+	defer rows.Close()
+
+	directors := []models.Director{}
+	for rows.Next() {
+		var director models.Director
+		if err := rows.Scan(&director.ID, &director.Name); err != nil {
+			return nil, 0, fmt.Errorf("failed to scan director: %v", err)
+		}
+		directors = append(directors, director)
+	}
+	return directors, total, rows.Err()
+}
+
+func (s *SQLiteStore) SetMovieDirectors(ctx context.Context, movieID, director string) error {
+	if _, err := s.db.ExecContext(ctx, "DELETE FROM movie_directors WHERE movie_id = ?", movieID); err != nil {
+		return fmt.Errorf("failed to clear movie directors: %v", err)
+	}
+
+	for i, name := range splitNames(director) {
+		directorID, err := s.EnsureDirector(ctx, name)
+		if err != nil {
+			return fmt.Errorf("failed to ensure director: %v", err)
+		}
+		_, err = s.db.ExecContext(ctx,
+			"INSERT INTO movie_directors (movie_id, director_id, position) VALUES (?, ?, ?)",
+			movieID, directorID, i+1)
+		if err != nil {
+			return fmt.Errorf("failed to link director to movie: %v", err)
+		}
+	}
+	return nil
+}
+
+func (s *SQLiteStore) DirectorsForMovies(ctx context.Context, movieIDs []string) (map[string][]models.Director, error) {
+	byMovie := make(map[string][]models.Director, len(movieIDs))
+	if len(movieIDs) == 0 {
+		return byMovie, nil
+	}
+
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT md.movie_id, d.id, d.name
+		FROM movie_directors md
+		INNER JOIN directors d ON d.id = md.director_id
+		WHERE md.movie_id IN (`+placeholders(len(movieIDs))+`)
+		ORDER BY md.movie_id, md.position`, stringArgs(movieIDs)...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query movie directors: %v", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var movieID string
+		var director models.Director
+		if err := rows.Scan(&movieID, &director.ID, &director.Name); err != nil {
+			return nil, fmt.Errorf("failed to scan director: %v", err)
+		}
+		byMovie[movieID] = append(byMovie[movieID], director)
+	}
+	return byMovie, rows.Err()
+}
+
+func (s *SQLiteStore) FilmographyForDirectors(ctx context.Context, directorIDs []string) (map[string][]models.Movie, error) {
+	byDirector := make(map[string][]models.Movie, len(directorIDs))
+	if len(directorIDs) == 0 {
+		return byDirector, nil
+	}
+
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT md.director_id, `+prefixedMovieColumns("m")+`
+		FROM movie_directors md
+		INNER JOIN movies m ON m.id = md.movie_id AND m.deleted_at IS NULL
+		WHERE md.director_id IN (`+placeholders(len(directorIDs))+`)
+		ORDER BY m.year DESC, m.title`, stringArgs(directorIDs)...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query director filmography: %v", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var directorID string
+		movie, err := scanMovie(prefixScanner{rows, []interface{}{&directorID}})
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan movie: %v", err)
+		}
+		byDirector[directorID] = append(byDirector[directorID], *movie)
+	}
+	return byDirector, rows.Err()
+}
+
+func (s *SQLiteStore) StatsForDirectors(ctx context.Context, directorIDs []string) (map[string]models.DirectorStats, error) {
+	byDirector := make(map[string]models.DirectorStats, len(directorIDs))
+	if len(directorIDs) == 0 {
+		return byDirector, nil
+	}
+
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT md.director_id, COUNT(*), AVG(m.rating), MIN(m.year), MAX(m.year),
+		       COALESCE(SUM(r.review_count), 0), SUM(r.rating_sum) * 1.0 / NULLIF(SUM(r.review_count), 0)
+		FROM movie_directors md
+		INNER JOIN movies m ON m.id = md.movie_id AND m.deleted_at IS NULL
+		LEFT JOIN movie_review_stats r ON r.movie_id = m.id
+		WHERE md.director_id IN (`+placeholders(len(directorIDs))+`)
+		GROUP BY md.director_id`, stringArgs(directorIDs)...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query director stats: %v", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var directorID string
+		var stats models.DirectorStats
+		var averageRating, averageReviewScore sql.NullFloat64
+		var firstYear, latestYear sql.NullInt64
+		err := rows.Scan(&directorID, &stats.MovieCount, &averageRating, &firstYear, &latestYear,
+			&stats.ReviewCount, &averageReviewScore)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan director stats: %v", err)
+		}
+		if averageRating.Valid {
+			stats.AverageRating = &averageRating.Float64
+		}
+		if firstYear.Valid {
+			year := int(firstYear.Int64)
+			stats.FirstYear = &year
+		}
+		if latestYear.Valid {
+			year := int(latestYear.Int64)
+			stats.LatestYear = &year
+		}
+		if averageReviewScore.Valid {
+			stats.AverageReviewScore = &averageReviewScore.Float64
+		}
+		byDirector[directorID] = stats
+	}
+	return byDirector, rows.Err()
+}
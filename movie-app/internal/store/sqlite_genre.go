@@ -0,0 +1,131 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"movie-app/internal/models"
+
+	"github.com/google/uuid"
+)
+
+const genreColumns = `g.id, g.name, (
+	SELECT COUNT(*) FROM movie_genres c
+	INNER JOIN movies m ON m.id = c.movie_id AND m.deleted_at IS NULL
+	WHERE c.genre_id = g.id)`
+
+func scanGenre(row scanner) (*models.Genre, error) {
+	var genre models.Genre
+	if err := row.Scan(&genre.ID, &genre.Name, &genre.MovieCount); err != nil {
+		return nil, err
+	}
+	return &genre, nil
+}
+
+func (s *SQLiteStore) ListGenres(ctx context.Context) ([]models.Genre, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT `+genreColumns+` FROM genres g ORDER BY g.name`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query genres: %v", err)
+	}
+	defer rows.Close()
+
+	genres := []models.Genre{}
+	for rows.Next() {
+		genre, err := scanGenre(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan genre: %v", err)
+		}
+		genres = append(genres, *genre)
+	}
+	return genres, rows.Err()
+}
+
+// ensureGenre returns the ID of the genre matching name case-insensitively,
+// creating it if needed.
+func (s *SQLiteStore) ensureGenre(ctx context.Context, name string) (string, error) {
+	var id string
+	err := s.db.QueryRowContext(ctx, "SELECT id FROM genres WHERE name = ?", name).Scan(&id)
+	if err != sql.ErrNoRows {
+		return id, err
+	}
+
+	id = uuid.New().String()
+	if _, err := s.db.ExecContext(ctx, "INSERT INTO genres (id, name) VALUES (?, ?)", id, name); err != nil {
+		return "", err
+	}
+	return id, nil
+}
+
+func (s *SQLiteStore) SetMovieGenres(ctx context.Context, movieID, genre string) error {
+	if _, err := s.db.ExecContext(ctx, "DELETE FROM movie_genres WHERE movie_id = ?", movieID); err != nil {
+		return fmt.Errorf("failed to clear movie genres: %v", err)
+	}
+
+	for i, name := range splitNames(genre) {
+		genreID, err := s.ensureGenre(ctx, name)
+		if err != nil {
+			return fmt.Errorf("failed to ensure genre: %v", err)
+		}
+		_, err = s.db.ExecContext(ctx,
+			"INSERT INTO movie_genres (movie_id, genre_id, position) VALUES (?, ?, ?)",
+			movieID, genreID, i+1)
+		if err != nil {
+			return fmt.Errorf("failed to tag movie with genre: %v", err)
+		}
+	}
+	return nil
+}
+
+func (s *SQLiteStore) GenresForMovies(ctx context.Context, movieIDs []string) (map[string][]models.Genre, error) {
+	byMovie := make(map[string][]models.Genre, len(movieIDs))
+	if len(movieIDs) == 0 {
+		return byMovie, nil
+	}
+
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT mg.movie_id, `+genreColumns+`
+		FROM movie_genres mg
+		INNER JOIN genres g ON g.id = mg.genre_id
+		WHERE mg.movie_id IN (`+placeholders(len(movieIDs))+`)
+		ORDER BY mg.movie_id, mg.position`, stringArgs(movieIDs)...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query movie genres: %v", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var movieID string
+		genre, err := scanGenre(prefixScanner{rows, []interface{}{&movieID}})
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan genre: %v", err)
+		}
+		byMovie[movieID] = append(byMovie[movieID], *genre)
+	}
+	return byMovie, rows.Err()
+}
+
+// genreFilter returns a condition on movies.id matching movies tagged with
+// any of names (or, with matchAll, every one of them), negated by exclude.
+// Names match whole genres case-insensitively. ok is false when names holds
+// no genre at all.
+func genreFilter(names []string, matchAll, exclude bool) (cond string, args []interface{}, ok bool) {
+	distinct := splitNames(strings.Join(names, ","))
+	if len(distinct) == 0 {
+		return "", nil, false
+	}
+
+	cond = `SELECT mg.movie_id FROM movie_genres mg
+		INNER JOIN genres g ON g.id = mg.genre_id
+		WHERE g.name IN (` + placeholders(len(distinct)) + `)`
+	args = stringArgs(distinct)
+	if matchAll {
+		cond += ` GROUP BY mg.movie_id HAVING COUNT(*) = ?`
+		args = append(args, len(distinct))
+	}
+	if exclude {
+		return " AND id NOT IN (" + cond + ")", args, true
+	}
+	return " AND id IN (" + cond + ")", args, true
+}
@@ -0,0 +1,190 @@
+package store
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"movie-app/internal/models"
+)
+
+// sortKey is one ORDER BY term of a keyset-paginated query. expr is trusted
+// SQL, never user input, and must not evaluate to NULL.
+type sortKey struct {
+	expr string
+	desc bool
+}
+
+// keyset is a total ordering for cursor pagination: its last key must be
+// unique per row (the ID). name identifies the ordering inside cursors so a
+// cursor is never applied to a different ordering.
+type keyset struct {
+	name string
+	keys []sortKey
+}
+
+type cursorPayload struct {
+	Order  string        `json:"o"`
+	Values []interface{} `json:"v"`
+}
+
+func (k keyset) encodeCursor(values []interface{}) (string, error) {
+	body, err := json.Marshal(cursorPayload{Order: k.name, Values: values})
+	if err != nil {
+		return "", fmt.Errorf("failed to encode cursor: %v", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(body), nil
+}
+
+func (k keyset) decodeCursor(cursor string) ([]interface{}, error) {
+	body, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	var payload cursorPayload
+	dec := json.NewDecoder(bytes.NewReader(body))
+	dec.UseNumber()
+	if err := dec.Decode(&payload); err != nil || payload.Order != k.name || len(payload.Values) != len(k.keys) {
+		return nil, ErrInvalidCursor
+	}
+
+	// Integers stay integers so they compare exactly against INTEGER columns.
+	for i, v := range payload.Values {
+		if n, ok := v.(json.Number); ok {
+			if integer, err := n.Int64(); err == nil {
+				payload.Values[i] = integer
+			} else if float, err := n.Float64(); err == nil {
+				payload.Values[i] = float
+			}
+		}
+		if _, ok := payload.Values[i].(json.Number); ok {
+			return nil, ErrInvalidCursor
+		}
+	}
+	return payload.Values, nil
+}
+
+// columns selects every key's raw value. The unary + stops go-sqlite3 from
+// converting DATETIME columns to time.Time, so values round-trip through a
+// cursor exactly as stored.
+func (k keyset) columns() string {
+	exprs := make([]string, len(k.keys))
+	for i, key := range k.keys {
+		exprs[i] = "+(" + key.expr + ")"
+	}
+	return strings.Join(exprs, ", ")
+}
+
+func (k keyset) orderBy(reverse bool) string {
+	terms := make([]string, len(k.keys))
+	for i, key := range k.keys {
+		dir := "ASC"
+		if key.desc != reverse {
+			dir = "DESC"
+		}
+		terms[i] = key.expr + " " + dir
+	}
+	return " ORDER BY " + strings.Join(terms, ", ")
+}
+
+// after returns a condition matching rows that come strictly after values in
+// the ordering (or strictly before it when reverse is set), expanded as
+// (k1 > v1) OR (k1 = v1 AND k2 > v2) OR ...
+func (k keyset) after(values []interface{}, reverse bool) (string, []interface{}) {
+	var ors []string
+	var args []interface{}
+	for i, key := range k.keys {
+		var ands []string
+		for j := 0; j < i; j++ {
+			ands = append(ands, k.keys[j].expr+" = ?")
+			args = append(args, values[j])
+		}
+		op := ">"
+		if key.desc != reverse {
+			op = "<"
+		}
+		ands = append(ands, key.expr+" "+op+" ?")
+		args = append(args, values[i])
+		ors = append(ors, "("+strings.Join(ands, " AND ")+")")
+	}
+	return " AND (" + strings.Join(ors, " OR ") + ")", args
+}
+
+// fetchPage runs a keyset-paginated query for page. from holds the FROM and
+// WHERE clauses (the WHERE may be "WHERE 1=1"), columns the row's columns
+// after the keys, and scan reads those columns.
+func fetchPage[T any](ctx context.Context, db DBTX, k keyset, page models.PageArgs,
+	columns, from string, args []interface{}, scan func(scanner) (*T, error)) ([]models.Edge[T], models.PageInfo, error) {
+	limit, cursor, backward := page.First, page.After, false
+	if page.Backward || page.Last > 0 {
+		limit, cursor, backward = page.Last, page.Before, true
+	}
+
+	query := "SELECT " + k.columns() + ", " + columns + " " + from
+	queryArgs := append([]interface{}{}, args...)
+	if cursor != "" {
+		values, err := k.decodeCursor(cursor)
+		if err != nil {
+			return nil, models.PageInfo{}, err
+		}
+		cond, condArgs := k.after(values, backward)
+		query += cond
+		queryArgs = append(queryArgs, condArgs...)
+	}
+	query += k.orderBy(backward) + " LIMIT ?"
+	queryArgs = append(queryArgs, limit+1)
+
+	rows, err := db.QueryContext(ctx, query, queryArgs...)
+	if err != nil {
+		return nil, models.PageInfo{}, fmt.Errorf("failed to query page: %v", err)
+	}
+	defer rows.Close()
+
+	edges := []models.Edge[T]{}
+	for rows.Next() {
+		values := make([]interface{}, len(k.keys))
+		prefix := make([]interface{}, len(values))
+		for i := range values {
+			prefix[i] = &values[i]
+		}
+		node, err := scan(prefixScanner{rows, prefix})
+		if err != nil {
+			return nil, models.PageInfo{}, fmt.Errorf("failed to scan page: %v", err)
+		}
+		for i, v := range values {
+			if b, ok := v.([]byte); ok {
+				values[i] = string(b)
+			}
+		}
+		c, err := k.encodeCursor(values)
+		if err != nil {
+			return nil, models.PageInfo{}, err
+		}
+		edges = append(edges, models.Edge[T]{Node: *node, Cursor: c})
+	}
+	if err := rows.Err(); err != nil {
+		return nil, models.PageInfo{}, fmt.Errorf("failed to query page: %v", err)
+	}
+
+	// One extra row was fetched to learn whether another page follows.
+	more := len(edges) > limit
+	if more {
+		edges = edges[:limit]
+	}
+	info := models.PageInfo{HasNextPage: more, HasPreviousPage: cursor != ""}
+	if backward {
+		for i, j := 0, len(edges)-1; i < j; i, j = i+1, j-1 {
+			edges[i], edges[j] = edges[j], edges[i]
+		}
+		info = models.PageInfo{HasNextPage: cursor != "", HasPreviousPage: more}
+	}
+	if len(edges) > 0 {
+		info.StartCursor = &edges[0].Cursor
+		info.EndCursor = &edges[len(edges)-1].Cursor
+	}
+	return edges, info, nil
+}
@@ -0,0 +1,82 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strconv"
+
+	"movie-app/internal/models"
+)
+
+// weightedScorePrior is m in IMDb's weighted rating: every movie's score
+// is blended with this many reviews at the catalogue-wide average, so a
+// couple of 5-star reviews do not outrank dozens of 4-star ones.
+const weightedScorePrior = 5
+
+// Review aggregate expressions over movies aliased as m, read from
+// movie_review_stats. reviewAverageExpr is NULL for an unreviewed movie and
+// weightedScoreExpr when no movie has reviews.
+const (
+	reviewCountExpr   = "COALESCE((SELECT rs.review_count FROM movie_review_stats rs WHERE rs.movie_id = m.id), 0)"
+	reviewSumExpr     = "COALESCE((SELECT rs.rating_sum FROM movie_review_stats rs WHERE rs.movie_id = m.id), 0)"
+	reviewAverageExpr = "(SELECT rs.rating_sum * 1.0 / NULLIF(rs.review_count, 0) FROM movie_review_stats rs WHERE rs.movie_id = m.id)"
+	meanReviewExpr    = "(SELECT SUM(rating_sum) * 1.0 / NULLIF(SUM(review_count), 0) FROM movie_review_stats)"
+)
+
+var weightedScoreExpr = "((" + reviewSumExpr + " + " + strconv.Itoa(weightedScorePrior) + " * " + meanReviewExpr + ") / (" +
+	reviewCountExpr + " + " + strconv.Itoa(weightedScorePrior) + "))"
+
+func (s *SQLiteStore) ReviewStatsForMovies(ctx context.Context, movieIDs []string) (map[string]models.ReviewStats, error) {
+	byMovie := make(map[string]models.ReviewStats, len(movieIDs))
+	if len(movieIDs) == 0 {
+		return byMovie, nil
+	}
+
+	var mean sql.NullFloat64
+	if err := s.db.QueryRowContext(ctx, "SELECT "+meanReviewExpr).Scan(&mean); err != nil {
+		return nil, fmt.Errorf("failed to query review average: %v", err)
+	}
+
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT movie_id, review_count, rating_sum, stars_1, stars_2, stars_3, stars_4, stars_5
+		FROM movie_review_stats
+		WHERE movie_id IN (`+placeholders(len(movieIDs))+`)`, stringArgs(movieIDs)...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query review stats: %v", err)
+	}
+	defer rows.Close()
+
+	sums := map[string]int{}
+	for rows.Next() {
+		var movieID string
+		var sum int
+		stats := models.ReviewStats{Histogram: make([]int, 5)}
+		h := stats.Histogram
+		if err := rows.Scan(&movieID, &stats.Count, &sum, &h[0], &h[1], &h[2], &h[3], &h[4]); err != nil {
+			return nil, fmt.Errorf("failed to scan review stats: %v", err)
+		}
+		byMovie[movieID] = stats
+		sums[movieID] = sum
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to query review stats: %v", err)
+	}
+
+	for _, id := range movieIDs {
+		stats, ok := byMovie[id]
+		if !ok {
+			stats = models.ReviewStats{Histogram: make([]int, 5)}
+		}
+		if stats.Count > 0 {
+			average := float64(sums[id]) / float64(stats.Count)
+			stats.Average = &average
+		}
+		if mean.Valid {
+			score := (float64(sums[id]) + weightedScorePrior*mean.Float64) / float64(stats.Count+weightedScorePrior)
+			stats.WeightedScore = &score
+		}
+		byMovie[id] = stats
+	}
+	return byMovie, nil
+}
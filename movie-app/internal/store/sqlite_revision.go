@@ -0,0 +1,150 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"movie-app/internal/models"
+
+	"github.com/google/uuid"
+)
+
+// timestampLayout is the text layout of the millisecond timestamps written
+// by nowMillis, such as movie_revisions.created_at and deleted_at, for
+// comparing against them as strings.
+const timestampLayout = "2006-01-02 15:04:05.000"
+
+// nowMillis is the current time in timestampLayout, as SQL.
+const nowMillis = "strftime('%Y-%m-%d %H:%M:%f', 'now')"
+
+const revisionColumns = `id, movie_id, mutation, actor, before_json, after_json, created_at`
+
+func scanRevision(row scanner) (*models.MovieRevision, error) {
+	var revision models.MovieRevision
+	var before, after sql.NullString
+	err := row.Scan(&revision.ID, &revision.MovieID, &revision.Mutation, &revision.Actor,
+		&before, &after, &revision.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	if revision.Before, err = snapshotFromJSON(before); err != nil {
+		return nil, err
+	}
+	if revision.After, err = snapshotFromJSON(after); err != nil {
+		return nil, err
+	}
+	return &revision, nil
+}
+
+func snapshotFromJSON(text sql.NullString) (*models.Movie, error) {
+	if !text.Valid {
+		return nil, nil
+	}
+	var movie models.Movie
+	if err := json.Unmarshal([]byte(text.String), &movie); err != nil {
+		return nil, fmt.Errorf("failed to decode movie snapshot: %v", err)
+	}
+	return &movie, nil
+}
+
+func snapshotJSON(movie *models.Movie) (interface{}, error) {
+	if movie == nil {
+		return nil, nil
+	}
+	// Only the movie's own fields are recorded.
+	snapshot := *movie
+	snapshot.Actors, snapshot.Reviews, snapshot.Highlights = nil, nil, nil
+	text, err := json.Marshal(snapshot)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode movie snapshot: %v", err)
+	}
+	return string(text), nil
+}
+
+func (s *SQLiteStore) RecordMovieRevision(ctx context.Context, revision models.MovieRevision) (*models.MovieRevision, error) {
+	if revision.ID == "" {
+		revision.ID = uuid.New().String()
+	}
+	before, err := snapshotJSON(revision.Before)
+	if err != nil {
+		return nil, err
+	}
+	after, err := snapshotJSON(revision.After)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = s.db.ExecContext(ctx, `
+		INSERT INTO movie_revisions (id, movie_id, mutation, actor, before_json, after_json)
+		VALUES (?, ?, ?, ?, ?, ?)`,
+		revision.ID, revision.MovieID, revision.Mutation, revision.Actor, before, after)
+	if err != nil {
+		return nil, fmt.Errorf("failed to record movie revision: %v", err)
+	}
+	return s.GetMovieRevision(ctx, revision.ID)
+}
+
+func (s *SQLiteStore) GetMovieRevision(ctx context.Context, id string) (*models.MovieRevision, error) {
+	revision, err := scanRevision(s.db.QueryRowContext(ctx,
+		`SELECT `+revisionColumns+` FROM movie_revisions WHERE id = ?`, id))
+	if err == sql.ErrNoRows {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to query movie revision: %v", err)
+	}
+	return revision, nil
+}
+
+func (s *SQLiteStore) RevisionsForMovies(ctx context.Context, movieIDs []string) (map[string][]models.MovieRevision, error) {
+	byMovie := make(map[string][]models.MovieRevision, len(movieIDs))
+	if len(movieIDs) == 0 {
+		return byMovie, nil
+	}
+
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT `+revisionColumns+` FROM movie_revisions
+		WHERE movie_id IN (`+placeholders(len(movieIDs))+`)
+		ORDER BY created_at DESC, rowid DESC`, stringArgs(movieIDs)...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query movie revisions: %v", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		revision, err := scanRevision(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan movie revision: %v", err)
+		}
+		byMovie[revision.MovieID] = append(byMovie[revision.MovieID], *revision)
+	}
+	return byMovie, rows.Err()
+}
+
+func (s *SQLiteStore) MovieAt(ctx context.Context, movieID string, at time.Time) (*models.Movie, error) {
+	var after sql.NullString
+	err := s.db.QueryRowContext(ctx, `
+		SELECT after_json FROM movie_revisions
+		WHERE movie_id = ? AND created_at <= ?
+		ORDER BY created_at DESC, rowid DESC LIMIT 1`,
+		movieID, at.UTC().Format(timestampLayout)).Scan(&after)
+	if err == sql.ErrNoRows {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to query movie revision: %v", err)
+	}
+
+	movie, err := snapshotFromJSON(after)
+	if err != nil {
+		return nil, err
+	}
+	if movie == nil {
+		// The movie had been deleted by then.
+		return nil, ErrNotFound
+	}
+	return movie, nil
+}
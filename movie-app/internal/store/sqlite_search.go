@@ -0,0 +1,194 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"unicode"
+
+	"movie-app/internal/models"
+)
+
+// ftsQuery turns user input into an FTS5 MATCH expression. Bare words must
+// all match (a trailing * makes a word a prefix), "quoted text" must match as
+// a phrase, and anything else is treated as word separators, so input can
+// never produce a malformed expression. Words joined by punctuation, such as
+// "sci-fi", become phrases. It returns "" when the input holds no words.
+func ftsQuery(input string) string {
+	var terms []string
+	rest := input
+	for rest != "" {
+		rest = strings.TrimLeftFunc(rest, unicode.IsSpace)
+		if rest == "" {
+			break
+		}
+
+		var token string
+		quoted := rest[0] == '"'
+		if quoted {
+			rest = rest[1:]
+			end := strings.IndexByte(rest, '"')
+			if end < 0 {
+				end = len(rest)
+			}
+			token, rest = rest[:end], strings.TrimPrefix(rest[end:], `"`)
+		} else {
+			end := strings.IndexFunc(rest, func(r rune) bool { return unicode.IsSpace(r) || r == '"' })
+			if end < 0 {
+				end = len(rest)
+			}
+			token, rest = rest[:end], rest[end:]
+		}
+
+		prefix := strings.HasSuffix(token, "*")
+		words := strings.FieldsFunc(strings.ToLower(token), func(r rune) bool {
+			return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+		})
+		if len(words) == 0 {
+			continue
+		}
+
+		term := strings.Join(words, " ")
+		if quoted || len(words) > 1 {
+			term = `"` + term + `"`
+		}
+		if prefix {
+			term += "*"
+		}
+		terms = append(terms, term)
+	}
+	return strings.Join(terms, " ")
+}
+
+// searchColumns are the movies_fts columns reported as highlights, by their
+// column index in movies_fts.
+var searchColumns = []struct {
+	index int
+	field string
+}{
+	{0, "title"},
+	{1, "description"},
+	{2, "director"},
+	{3, "genre"},
+	{4, "actors"},
+}
+
+const (
+	highlightStart = "<mark>"
+	highlightEnd   = "</mark>"
+)
+
+// searchOrder ranks matches by FTS5's bm25(), best (most negative) first.
+// Column weights follow movies_fts: title, description, director, genre,
+// actors.
+var searchOrder = keyset{name: "relevance", keys: []sortKey{
+	{expr: "bm25(movies_fts, 10.0, 1.0, 4.0, 2.0, 4.0)"},
+	{expr: "m.id"},
+}}
+
+// searchFrom joins index rows to their movies through movies_fts_ids.
+const searchFrom = `FROM movies_fts
+		INNER JOIN movies_fts_ids i ON i.fts_rowid = movies_fts.rowid
+		INNER JOIN movies m ON m.id = i.movie_id
+		WHERE movies_fts MATCH ?`
+
+// searchColumnList selects one snippet per searchColumns entry followed by
+// the movie's columns, as read by scanSearchHit.
+func searchColumnList() string {
+	var columns []string
+	for _, col := range searchColumns {
+		columns = append(columns, fmt.Sprintf("COALESCE(snippet(movies_fts, %d, '%s', '%s', '…', 12), '')",
+			col.index, highlightStart, highlightEnd))
+	}
+	return strings.Join(append(columns, prefixedMovieColumns("m")), ", ")
+}
+
+func scanSearchHit(row scanner) (*models.Movie, error) {
+	texts := make([]string, len(searchColumns))
+	prefix := make([]interface{}, len(texts))
+	for i := range texts {
+		prefix[i] = &texts[i]
+	}
+	movie, err := scanMovie(prefixScanner{row, prefix})
+	if err != nil {
+		return nil, err
+	}
+
+	// snippet() returns text for every column; only those with a marked
+	// match are highlights.
+	movie.Highlights = []models.Highlight{}
+	for i, text := range texts {
+		if strings.Contains(text, highlightStart) {
+			movie.Highlights = append(movie.Highlights, models.Highlight{Field: searchColumns[i].field, Snippet: text})
+		}
+	}
+	return movie, nil
+}
+
+func (s *SQLiteStore) countSearch(ctx context.Context, match string) (int, error) {
+	var total int
+	err := s.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM movies_fts WHERE movies_fts MATCH ?`, match).Scan(&total)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count movies: %v", err)
+	}
+	return total, nil
+}
+
+func (s *SQLiteStore) SearchMovies(ctx context.Context, query string, sort []models.MovieSort, limit, offset int) ([]models.Movie, int, error) {
+	order, err := movieOrder(sort, searchOrder)
+	if err != nil {
+		return nil, 0, err
+	}
+	match := ftsQuery(query)
+	if match == "" {
+		return []models.Movie{}, 0, nil
+	}
+
+	rows, err := s.db.QueryContext(ctx, `SELECT `+searchColumnList()+` `+searchFrom+
+		order.orderBy(false)+` LIMIT ? OFFSET ?`, match, limit, offset)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to search movies: %v", err)
+	}
+	defer rows.Close()
+
+	movies := []models.Movie{}
+	for rows.Next() {
+		movie, err := scanSearchHit(rows)
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to scan movie: %v", err)
+		}
+		movies = append(movies, *movie)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, fmt.Errorf("failed to search movies: %v", err)
+	}
+
+	total, err := s.countSearch(ctx, match)
+	if err != nil {
+		return nil, 0, err
+	}
+	return movies, total, nil
+}
+
+func (s *SQLiteStore) SearchMoviesConnection(ctx context.Context, query string, sort []models.MovieSort, page models.PageArgs) (*models.Connection[models.Movie], error) {
+	order, err := movieOrder(sort, searchOrder)
+	if err != nil {
+		return nil, err
+	}
+	match := ftsQuery(query)
+	if match == "" {
+		return &models.Connection[models.Movie]{Edges: []models.Edge[models.Movie]{}}, nil
+	}
+
+	total, err := s.countSearch(ctx, match)
+	if err != nil {
+		return nil, err
+	}
+
+	edges, info, err := fetchPage(ctx, s.db, order, page, searchColumnList(), searchFrom,
+		[]interface{}{match}, scanSearchHit)
+	if err != nil {
+		return nil, err
+	}
+	return &models.Connection[models.Movie]{Edges: edges, PageInfo: info, TotalCount: total}, nil
+}
@@ -0,0 +1,24 @@
+package store
+
+import "testing"
+
+func TestFTSQuery(t *testing.T) {
+	for _, tc := range []struct {
+		input, want string
+	}{
+		{"dark knight", "dark knight"},
+		{"Knight*", "knight*"},
+		{`"the dark knight" batman`, `"the dark knight" batman`},
+		{`"dark kni*"`, `"dark kni"*`},
+		{"sci-fi", `"sci fi"`},
+		{"a OR b NOT c", "a or b not c"},
+		{`"unbalanced quote`, `"unbalanced quote"`},
+		{`weird"quote`, `weird "quote"`},
+		{"  -- * !! ", ""},
+		{"Amélie", "amélie"},
+	} {
+		if got := ftsQuery(tc.input); got != tc.want {
+			t.Errorf("ftsQuery(%q) = %q, want %q", tc.input, got, tc.want)
+		}
+	}
+}
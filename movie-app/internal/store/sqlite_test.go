@@ -0,0 +1,853 @@
+package store_test
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"movie-app/internal/database"
+	"movie-app/internal/models"
+	"movie-app/internal/store"
+)
+
+func newTestStore(t *testing.T) *store.SQLiteStore {
+	t.Helper()
+
+	db, err := database.Open(filepath.Join(t.TempDir(), "movies.db"))
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	return store.NewSQLiteStore(db)
+}
+
+// userID returns the ID of the placeholder user named name.
+func userID(t *testing.T, s *store.SQLiteStore, name string) string {
+	t.Helper()
+
+	user, err := s.EnsurePlaceholderUser(context.Background(), name)
+	if err != nil {
+		t.Fatalf("failed to create user %q: %v", name, err)
+	}
+	return user.ID
+}
+
+func TestSQLiteStoreMovieLifecycle(t *testing.T) {
+	ctx := context.Background()
+	s := newTestStore(t)
+
+	movie, err := s.CreateMovie(ctx, models.Movie{Title: "Alien", Year: 1979, Rating: 8.5, Duration: 117, Genre: "Horror"})
+	if err != nil {
+		t.Fatalf("failed to create movie: %v", err)
+	}
+	if movie.ID == "" || movie.Description != "" {
+		t.Fatalf("unexpected created movie: %+v", movie)
+	}
+
+	actor, err := s.CreateActor(ctx, models.Actor{Name: "Sigourney Weaver"})
+	if err != nil {
+		t.Fatalf("failed to create actor: %v", err)
+	}
+	if err := s.AddCastMember(ctx, models.MovieActor{MovieID: movie.ID, ActorID: actor.ID, CharacterName: "Ripley"}); err != nil {
+		t.Fatalf("failed to link actor: %v", err)
+	}
+	if _, err := s.CreateReview(ctx, models.Review{MovieID: movie.ID, UserID: userID(t, s, "alice"), Rating: 5}); err != nil {
+		t.Fatalf("failed to create review: %v", err)
+	}
+
+	movies, total, err := s.ListMovies(ctx, models.MovieFilter{Genre: "Horror"}, nil, 10, 0)
+	if err != nil {
+		t.Fatalf("failed to list movies: %v", err)
+	}
+	if total != 1 || len(movies) != 1 || movies[0].ID != movie.ID {
+		t.Fatalf("unexpected listing: total=%d movies=%+v", total, movies)
+	}
+
+	cast, err := s.CastForMovies(ctx, []string{movie.ID})
+	if err != nil || len(cast[movie.ID]) != 1 || cast[movie.ID][0].CharacterName != "Ripley" {
+		t.Fatalf("expected Ripley in the cast, got %v (err %v)", cast, err)
+	}
+
+	deleted, err := s.DeleteMovie(ctx, movie.ID, 0)
+	if err != nil || !deleted {
+		t.Fatalf("expected movie to be deleted, got %v (err %v)", deleted, err)
+	}
+	if _, err := s.GetMovie(ctx, movie.ID); err != store.ErrNotFound {
+		t.Fatalf("expected ErrNotFound after delete, got %v", err)
+	}
+	reviews, err := s.ReviewsForMovie(ctx, movie.ID)
+	if err != nil || len(reviews) != 0 {
+		t.Fatalf("expected reviews to be removed with the movie, got %v (err %v)", reviews, err)
+	}
+}
+
+func TestSQLiteStoreEnsureDirectorIsIdempotent(t *testing.T) {
+	ctx := context.Background()
+	s := newTestStore(t)
+
+	first, err := s.EnsureDirector(ctx, "Ridley Scott")
+	if err != nil {
+		t.Fatalf("failed to ensure director: %v", err)
+	}
+	second, err := s.EnsureDirector(ctx, "Ridley Scott")
+	if err != nil {
+		t.Fatalf("failed to ensure director: %v", err)
+	}
+	if first != second {
+		t.Fatalf("expected the same director ID, got %s and %s", first, second)
+	}
+}
+
+func TestSQLiteStoreCastBillingOrder(t *testing.T) {
+	ctx := context.Background()
+	s := newTestStore(t)
+
+	movie, err := s.CreateMovie(ctx, models.Movie{Title: "Heat", Year: 1995, Rating: 8.3, Duration: 170})
+	if err != nil {
+		t.Fatalf("failed to create movie: %v", err)
+	}
+
+	var ids []string
+	for _, name := range []string{"Al Pacino", "Robert De Niro", "Val Kilmer"} {
+		actor, err := s.CreateActor(ctx, models.Actor{Name: name})
+		if err != nil {
+			t.Fatalf("failed to create actor: %v", err)
+		}
+		ids = append(ids, actor.ID)
+	}
+
+	billing := func() []string {
+		t.Helper()
+		cast, err := s.CastForMovies(ctx, []string{movie.ID})
+		if err != nil {
+			t.Fatalf("failed to load cast: %v", err)
+		}
+		var names []string
+		for i, m := range cast[movie.ID] {
+			if m.BillingOrder != i+1 {
+				t.Fatalf("expected contiguous billing, got %d at position %d", m.BillingOrder, i)
+			}
+			names = append(names, m.Actor.Name)
+		}
+		return names
+	}
+
+	// Appended, then De Niro inserted at the top.
+	for _, link := range []models.MovieActor{
+		{MovieID: movie.ID, ActorID: ids[0]},
+		{MovieID: movie.ID, ActorID: ids[2]},
+		{MovieID: movie.ID, ActorID: ids[1], BillingOrder: 1},
+	} {
+		if err := s.AddCastMember(ctx, link); err != nil {
+			t.Fatalf("failed to add cast member: %v", err)
+		}
+	}
+	if got := billing(); got[0] != "Robert De Niro" || got[1] != "Al Pacino" || got[2] != "Val Kilmer" {
+		t.Fatalf("unexpected billing after insert: %v", got)
+	}
+
+	if err := s.AddCastMember(ctx, models.MovieActor{MovieID: movie.ID, ActorID: ids[0]}); err != store.ErrDuplicate {
+		t.Fatalf("expected ErrDuplicate, got %v", err)
+	}
+
+	if err := s.ReorderCast(ctx, movie.ID, []string{ids[2], ids[0], ids[1]}); err != nil {
+		t.Fatalf("failed to reorder: %v", err)
+	}
+	if got := billing(); got[0] != "Val Kilmer" || got[2] != "Robert De Niro" {
+		t.Fatalf("unexpected billing after reorder: %v", got)
+	}
+	if err := s.ReorderCast(ctx, movie.ID, []string{ids[0]}); err == nil {
+		t.Fatalf("expected a partial reorder to be rejected")
+	}
+
+	if removed, err := s.RemoveCastMember(ctx, movie.ID, ids[2]); err != nil || !removed {
+		t.Fatalf("expected Val Kilmer to be removed, got %v (err %v)", removed, err)
+	}
+	if got := billing(); len(got) != 2 || got[0] != "Al Pacino" {
+		t.Fatalf("unexpected billing after remove: %v", got)
+	}
+
+	films, err := s.FilmographyForActors(ctx, []string{ids[0]})
+	if err != nil || len(films[ids[0]]) != 1 || films[ids[0]][0].Movie.Title != "Heat" {
+		t.Fatalf("expected Heat in Al Pacino's filmography, got %v (err %v)", films, err)
+	}
+}
+
+func TestSQLiteStoreGenreFiltersMatchWholeGenres(t *testing.T) {
+	ctx := context.Background()
+	s := newTestStore(t)
+
+	for _, m := range []models.Movie{
+		{Title: "Heat", Genre: "Action, Crime"},
+		{Title: "Rush Hour", Genre: "Action-Comedy"},
+		{Title: "Inception", Genre: "sci-fi, action"},
+		{Title: "Amélie", Genre: "Comedy, Romance"},
+	} {
+		if _, err := s.CreateMovie(ctx, m); err != nil {
+			t.Fatalf("failed to create movie: %v", err)
+		}
+	}
+
+	titles := func(filter models.MovieFilter) []string {
+		t.Helper()
+		movies, total, err := s.ListMovies(ctx, filter, nil, 10, 0)
+		if err != nil {
+			t.Fatalf("failed to list movies: %v", err)
+		}
+		if total != len(movies) {
+			t.Fatalf("expected total %d to match the page, got %d", len(movies), total)
+		}
+		var titles []string
+		for _, m := range movies {
+			titles = append(titles, m.Title)
+		}
+		sort.Strings(titles)
+		return titles
+	}
+
+	for _, tc := range []struct {
+		name   string
+		filter models.MovieFilter
+		want   string
+	}{
+		{"genre", models.MovieFilter{Genre: "Action"}, "Heat Inception"},
+		{"any", models.MovieFilter{GenresAny: []string{"crime", "Comedy"}}, "Amélie Heat"},
+		{"all", models.MovieFilter{GenresAll: []string{"Action", "Sci-Fi"}}, "Inception"},
+		{"all repeated", models.MovieFilter{GenresAll: []string{"Action", "ACTION"}}, "Heat Inception"},
+		{"exclude", models.MovieFilter{ExcludeGenres: []string{"Action"}}, "Amélie Rush Hour"},
+		{"combined", models.MovieFilter{GenresAny: []string{"Action", "Comedy"}, ExcludeGenres: []string{"Romance", "Sci-Fi"}}, "Heat"},
+	} {
+		if got := strings.Join(titles(tc.filter), " "); got != tc.want {
+			t.Errorf("%s: expected %q, got %q", tc.name, tc.want, got)
+		}
+	}
+
+	genres, err := s.ListGenres(ctx)
+	if err != nil {
+		t.Fatalf("failed to list genres: %v", err)
+	}
+	counts := map[string]int{}
+	for _, g := range genres {
+		counts[g.Name] = g.MovieCount
+	}
+	if len(genres) != 6 || counts["Action"] != 2 || counts["Action-Comedy"] != 1 || counts["Comedy"] != 1 {
+		t.Fatalf("unexpected genres: %+v", genres)
+	}
+}
+
+func TestSQLiteStoreSearchRanksAndHighlights(t *testing.T) {
+	ctx := context.Background()
+	s := newTestStore(t)
+
+	knight, err := s.CreateMovie(ctx, models.Movie{Title: "The Dark Knight", Description: "Batman faces the Joker.", Genre: "Action"})
+	if err != nil {
+		t.Fatalf("failed to create movie: %v", err)
+	}
+	for _, m := range []models.Movie{
+		{Title: "Batman Begins", Description: "A young Bruce Wayne trains to fight crime in a dark city."},
+		{Title: "Knight and Day", Description: "A spy comedy.", Genre: "Sci-Fi, Comedy"},
+		{Title: "Heat", Description: "A heist thriller."},
+	} {
+		if _, err := s.CreateMovie(ctx, m); err != nil {
+			t.Fatalf("failed to create movie: %v", err)
+		}
+	}
+
+	search := func(query string) []models.Movie {
+		t.Helper()
+		movies, total, err := s.SearchMovies(ctx, query, nil, 10, 0)
+		if err != nil {
+			t.Fatalf("search %q failed: %v", query, err)
+		}
+		if total != len(movies) {
+			t.Fatalf("search %q: expected total %d, got %d", query, len(movies), total)
+		}
+		return movies
+	}
+
+	// A title hit outranks a description hit.
+	movies := search("dark")
+	if len(movies) != 2 || movies[0].Title != "The Dark Knight" {
+		t.Fatalf("expected The Dark Knight first, got %+v", movies)
+	}
+	if h := movies[0].Highlights; len(h) != 1 || h[0].Field != "title" || h[0].Snippet != "The <mark>Dark</mark> Knight" {
+		t.Fatalf("unexpected highlights: %+v", h)
+	}
+
+	if movies := search(`"dark knight"`); len(movies) != 1 {
+		t.Fatalf("expected the phrase to match one movie, got %+v", movies)
+	}
+	if movies := search("kni*"); len(movies) != 2 {
+		t.Fatalf("expected the prefix to match two movies, got %+v", movies)
+	}
+	if movies := search("sci-fi"); len(movies) != 1 || movies[0].Title != "Knight and Day" {
+		t.Fatalf("expected sci-fi to match Knight and Day, got %+v", movies)
+	}
+	// Porter stemming: "trained" finds "trains".
+	if movies := search("trained"); len(movies) != 1 || movies[0].Title != "Batman Begins" {
+		t.Fatalf("expected a stemmed match, got %+v", movies)
+	}
+
+	// The index follows updates, cast changes and deletes.
+	knight.Title = "The Dark Knight Rises"
+	if _, err := s.UpdateMovie(ctx, *knight, 0); err != nil {
+		t.Fatalf("failed to update movie: %v", err)
+	}
+	if movies := search("rises"); len(movies) != 1 {
+		t.Fatalf("expected the updated title to be searchable, got %+v", movies)
+	}
+
+	actor, err := s.CreateActor(ctx, models.Actor{Name: "Heath Ledger"})
+	if err != nil {
+		t.Fatalf("failed to create actor: %v", err)
+	}
+	if err := s.AddCastMember(ctx, models.MovieActor{MovieID: knight.ID, ActorID: actor.ID}); err != nil {
+		t.Fatalf("failed to add cast member: %v", err)
+	}
+	movies = search("ledger")
+	if len(movies) != 1 || movies[0].Highlights[0].Field != "actors" {
+		t.Fatalf("expected to find the movie by its cast, got %+v", movies)
+	}
+
+	if _, err := s.DeleteMovie(ctx, knight.ID, 0); err != nil {
+		t.Fatalf("failed to delete movie: %v", err)
+	}
+	if movies := search("ledger"); len(movies) != 0 {
+		t.Fatalf("expected the deleted movie to leave the index, got %+v", movies)
+	}
+}
+
+func TestSQLiteStoreMoviesConnectionPagesWithoutGaps(t *testing.T) {
+	ctx := context.Background()
+	s := newTestStore(t)
+
+	// Created within the same second, so only the ID breaks ties.
+	var want []string
+	for _, title := range []string{"Alien", "Aliens", "Heat", "Ronin", "Thief"} {
+		movie, err := s.CreateMovie(ctx, models.Movie{Title: title})
+		if err != nil {
+			t.Fatalf("failed to create movie: %v", err)
+		}
+		want = append(want, movie.ID)
+	}
+
+	seen := map[string]int{}
+	page := models.PageArgs{First: 2}
+	for pages := 0; ; pages++ {
+		conn, err := s.MoviesConnection(ctx, models.MovieFilter{}, nil, page)
+		if err != nil {
+			t.Fatalf("failed to page movies: %v", err)
+		}
+		for _, edge := range conn.Edges {
+			seen[edge.Node.ID]++
+		}
+		if pages == 0 {
+			// A movie added mid-listing must not shift the remaining pages.
+			if _, err := s.CreateMovie(ctx, models.Movie{Title: "Collateral"}); err != nil {
+				t.Fatalf("failed to create movie: %v", err)
+			}
+		}
+		if !conn.PageInfo.HasNextPage {
+			break
+		}
+		page.After = *conn.PageInfo.EndCursor
+	}
+	for _, id := range want {
+		if seen[id] != 1 {
+			t.Fatalf("expected movie %s once across pages, got %d (seen %v)", id, seen[id], seen)
+		}
+	}
+
+	all, err := s.MoviesConnection(ctx, models.MovieFilter{}, nil, models.PageArgs{First: 10})
+	if err != nil {
+		t.Fatalf("failed to list movies: %v", err)
+	}
+	last, err := s.MoviesConnection(ctx, models.MovieFilter{}, nil, models.PageArgs{Last: 2})
+	if err != nil {
+		t.Fatalf("failed to page backward: %v", err)
+	}
+	if all.TotalCount != 6 || len(last.Edges) != 2 || !last.PageInfo.HasPreviousPage || last.PageInfo.HasNextPage ||
+		last.Edges[0].Node.ID != all.Edges[4].Node.ID || last.Edges[1].Node.ID != all.Edges[5].Node.ID {
+		t.Fatalf("expected the last 2 of %d movies in order, got %+v", all.TotalCount, last)
+	}
+
+	search, err := s.SearchMoviesConnection(ctx, "alien*", nil, models.PageArgs{First: 1})
+	if err != nil || search.TotalCount != 2 || !search.PageInfo.HasNextPage {
+		t.Fatalf("expected 2 search matches, got %+v (err %v)", search, err)
+	}
+	_, err = s.MoviesConnection(ctx, models.MovieFilter{}, nil, models.PageArgs{First: 2, After: *search.PageInfo.EndCursor})
+	if err != store.ErrInvalidCursor {
+		t.Fatalf("expected a search cursor to be rejected by movies, got %v", err)
+	}
+}
+
+func TestSQLiteStoreSortsMovies(t *testing.T) {
+	ctx := context.Background()
+	s := newTestStore(t)
+
+	ids := map[string]string{}
+	for _, m := range []models.Movie{
+		{Title: "heat", Year: 1995},
+		{Title: "Alien", Year: 1979},
+		{Title: "Ronin", Year: 1998},
+		{Title: "Aliens", Year: 1986},
+		{Title: "Thief", Year: 1981},
+	} {
+		movie, err := s.CreateMovie(ctx, m)
+		if err != nil {
+			t.Fatalf("failed to create movie: %v", err)
+		}
+		ids[m.Title] = movie.ID
+	}
+	for _, r := range []struct {
+		title, user string
+		rating      int
+	}{{"Alien", "alice", 5}, {"Alien", "bob", 4}, {"Aliens", "alice", 5}, {"Ronin", "alice", 3}} {
+		if _, err := s.CreateReview(ctx, models.Review{MovieID: ids[r.title], UserID: userID(t, s, r.user), Rating: r.rating}); err != nil {
+			t.Fatalf("failed to create review: %v", err)
+		}
+	}
+
+	titles := func(movies []models.Movie) string {
+		var names []string
+		for _, m := range movies {
+			names = append(names, m.Title)
+		}
+		return strings.Join(names, ",")
+	}
+
+	for _, tc := range []struct {
+		sort []models.MovieSort
+		want string
+	}{
+		{[]models.MovieSort{{Field: "title"}}, "Alien,Aliens,heat,Ronin,Thief"},
+		{[]models.MovieSort{{Field: "year", Desc: true}}, "Ronin,heat,Aliens,Thief,Alien"},
+		// Unreviewed movies come last, then fall through to the title.
+		{[]models.MovieSort{{Field: "average_review_score", Desc: true}, {Field: "title"}}, "Aliens,Alien,Ronin,heat,Thief"},
+		{[]models.MovieSort{{Field: "average_review_score"}, {Field: "title", Desc: true}}, "Ronin,Alien,Aliens,Thief,heat"},
+		{[]models.MovieSort{{Field: "review_count", Desc: true}, {Field: "year"}}, "Alien,Aliens,Ronin,Thief,heat"},
+	} {
+		movies, _, err := s.ListMovies(ctx, models.MovieFilter{}, tc.sort, 10, 0)
+		if err != nil {
+			t.Fatalf("failed to list movies by %v: %v", tc.sort, err)
+		}
+		if got := titles(movies); got != tc.want {
+			t.Fatalf("sorted by %v: expected %s, got %s", tc.sort, tc.want, got)
+		}
+
+		// Cursor pages must follow the same order.
+		var paged []models.Movie
+		page := models.PageArgs{First: 2}
+		for {
+			conn, err := s.MoviesConnection(ctx, models.MovieFilter{}, tc.sort, page)
+			if err != nil {
+				t.Fatalf("failed to page movies by %v: %v", tc.sort, err)
+			}
+			for _, edge := range conn.Edges {
+				paged = append(paged, edge.Node)
+			}
+			if !conn.PageInfo.HasNextPage {
+				break
+			}
+			page.After = *conn.PageInfo.EndCursor
+		}
+		if got := titles(paged); got != tc.want {
+			t.Fatalf("paged by %v: expected %s, got %s", tc.sort, tc.want, got)
+		}
+	}
+
+	movies, _, err := s.SearchMovies(ctx, "alien*", []models.MovieSort{{Field: "year", Desc: true}}, 10, 0)
+	if err != nil || titles(movies) != "Aliens,Alien" {
+		t.Fatalf("expected search results newest first, got %v (err %v)", movies, err)
+	}
+
+	if _, _, err := s.ListMovies(ctx, models.MovieFilter{}, []models.MovieSort{{Field: "title; DROP TABLE movies"}}, 10, 0); err == nil {
+		t.Fatalf("expected an unknown sort field to be rejected")
+	}
+
+	byTitle, err := s.MoviesConnection(ctx, models.MovieFilter{}, []models.MovieSort{{Field: "title"}}, models.PageArgs{First: 1})
+	if err != nil {
+		t.Fatalf("failed to page movies: %v", err)
+	}
+	_, err = s.MoviesConnection(ctx, models.MovieFilter{}, []models.MovieSort{{Field: "title", Desc: true}},
+		models.PageArgs{First: 1, After: *byTitle.PageInfo.EndCursor})
+	if err != store.ErrInvalidCursor {
+		t.Fatalf("expected a cursor from another sort to be rejected, got %v", err)
+	}
+}
+
+func TestSQLiteStoreReviewStatsFollowReviews(t *testing.T) {
+	ctx := context.Background()
+	s := newTestStore(t)
+
+	alien, err := s.CreateMovie(ctx, models.Movie{Title: "Alien"})
+	if err != nil {
+		t.Fatalf("failed to create movie: %v", err)
+	}
+	heat, err := s.CreateMovie(ctx, models.Movie{Title: "Heat"})
+	if err != nil {
+		t.Fatalf("failed to create movie: %v", err)
+	}
+
+	stats, err := s.ReviewStatsForMovies(ctx, []string{alien.ID})
+	if err != nil || stats[alien.ID].Count != 0 || stats[alien.ID].Average != nil || stats[alien.ID].WeightedScore != nil {
+		t.Fatalf("expected empty stats before any review, got %+v (err %v)", stats, err)
+	}
+
+	var reviewIDs []string
+	for i, rating := range []int{5, 5, 4} {
+		review, err := s.CreateReview(ctx, models.Review{MovieID: alien.ID, UserID: userID(t, s, fmt.Sprint("user", i)), Rating: rating})
+		if err != nil {
+			t.Fatalf("failed to create review: %v", err)
+		}
+		reviewIDs = append(reviewIDs, review.ID)
+	}
+	if _, err := s.CreateReview(ctx, models.Review{MovieID: heat.ID, UserID: userID(t, s, "bob"), Rating: 1}); err != nil {
+		t.Fatalf("failed to create review: %v", err)
+	}
+	if _, err := s.DeleteReview(ctx, reviewIDs[1]); err != nil {
+		t.Fatalf("failed to delete review: %v", err)
+	}
+
+	stats, err = s.ReviewStatsForMovies(ctx, []string{alien.ID, heat.ID})
+	if err != nil {
+		t.Fatalf("failed to load review stats: %v", err)
+	}
+	got := stats[alien.ID]
+	if got.Count != 2 || got.Average == nil || *got.Average != 4.5 || fmt.Sprint(got.Histogram) != "[0 0 0 1 1]" {
+		t.Fatalf("unexpected stats for Alien: %+v", got)
+	}
+	// Mean of all reviews is (5+4+1)/3; Alien blends its 2 reviews with 5 at that mean.
+	want := (9 + 5*(10.0/3)) / 7
+	if got.WeightedScore == nil || math.Abs(*got.WeightedScore-want) > 1e-9 {
+		t.Fatalf("expected weighted score %v, got %v", want, got.WeightedScore)
+	}
+
+	movies, _, err := s.ListMovies(ctx, models.MovieFilter{MinWeightedScore: 3.5}, nil, 10, 0)
+	if err != nil || len(movies) != 1 || movies[0].ID != alien.ID {
+		t.Fatalf("expected only Alien above a 3.5 weighted score, got %v (err %v)", movies, err)
+	}
+	movies, _, err = s.ListMovies(ctx, models.MovieFilter{MinReviewCount: 1},
+		[]models.MovieSort{{Field: "weighted_score"}}, 10, 0)
+	if err != nil || len(movies) != 2 || movies[0].ID != heat.ID {
+		t.Fatalf("expected Heat first by weighted score, got %v (err %v)", movies, err)
+	}
+
+	if _, err := s.DeleteMovie(ctx, heat.ID, 0); err != nil {
+		t.Fatalf("failed to delete movie: %v", err)
+	}
+	stats, err = s.ReviewStatsForMovies(ctx, []string{heat.ID})
+	if err != nil || stats[heat.ID].Count != 0 {
+		t.Fatalf("expected deleted movie's stats to be gone, got %+v (err %v)", stats, err)
+	}
+}
+
+func TestSQLiteStoreMovieVersionChecks(t *testing.T) {
+	ctx := context.Background()
+	s := newTestStore(t)
+
+	movie, err := s.CreateMovie(ctx, models.Movie{Title: "Alien"})
+	if err != nil || movie.Version != 1 {
+		t.Fatalf("expected a new movie at version 1, got %+v (err %v)", movie, err)
+	}
+
+	movie.Title = "Alien (1979)"
+	updated, err := s.UpdateMovie(ctx, *movie, 1)
+	if err != nil || updated.Version != 2 {
+		t.Fatalf("expected the update to reach version 2, got %+v (err %v)", updated, err)
+	}
+
+	if _, err := s.UpdateMovie(ctx, *movie, 1); err != store.ErrVersionConflict {
+		t.Fatalf("expected a stale update to conflict, got %v", err)
+	}
+	if _, err := s.DeleteMovie(ctx, movie.ID, 1); err != store.ErrVersionConflict {
+		t.Fatalf("expected a stale delete to conflict, got %v", err)
+	}
+	if _, err := s.UpdateMovie(ctx, models.Movie{ID: "missing", Title: "Heat"}, 1); err != store.ErrNotFound {
+		t.Fatalf("expected an unknown movie to be not found, got %v", err)
+	}
+
+	// Without an expected version writes are unconditional.
+	if updated, err := s.UpdateMovie(ctx, *movie, 0); err != nil || updated.Version != 3 {
+		t.Fatalf("expected an unchecked update to reach version 3, got %+v (err %v)", updated, err)
+	}
+	if deleted, err := s.DeleteMovie(ctx, movie.ID, 3); err != nil || !deleted {
+		t.Fatalf("expected the current version to be deleted, got %v (err %v)", deleted, err)
+	}
+}
+
+func TestSQLiteStoreMovieRevisions(t *testing.T) {
+	ctx := context.Background()
+	s := newTestStore(t)
+
+	movie, err := s.CreateMovie(ctx, models.Movie{Title: "Alien", Year: 1979})
+	if err != nil {
+		t.Fatalf("failed to create movie: %v", err)
+	}
+	created, err := s.RecordMovieRevision(ctx, models.MovieRevision{MovieID: movie.ID, Mutation: "createMovie", Actor: "alice", After: movie})
+	if err != nil {
+		t.Fatalf("failed to record revision: %v", err)
+	}
+
+	time.Sleep(5 * time.Millisecond)
+	edited := *movie
+	edited.Title = "Alien (Director's Cut)"
+	if _, err := s.RecordMovieRevision(ctx, models.MovieRevision{MovieID: movie.ID, Mutation: "patchMovie", Actor: "bob", Before: movie, After: &edited}); err != nil {
+		t.Fatalf("failed to record revision: %v", err)
+	}
+	time.Sleep(5 * time.Millisecond)
+	if _, err := s.RecordMovieRevision(ctx, models.MovieRevision{MovieID: movie.ID, Mutation: "deleteMovie", Actor: "bob", Before: &edited}); err != nil {
+		t.Fatalf("failed to record revision: %v", err)
+	}
+
+	history, err := s.RevisionsForMovies(ctx, []string{movie.ID})
+	if err != nil || len(history[movie.ID]) != 3 {
+		t.Fatalf("expected 3 revisions, got %v (err %v)", history, err)
+	}
+	if got := history[movie.ID]; got[0].Mutation != "deleteMovie" || got[0].After != nil || got[2].Before != nil ||
+		got[1].After.Title != "Alien (Director's Cut)" || got[2].After.Year != 1979 {
+		t.Fatalf("expected history newest first with snapshots, got %+v", got)
+	}
+
+	at, err := s.MovieAt(ctx, movie.ID, created.CreatedAt)
+	if err != nil || at.Title != "Alien" {
+		t.Fatalf("expected the original title at creation, got %+v (err %v)", at, err)
+	}
+	if _, err := s.MovieAt(ctx, movie.ID, created.CreatedAt.Add(-time.Second)); err != store.ErrNotFound {
+		t.Fatalf("expected no movie before its creation, got %v", err)
+	}
+	if _, err := s.MovieAt(ctx, movie.ID, time.Now().Add(time.Second)); err != store.ErrNotFound {
+		t.Fatalf("expected no movie after its deletion, got %v", err)
+	}
+}
+
+func TestSQLiteStoreTrashAndRestore(t *testing.T) {
+	ctx := context.Background()
+	s := newTestStore(t)
+
+	movie, err := s.CreateMovie(ctx, models.Movie{Title: "Alien", Year: 1979, Rating: 8.5, Duration: 117, Genre: "Horror"})
+	if err != nil {
+		t.Fatalf("failed to create movie: %v", err)
+	}
+	actor, err := s.CreateActor(ctx, models.Actor{Name: "Sigourney Weaver"})
+	if err != nil {
+		t.Fatalf("failed to create actor: %v", err)
+	}
+	if err := s.AddCastMember(ctx, models.MovieActor{MovieID: movie.ID, ActorID: actor.ID}); err != nil {
+		t.Fatalf("failed to link actor: %v", err)
+	}
+	kept, err := s.CreateReview(ctx, models.Review{MovieID: movie.ID, UserID: userID(t, s, "alice"), Rating: 5})
+	if err != nil {
+		t.Fatalf("failed to create review: %v", err)
+	}
+	trashed, err := s.CreateReview(ctx, models.Review{MovieID: movie.ID, UserID: userID(t, s, "bob"), Rating: 3})
+	if err != nil {
+		t.Fatalf("failed to create review: %v", err)
+	}
+
+	reviewCount := func() int {
+		t.Helper()
+		stats, err := s.ReviewStatsForMovies(ctx, []string{movie.ID})
+		if err != nil {
+			t.Fatalf("failed to load review stats: %v", err)
+		}
+		return stats[movie.ID].Count
+	}
+	searchTotal := func(query string) int {
+		t.Helper()
+		_, total, err := s.SearchMovies(ctx, query, nil, 10, 0)
+		if err != nil {
+			t.Fatalf("failed to search %q: %v", query, err)
+		}
+		return total
+	}
+
+	if deleted, err := s.DeleteReview(ctx, trashed.ID); err != nil || !deleted {
+		t.Fatalf("expected review to be deleted, got %v (err %v)", deleted, err)
+	}
+	time.Sleep(5 * time.Millisecond)
+	if deleted, err := s.DeleteMovie(ctx, movie.ID, 0); err != nil || !deleted {
+		t.Fatalf("expected movie to be deleted, got %v (err %v)", deleted, err)
+	}
+
+	if _, total, _ := s.ListMovies(ctx, models.MovieFilter{}, nil, 10, 0); total != 0 {
+		t.Fatalf("expected no movies listed, got %d", total)
+	}
+	if total := searchTotal("alien"); total != 0 {
+		t.Fatalf("expected trashed movie to leave the search index, got %d", total)
+	}
+	genres, err := s.ListGenres(ctx)
+	if err != nil || len(genres) != 1 || genres[0].MovieCount != 0 {
+		t.Fatalf("expected Horror to count no movies, got %+v (err %v)", genres, err)
+	}
+	if n := reviewCount(); n != 0 {
+		t.Fatalf("expected trashed reviews not to count, got %d", n)
+	}
+
+	items, total, err := s.Trash(ctx, nil, 10, 0)
+	if err != nil {
+		t.Fatalf("failed to list trash: %v", err)
+	}
+	if total != 2 || len(items) != 2 ||
+		items[0].Kind != models.TrashMovie || items[0].Movie == nil || items[0].Movie.Title != "Alien" ||
+		items[1].Kind != models.TrashReview || items[1].Review == nil || items[1].Review.UserName != "bob" {
+		t.Fatalf("expected the movie then bob's review in the trash, got %d %+v", total, items)
+	}
+	if items[0].DeletedAt.Before(items[1].DeletedAt) || time.Since(items[0].DeletedAt) > time.Minute {
+		t.Fatalf("unexpected deleted_at: %v, %v", items[0].DeletedAt, items[1].DeletedAt)
+	}
+	if _, err := s.RestoreReview(ctx, trashed.ID); err != store.ErrNotFound {
+		t.Fatalf("expected a review of a trashed movie not to be restorable, got %v", err)
+	}
+
+	restored, err := s.RestoreMovie(ctx, movie.ID)
+	if err != nil || restored.Title != "Alien" {
+		t.Fatalf("failed to restore movie: %+v (err %v)", restored, err)
+	}
+	reviews, err := s.ReviewsForMovie(ctx, movie.ID)
+	if err != nil || len(reviews) != 1 || reviews[0].ID != kept.ID {
+		t.Fatalf("expected only the review deleted with the movie back, got %+v (err %v)", reviews, err)
+	}
+	if n := reviewCount(); n != 1 {
+		t.Fatalf("expected 1 counted review after restore, got %d", n)
+	}
+	if total := searchTotal("weaver"); total != 1 {
+		t.Fatalf("expected restored movie to be searchable by its cast, got %d", total)
+	}
+
+	if deleted, err := s.DeleteActor(ctx, actor.ID); err != nil || !deleted {
+		t.Fatalf("expected actor to be deleted, got %v (err %v)", deleted, err)
+	}
+	if cast, _ := s.CastForMovies(ctx, []string{movie.ID}); len(cast[movie.ID]) != 0 {
+		t.Fatalf("expected trashed actor to leave the cast, got %+v", cast)
+	}
+	if total := searchTotal("weaver"); total != 0 {
+		t.Fatalf("expected trashed actor to leave the search index, got %d", total)
+	}
+	if _, err := s.RestoreActor(ctx, actor.ID); err != nil {
+		t.Fatalf("failed to restore actor: %v", err)
+	}
+	if cast, _ := s.CastForMovies(ctx, []string{movie.ID}); len(cast[movie.ID]) != 1 {
+		t.Fatalf("expected restored actor back in the cast, got %+v", cast)
+	}
+	if _, err := s.RestoreReview(ctx, trashed.ID); err != nil {
+		t.Fatalf("failed to restore review: %v", err)
+	}
+	if n := reviewCount(); n != 2 {
+		t.Fatalf("expected 2 counted reviews, got %d", n)
+	}
+
+	if _, err := s.DeleteMovie(ctx, movie.ID, 0); err != nil {
+		t.Fatalf("failed to delete movie: %v", err)
+	}
+	counts, err := s.PurgeDeleted(ctx, time.Now().Add(-time.Hour))
+	if err != nil || counts != (store.PurgeCounts{}) {
+		t.Fatalf("expected nothing old enough to purge, got %+v (err %v)", counts, err)
+	}
+	counts, err = s.PurgeDeleted(ctx, time.Now().Add(time.Second))
+	if err != nil || counts != (store.PurgeCounts{Movies: 1, Reviews: 2}) {
+		t.Fatalf("expected the movie and its reviews to be purged, got %+v (err %v)", counts, err)
+	}
+	if _, total, _ := s.Trash(ctx, nil, 10, 0); total != 0 {
+		t.Fatalf("expected an empty trash after purging, got %d", total)
+	}
+	if _, err := s.RestoreMovie(ctx, movie.ID); err != store.ErrNotFound {
+		t.Fatalf("expected a purged movie not to be restorable, got %v", err)
+	}
+}
+
+func TestSQLiteStoreAPIKeys(t *testing.T) {
+	ctx := context.Background()
+	s := newTestStore(t)
+
+	key, err := s.CreateAPIKey(ctx, models.APIKey{Name: "importer", Prefix: "mvk_abc123", KeyHash: "hash-1", Role: "editor"})
+	if err != nil || key.ID == "" || key.CreatedAt.IsZero() || key.RevokedAt != nil {
+		t.Fatalf("unexpected created key %+v (err %v)", key, err)
+	}
+	if _, err := s.CreateAPIKey(ctx, models.APIKey{Name: "importer", Prefix: "mvk_def456", KeyHash: "hash-2"}); err != store.ErrDuplicate {
+		t.Fatalf("expected a taken name to be ErrDuplicate, got %v", err)
+	}
+
+	found, err := s.APIKeyByHash(ctx, "hash-1")
+	if err != nil || found.Name != "importer" || found.Role != "editor" {
+		t.Fatalf("expected to find the key by hash, got %+v (err %v)", found, err)
+	}
+
+	if revoked, err := s.RevokeAPIKey(ctx, "importer"); err != nil || !revoked {
+		t.Fatalf("expected the key to be revoked, got %v (err %v)", revoked, err)
+	}
+	if revoked, err := s.RevokeAPIKey(ctx, "importer"); err != nil || revoked {
+		t.Fatalf("expected a second revoke to do nothing, got %v (err %v)", revoked, err)
+	}
+	if _, err := s.APIKeyByHash(ctx, "hash-1"); err != store.ErrNotFound {
+		t.Fatalf("expected a revoked key to be ErrNotFound, got %v", err)
+	}
+
+	keys, err := s.ListAPIKeys(ctx)
+	if err != nil || len(keys) != 1 || keys[0].RevokedAt == nil {
+		t.Fatalf("expected the revoked key to be listed, got %+v (err %v)", keys, err)
+	}
+}
+
+func TestSQLiteStoreUsersAndReviewUniqueness(t *testing.T) {
+	ctx := context.Background()
+	s := newTestStore(t)
+
+	alice, err := s.EnsureUser(ctx, "alice")
+	if err != nil || alice.Name != "alice" || alice.Subject != "alice" {
+		t.Fatalf("unexpected user %+v (err %v)", alice, err)
+	}
+	if again, err := s.EnsureUser(ctx, "alice"); err != nil || again.ID != alice.ID {
+		t.Fatalf("expected EnsureUser to return the same user, got %+v (err %v)", again, err)
+	}
+	if placeholder := userID(t, s, "alice"); placeholder == alice.ID || placeholder != userID(t, s, "alice") {
+		t.Fatalf("expected one placeholder alice apart from the signed-in one")
+	}
+
+	movie, err := s.CreateMovie(ctx, models.Movie{Title: "Alien", Year: 1979})
+	if err != nil {
+		t.Fatalf("failed to create movie: %v", err)
+	}
+	first, err := s.CreateReview(ctx, models.Review{MovieID: movie.ID, UserID: alice.ID, Rating: 5})
+	if err != nil || first.UserName != "alice" {
+		t.Fatalf("unexpected review %+v (err %v)", first, err)
+	}
+	if _, err := s.CreateReview(ctx, models.Review{MovieID: movie.ID, UserID: alice.ID, Rating: 4}); err != store.ErrDuplicate {
+		t.Fatalf("expected a second review by the same user to be ErrDuplicate, got %v", err)
+	}
+
+	upserted, err := s.UpsertReview(ctx, models.Review{MovieID: movie.ID, UserID: alice.ID, Rating: 2, Comment: "Slow."})
+	if err != nil || upserted.ID != first.ID || upserted.Rating != 2 || upserted.Comment != "Slow." {
+		t.Fatalf("expected the review to be updated in place, got %+v (err %v)", upserted, err)
+	}
+
+	if _, err := s.DeleteReview(ctx, first.ID); err != nil {
+		t.Fatalf("failed to delete review: %v", err)
+	}
+	second, err := s.UpsertReview(ctx, models.Review{MovieID: movie.ID, UserID: alice.ID, Rating: 4})
+	if err != nil || second.ID == first.ID {
+		t.Fatalf("expected a new review once the old one is trashed, got %+v (err %v)", second, err)
+	}
+	if _, err := s.RestoreReview(ctx, first.ID); err != store.ErrDuplicate {
+		t.Fatalf("expected restoring the old review to be ErrDuplicate, got %v", err)
+	}
+
+	renamed, err := s.UpdateUser(ctx, models.User{ID: alice.ID, Name: "Alice L.", Bio: "Sci-fi fan"})
+	if err != nil || renamed.Name != "Alice L." || renamed.Bio != "Sci-fi fan" {
+		t.Fatalf("unexpected updated user %+v (err %v)", renamed, err)
+	}
+	byUser, err := s.ReviewsForUsers(ctx, []string{alice.ID})
+	if err != nil || len(byUser[alice.ID]) != 1 || byUser[alice.ID][0].UserName != "Alice L." {
+		t.Fatalf("expected alice's live review under her new name, got %+v (err %v)", byUser, err)
+	}
+	stats, err := s.ReviewStatsForMovies(ctx, []string{movie.ID})
+	if err != nil || stats[movie.ID].Count != 1 || *stats[movie.ID].Average != 4 {
+		t.Fatalf("expected stats for the one live review, got %+v (err %v)", stats, err)
+	}
+}
@@ -0,0 +1,179 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"movie-app/internal/models"
+)
+
+// trashedRows lists every trashed row as (kind, id, deleted_at). +deleted_at
+// keeps the stored text rather than letting the driver parse it. A review
+// trashed with its movie has the movie's deleted_at and is left out.
+const trashedRows = `
+	SELECT 'MOVIE' AS kind, id, +deleted_at AS deleted_at FROM movies WHERE deleted_at IS NOT NULL
+	UNION ALL
+	SELECT 'ACTOR', id, +deleted_at FROM actors WHERE deleted_at IS NOT NULL
+	UNION ALL
+	SELECT 'REVIEW', r.id, +r.deleted_at FROM reviews r
+	WHERE r.deleted_at IS NOT NULL AND NOT EXISTS (
+		SELECT 1 FROM movies m WHERE m.id = r.movie_id AND m.deleted_at = r.deleted_at)`
+
+func (s *SQLiteStore) Trash(ctx context.Context, kinds []string, limit, offset int) ([]models.TrashItem, int, error) {
+	where := ""
+	args := []interface{}{}
+	if len(kinds) > 0 {
+		where = " WHERE kind IN (" + placeholders(len(kinds)) + ")"
+		args = stringArgs(kinds)
+	}
+
+	var total int
+	err := s.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM ("+trashedRows+")"+where, args...).Scan(&total)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to count trash: %v", err)
+	}
+
+	rows, err := s.db.QueryContext(ctx,
+		"SELECT kind, id, deleted_at FROM ("+trashedRows+")"+where+
+			" ORDER BY deleted_at DESC, kind, id LIMIT ? OFFSET ?",
+		append(args, limit, offset)...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to query trash: %v", err)
+	}
+	defer rows.Close()
+
+	items := []models.TrashItem{}
+	ids := map[string][]string{}
+	for rows.Next() {
+		var item models.TrashItem
+		var deletedAt string
+		if err := rows.Scan(&item.Kind, &item.ID, &deletedAt); err != nil {
+			return nil, 0, fmt.Errorf("failed to scan trash item: %v", err)
+		}
+		// Parse accepts the fractional seconds without them in the layout.
+		if item.DeletedAt, err = time.Parse(time.DateTime, deletedAt); err != nil {
+			return nil, 0, fmt.Errorf("failed to parse deleted_at %q: %v", deletedAt, err)
+		}
+		items = append(items, item)
+		ids[item.Kind] = append(ids[item.Kind], item.ID)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, fmt.Errorf("failed to query trash: %v", err)
+	}
+
+	if err := s.loadTrashItems(ctx, items, ids); err != nil {
+		return nil, 0, err
+	}
+	return items, total, nil
+}
+
+// loadTrashItems fills in the trashed rows of items, whose IDs are grouped
+// by kind in ids.
+func (s *SQLiteStore) loadTrashItems(ctx context.Context, items []models.TrashItem, ids map[string][]string) error {
+	movies := map[string]*models.Movie{}
+	actors := map[string]*models.Actor{}
+	reviews := map[string]*models.Review{}
+
+	if len(ids[models.TrashMovie]) > 0 {
+		rows, err := s.db.QueryContext(ctx,
+			"SELECT "+movieColumns+" FROM movies WHERE id IN ("+placeholders(len(ids[models.TrashMovie]))+")",
+			stringArgs(ids[models.TrashMovie])...)
+		if err != nil {
+			return fmt.Errorf("failed to query trashed movies: %v", err)
+		}
+		list, err := scanMovies(rows)
+		if err != nil {
+			return err
+		}
+		for i := range list {
+			movies[list[i].ID] = &list[i]
+		}
+	}
+
+	if len(ids[models.TrashActor]) > 0 {
+		rows, err := s.db.QueryContext(ctx, `
+			SELECT id, name, birth_date, nationality, biography, profile_url
+			FROM actors WHERE id IN (`+placeholders(len(ids[models.TrashActor]))+`)`,
+			stringArgs(ids[models.TrashActor])...)
+		if err != nil {
+			return fmt.Errorf("failed to query trashed actors: %v", err)
+		}
+		defer rows.Close()
+		for rows.Next() {
+			actor, err := scanActor(rows)
+			if err != nil {
+				return fmt.Errorf("failed to scan actor: %v", err)
+			}
+			actors[actor.ID] = actor
+		}
+		if err := rows.Err(); err != nil {
+			return fmt.Errorf("failed to query trashed actors: %v", err)
+		}
+	}
+
+	if len(ids[models.TrashReview]) > 0 {
+		rows, err := s.db.QueryContext(ctx, `
+			SELECT `+reviewColumns+`
+			FROM reviews WHERE id IN (`+placeholders(len(ids[models.TrashReview]))+`)`,
+			stringArgs(ids[models.TrashReview])...)
+		if err != nil {
+			return fmt.Errorf("failed to query trashed reviews: %v", err)
+		}
+		defer rows.Close()
+		for rows.Next() {
+			review, err := scanReview(rows)
+			if err != nil {
+				return fmt.Errorf("failed to scan review: %v", err)
+			}
+			reviews[review.ID] = review
+		}
+		if err := rows.Err(); err != nil {
+			return fmt.Errorf("failed to query trashed reviews: %v", err)
+		}
+	}
+
+	for i := range items {
+		switch items[i].Kind {
+		case models.TrashMovie:
+			items[i].Movie = movies[items[i].ID]
+		case models.TrashActor:
+			items[i].Actor = actors[items[i].ID]
+		case models.TrashReview:
+			items[i].Review = reviews[items[i].ID]
+		}
+	}
+	return nil
+}
+
+func (s *SQLiteStore) PurgeDeleted(ctx context.Context, before time.Time) (PurgeCounts, error) {
+	var counts PurgeCounts
+	cutoff := before.UTC().Format(timestampLayout)
+
+	const purgedMovies = "SELECT id FROM movies WHERE deleted_at < ?"
+	const purgedActors = "SELECT id FROM actors WHERE deleted_at < ?"
+	for _, stmt := range []struct {
+		query string
+		args  []interface{}
+		count *int
+	}{
+		{"DELETE FROM movie_actors WHERE movie_id IN (" + purgedMovies + ") OR actor_id IN (" + purgedActors + ")",
+			[]interface{}{cutoff, cutoff}, nil},
+		{"DELETE FROM movie_directors WHERE movie_id IN (" + purgedMovies + ")", []interface{}{cutoff}, nil},
+		{"DELETE FROM movie_genres WHERE movie_id IN (" + purgedMovies + ")", []interface{}{cutoff}, nil},
+		{"DELETE FROM reviews WHERE deleted_at < ? OR movie_id IN (" + purgedMovies + ")",
+			[]interface{}{cutoff, cutoff}, &counts.Reviews},
+		{"DELETE FROM movies WHERE deleted_at < ?", []interface{}{cutoff}, &counts.Movies},
+		{"DELETE FROM actors WHERE deleted_at < ?", []interface{}{cutoff}, &counts.Actors},
+	} {
+		result, err := s.db.ExecContext(ctx, stmt.query, stmt.args...)
+		if err != nil {
+			return PurgeCounts{}, fmt.Errorf("failed to purge trash: %v", err)
+		}
+		if stmt.count != nil {
+			n, _ := result.RowsAffected()
+			*stmt.count = int(n)
+		}
+	}
+	return counts, nil
+}
@@ -0,0 +1,103 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"movie-app/internal/models"
+
+	"github.com/google/uuid"
+	"github.com/mattn/go-sqlite3"
+)
+
+const userColumns = `id, subject, name, bio, avatar_url, created_at`
+
+func scanUser(row scanner) (*models.User, error) {
+	var user models.User
+	var subject, bio, avatarURL sql.NullString
+	if err := row.Scan(&user.ID, &subject, &user.Name, &bio, &avatarURL, &user.CreatedAt); err != nil {
+		return nil, err
+	}
+	user.Subject = subject.String
+	user.Bio = bio.String
+	user.AvatarURL = avatarURL.String
+	return &user, nil
+}
+
+func (s *SQLiteStore) GetUser(ctx context.Context, id string) (*models.User, error) {
+	return s.userWhere(ctx, "id = ?", id)
+}
+
+func (s *SQLiteStore) userWhere(ctx context.Context, cond string, args ...interface{}) (*models.User, error) {
+	user, err := scanUser(s.db.QueryRowContext(ctx, "SELECT "+userColumns+" FROM users WHERE "+cond, args...))
+	if err == sql.ErrNoRows {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user: %v", err)
+	}
+	return user, nil
+}
+
+func (s *SQLiteStore) UsersByIDs(ctx context.Context, ids []string) (map[string]*models.User, error) {
+	users := make(map[string]*models.User, len(ids))
+	if len(ids) == 0 {
+		return users, nil
+	}
+
+	rows, err := s.db.QueryContext(ctx,
+		"SELECT "+userColumns+" FROM users WHERE id IN ("+placeholders(len(ids))+")", stringArgs(ids)...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query users: %v", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		user, err := scanUser(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan user: %v", err)
+		}
+		users[user.ID] = user
+	}
+	return users, rows.Err()
+}
+
+func (s *SQLiteStore) EnsureUser(ctx context.Context, subject string) (*models.User, error) {
+	return s.ensureUser(ctx, "subject = ?", subject,
+		"INSERT INTO users (id, subject, name) VALUES (?, ?, ?)", subject, subject)
+}
+
+func (s *SQLiteStore) EnsurePlaceholderUser(ctx context.Context, name string) (*models.User, error) {
+	return s.ensureUser(ctx, "subject IS NULL AND name = ?", name,
+		"INSERT INTO users (id, name) VALUES (?, ?)", name)
+}
+
+// ensureUser returns the user matching cond, inserting it if there is none.
+// A concurrent insert of the same user loses to the unique indexes and
+// finds the winner's row instead.
+func (s *SQLiteStore) ensureUser(ctx context.Context, cond string, key interface{}, insert string, values ...interface{}) (*models.User, error) {
+	user, err := s.userWhere(ctx, cond, key)
+	if err != ErrNotFound {
+		return user, err
+	}
+
+	_, err = s.db.ExecContext(ctx, insert, append([]interface{}{uuid.New().String()}, values...)...)
+	if err != nil && !isConstraintError(err, sqlite3.ErrConstraintUnique) {
+		return nil, fmt.Errorf("failed to create user: %v", err)
+	}
+	return s.userWhere(ctx, cond, key)
+}
+
+func (s *SQLiteStore) UpdateUser(ctx context.Context, user models.User) (*models.User, error) {
+	result, err := s.db.ExecContext(ctx,
+		"UPDATE users SET name = ?, bio = ?, avatar_url = ? WHERE id = ?",
+		user.Name, nullable(user.Bio), nullable(user.AvatarURL), user.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update user: %v", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return nil, ErrNotFound
+	}
+	return s.GetUser(ctx, user.ID)
+}
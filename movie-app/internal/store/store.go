@@ -0,0 +1,230 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"movie-app/internal/models"
+)
+
+var (
+	// ErrNotFound is returned when a lookup by ID matches no row.
+	ErrNotFound = errors.New("not found")
+	// ErrDuplicate is returned when an insert collides with an existing row.
+	ErrDuplicate = errors.New("already exists")
+	// ErrInvalidCursor is returned for a pagination cursor that was not
+	// issued for the requested ordering.
+	ErrInvalidCursor = errors.New("invalid cursor")
+	// ErrVersionConflict is returned when a write names a version of a row
+	// that is no longer current.
+	ErrVersionConflict = errors.New("version conflict")
+)
+
+// ValidationError is returned when a write's input does not fit the stored
+// data, such as a cast order that leaves out part of the cast. Field is the
+// path of the offending input.
+type ValidationError struct {
+	Field   string
+	Message string
+}
+
+func (e *ValidationError) Error() string {
+	return e.Message
+}
+
+// DBTX is the subset of *sql.DB (and *sql.Tx) the SQLite store needs.
+type DBTX interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+type MovieStore interface {
+	GetMovie(ctx context.Context, id string) (*models.Movie, error)
+	// ListMovies and SearchMovies order by sort, falling back to newest
+	// first and to relevance respectively when sort is empty.
+	ListMovies(ctx context.Context, filter models.MovieFilter, sort []models.MovieSort, limit, offset int) ([]models.Movie, int, error)
+	SearchMovies(ctx context.Context, query string, sort []models.MovieSort, limit, offset int) ([]models.Movie, int, error)
+	// MoviesConnection and SearchMoviesConnection are the keyset-paginated
+	// forms of ListMovies and SearchMovies, in the same order.
+	MoviesConnection(ctx context.Context, filter models.MovieFilter, sort []models.MovieSort, page models.PageArgs) (*models.Connection[models.Movie], error)
+	SearchMoviesConnection(ctx context.Context, query string, sort []models.MovieSort, page models.PageArgs) (*models.Connection[models.Movie], error)
+	CreateMovie(ctx context.Context, movie models.Movie) (*models.Movie, error)
+	// UpdateMovie and DeleteMovie return ErrVersionConflict when
+	// expectedVersion is non-zero and is not the movie's current version.
+	// UpdateMovie increments the version.
+	UpdateMovie(ctx context.Context, movie models.Movie, expectedVersion int) (*models.Movie, error)
+	// DeleteMovie moves a movie and its reviews to the trash. Trashed rows
+	// are hidden from every other lookup until restored.
+	DeleteMovie(ctx context.Context, id string, expectedVersion int) (bool, error)
+	// RestoreMovie brings a movie back from the trash with the reviews that
+	// were trashed along with it, or returns ErrNotFound.
+	RestoreMovie(ctx context.Context, id string) (*models.Movie, error)
+}
+
+type ActorStore interface {
+	GetActor(ctx context.Context, id string) (*models.Actor, error)
+	// FindActorByName returns the first actor whose name matches
+	// case-insensitively, or ErrNotFound.
+	FindActorByName(ctx context.Context, name string) (*models.Actor, error)
+	CreateActor(ctx context.Context, actor models.Actor) (*models.Actor, error)
+	// DeleteActor moves an actor to the trash, hiding them from every cast.
+	DeleteActor(ctx context.Context, id string) (bool, error)
+	RestoreActor(ctx context.Context, id string) (*models.Actor, error)
+}
+
+type CastStore interface {
+	// CastForMovies batch-loads the billed cast of several movies, keyed by
+	// movie ID and ordered by billing_order.
+	CastForMovies(ctx context.Context, movieIDs []string) (map[string][]models.CastMember, error)
+	// FilmographyForActors batch-loads the roles of several actors, keyed by
+	// actor ID, newest movie first.
+	FilmographyForActors(ctx context.Context, actorIDs []string) (map[string][]models.FilmographyEntry, error)
+	// AddCastMember links an actor to a movie. A zero BillingOrder appends
+	// the actor to the end of the cast; otherwise later entries shift down.
+	// Adding an actor who is already billed returns ErrDuplicate.
+	AddCastMember(ctx context.Context, link models.MovieActor) error
+	// ReorderCast rewrites billing_order to follow actorIDs, which must list
+	// exactly the movie's current cast.
+	ReorderCast(ctx context.Context, movieID string, actorIDs []string) error
+	RemoveCastMember(ctx context.Context, movieID, actorID string) (bool, error)
+}
+
+type ReviewStore interface {
+	GetReview(ctx context.Context, id string) (*models.Review, error)
+	ReviewsForMovie(ctx context.Context, movieID string) ([]models.Review, error)
+	// ReviewsConnection pages through a movie's reviews, newest first.
+	ReviewsConnection(ctx context.Context, movieID string, page models.PageArgs) (*models.Connection[models.Review], error)
+	// ReviewsForMovies batch-loads the reviews of several movies, keyed by movie ID.
+	ReviewsForMovies(ctx context.Context, movieIDs []string) (map[string][]models.Review, error)
+	// ReviewStatsForMovies batch-loads review aggregates, keyed by movie ID.
+	// Every requested ID has an entry; unreviewed movies have zero counts.
+	ReviewStatsForMovies(ctx context.Context, movieIDs []string) (map[string]models.ReviewStats, error)
+	// ReviewsForUsers batch-loads the reviews of several users, keyed by
+	// user ID.
+	ReviewsForUsers(ctx context.Context, userIDs []string) (map[string][]models.Review, error)
+	// CreateReview returns ErrDuplicate if the user already has a review of
+	// the movie.
+	CreateReview(ctx context.Context, review models.Review) (*models.Review, error)
+	// UpsertReview creates the user's review of the movie, or replaces the
+	// rating and comment of the one they have.
+	UpsertReview(ctx context.Context, review models.Review) (*models.Review, error)
+	DeleteReview(ctx context.Context, id string) (bool, error)
+	// RestoreReview brings a review back from the trash. It returns
+	// ErrNotFound if the review is not trashed or its movie is, and
+	// ErrDuplicate if its author has reviewed the movie again since.
+	RestoreReview(ctx context.Context, id string) (*models.Review, error)
+}
+
+type UserStore interface {
+	GetUser(ctx context.Context, id string) (*models.User, error)
+	// UsersByIDs batch-loads users, keyed by ID.
+	UsersByIDs(ctx context.Context, ids []string) (map[string]*models.User, error)
+	// EnsureUser returns the user signed in as subject, creating it, named
+	// after the subject, on first use.
+	EnsureUser(ctx context.Context, subject string) (*models.User, error)
+	// EnsurePlaceholderUser returns the placeholder user with the given
+	// name, creating it if there is none.
+	EnsurePlaceholderUser(ctx context.Context, name string) (*models.User, error)
+	// UpdateUser replaces a user's name, bio and avatar URL.
+	UpdateUser(ctx context.Context, user models.User) (*models.User, error)
+}
+
+type DirectorStore interface {
+	// EnsureDirector returns the ID of the director with the given name,
+	// creating the row if needed.
+	EnsureDirector(ctx context.Context, name string) (string, error)
+	GetDirector(ctx context.Context, id string) (*models.Director, error)
+	// ListDirectors returns directors ordered by name, optionally filtered
+	// by a case-insensitive substring of the name, plus the total match count.
+	ListDirectors(ctx context.Context, search string, limit, offset int) ([]models.Director, int, error)
+	// SetMovieDirectors replaces a movie's director credits with the
+	// comma-separated names in director, creating directors as needed.
+	SetMovieDirectors(ctx context.Context, movieID, director string) error
+	// DirectorsForMovies batch-loads the credited directors of several
+	// movies, keyed by movie ID, in credit order.
+	DirectorsForMovies(ctx context.Context, movieIDs []string) (map[string][]models.Director, error)
+	// FilmographyForDirectors batch-loads the movies of several directors,
+	// keyed by director ID, newest first.
+	FilmographyForDirectors(ctx context.Context, directorIDs []string) (map[string][]models.Movie, error)
+	// StatsForDirectors batch-loads aggregate stats, keyed by director ID.
+	// Directors without movies are omitted.
+	StatsForDirectors(ctx context.Context, directorIDs []string) (map[string]models.DirectorStats, error)
+}
+
+type GenreStore interface {
+	// ListGenres returns every genre ordered by name, with movie counts.
+	ListGenres(ctx context.Context) ([]models.Genre, error)
+	// SetMovieGenres replaces a movie's genres with the comma-separated
+	// names in genre, creating genres as needed.
+	SetMovieGenres(ctx context.Context, movieID, genre string) error
+	// GenresForMovies batch-loads the genres of several movies, keyed by
+	// movie ID, in the order they were listed.
+	GenresForMovies(ctx context.Context, movieIDs []string) (map[string][]models.Genre, error)
+}
+
+type RevisionStore interface {
+	// RecordMovieRevision appends a change to a movie's history.
+	RecordMovieRevision(ctx context.Context, revision models.MovieRevision) (*models.MovieRevision, error)
+	GetMovieRevision(ctx context.Context, id string) (*models.MovieRevision, error)
+	// RevisionsForMovies batch-loads the history of several movies, keyed
+	// by movie ID, newest first.
+	RevisionsForMovies(ctx context.Context, movieIDs []string) (map[string][]models.MovieRevision, error)
+	// MovieAt returns a movie's fields as they were at a point in time, or
+	// ErrNotFound if it did not exist then (or has no recorded history).
+	MovieAt(ctx context.Context, movieID string, at time.Time) (*models.Movie, error)
+}
+
+// PurgeCounts is the number of rows of each kind PurgeDeleted removed.
+type PurgeCounts struct {
+	Movies  int `json:"movies"`
+	Actors  int `json:"actors"`
+	Reviews int `json:"reviews"`
+}
+
+type TrashStore interface {
+	// Trash lists trashed movies, actors and reviews of the given kinds (all
+	// when kinds is empty), most recently deleted first, plus the total.
+	// Reviews trashed along with their movie are listed under the movie.
+	Trash(ctx context.Context, kinds []string, limit, offset int) ([]models.TrashItem, int, error)
+	// PurgeDeleted permanently removes rows trashed before the given time,
+	// with everything that refers to them except movie history.
+	PurgeDeleted(ctx context.Context, before time.Time) (PurgeCounts, error)
+}
+
+// APIKeyStore holds the API keys the server accepts. It is used by the
+// auth middleware rather than by resolvers, so Store does not include it.
+type APIKeyStore interface {
+	// CreateAPIKey stores a key, returning ErrDuplicate if its name is taken.
+	CreateAPIKey(ctx context.Context, key models.APIKey) (*models.APIKey, error)
+	// APIKeyByHash returns the unrevoked key with the given hash, or
+	// ErrNotFound.
+	APIKeyByHash(ctx context.Context, hash string) (*models.APIKey, error)
+	// ListAPIKeys returns every key, revoked ones included, oldest first.
+	ListAPIKeys(ctx context.Context) ([]models.APIKey, error)
+	// RevokeAPIKey stops a key from being accepted. It returns false if no
+	// unrevoked key has the given name.
+	RevokeAPIKey(ctx context.Context, name string) (bool, error)
+}
+
+type Transactor interface {
+	// InTx runs fn against a Store whose writes are committed together if fn
+	// returns nil and rolled back otherwise.
+	InTx(ctx context.Context, fn func(Store) error) error
+}
+
+// Store is everything the GraphQL resolvers need from persistence.
+type Store interface {
+	MovieStore
+	ActorStore
+	CastStore
+	ReviewStore
+	UserStore
+	DirectorStore
+	GenreStore
+	RevisionStore
+	TrashStore
+	Transactor
+}